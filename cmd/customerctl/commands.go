@@ -0,0 +1,552 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/validator"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+)
+
+var (
+	documentValidator = validator.NewDocumentValidator()
+	emailValidator    = validator.NewEmailValidator()
+)
+
+func runGet(ctx context.Context, a *app, args []string) error {
+	f := newFlagSet("get")
+	output := outputFlag(f)
+	document := f.String("document", "", "look the customer up by CPF or CNPJ")
+	userID := f.Uint64("user", 0, "look the customer up by auth user ID")
+
+	positional, err := parse(f, args)
+	if err != nil {
+		return err
+	}
+	p, err := newPrinter(a.out, *output)
+	if err != nil {
+		return err
+	}
+
+	var c *domain.Customer
+	switch {
+	case *document != "" && *userID == 0 && len(positional) == 0:
+		normalized, err := documentValidator.Normalize(*document)
+		if err != nil {
+			return domain.ErrInvalidDocument
+		}
+		c, err = a.customers.GetByDocument(ctx, normalized)
+		if err != nil {
+			return err
+		}
+	case *userID != 0 && *document == "" && len(positional) == 0:
+		if c, err = a.customers.GetByUser(ctx, *userID); err != nil {
+			return err
+		}
+	case *document == "" && *userID == 0:
+		id, err := parseID(positional)
+		if err != nil {
+			return err
+		}
+		if c, err = a.customers.GetByID(ctx, id); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: give one of ID, --document or --user", errUsage)
+	}
+
+	return p.customer(c)
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	f := newFlagSet("list")
+	output := outputFlag(f)
+	filters := addQueryFlags(f)
+	limit := f.Int("limit", domain.DefaultPageLimit, "customers per page")
+	cursor := f.String("cursor", "", "next page cursor from a previous listing")
+	all := f.Bool("all", false, "follow cursors and list every matching customer")
+
+	if err := parseNoArgs(f, args); err != nil {
+		return err
+	}
+	p, err := newPrinter(a.out, *output)
+	if err != nil {
+		return err
+	}
+
+	q, err := filters.query()
+	if err != nil {
+		return err
+	}
+	q.Limit, q.Cursor = *limit, *cursor
+
+	if !*all {
+		page, err := a.customers.GetAll(ctx, q)
+		if err != nil {
+			return err
+		}
+		return p.page(&customerPage{Items: page.Items, NextCursor: page.NextCursor, Total: page.Total})
+	}
+
+	result := &customerPage{Items: []domain.Customer{}}
+	err = eachPage(ctx, a, q, func(page *domain.CustomerPage) error {
+		result.Items = append(result.Items, page.Items...)
+		result.Total = page.Total
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return p.page(result)
+}
+
+func runCreate(ctx context.Context, a *app, args []string) error {
+	f := newFlagSet("create")
+	output := outputFlag(f)
+	dryRun := dryRunFlag(f)
+	nome := f.String("nome", "", "customer name")
+	email := f.String("email", "", "customer email")
+	document := f.String("document", "", "CPF or CNPJ, optional")
+	userID := f.Uint64("user-id", 0, "auth user to link, must match email and document")
+
+	if err := parseNoArgs(f, args); err != nil {
+		return err
+	}
+	p, err := newPrinter(a.out, *output)
+	if err != nil {
+		return err
+	}
+
+	c := &domain.Customer{Nome: *nome, Email: *email, Document: *document, UserID: *userID}
+	if c.Document, err = validate(c.Nome, c.Email, c.Document); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		if err := checkUnique(ctx, a, 0, c.Email, c.Document); err != nil {
+			return err
+		}
+		c.Status = domain.StatusPending
+		dryRunNotice()
+		return p.customer(c)
+	}
+
+	created, err := a.customers.Create(ctx, c)
+	if err != nil {
+		return err
+	}
+	return p.customer(created)
+}
+
+func runUpdate(ctx context.Context, a *app, args []string) error {
+	f := newFlagSet("update")
+	output := outputFlag(f)
+	dryRun := dryRunFlag(f)
+	nome := f.String("nome", "", "new name")
+	email := f.String("email", "", "new email")
+	document := f.String("document", "", `new CPF or CNPJ, "" to clear it`)
+	version := f.Uint("version", 0, "expected current version, 0 skips the check")
+
+	positional, err := parse(f, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(positional)
+	if err != nil {
+		return err
+	}
+	p, err := newPrinter(a.out, *output)
+	if err != nil {
+		return err
+	}
+
+	patch := domain.CustomerPatch{ExpectedVersion: *version}
+	if isSet(f, "nome") {
+		if *nome == "" {
+			return ErrMissingNome
+		}
+		patch.Nome = nome
+	}
+	if isSet(f, "email") {
+		if !emailValidator.IsValid(*email) {
+			return validator.ErrInvalidEmail
+		}
+		patch.Email = email
+	}
+	if isSet(f, "document") {
+		// an empty document clears it, like null in a merge patch
+		normalized := ""
+		if *document != "" {
+			if normalized, err = documentValidator.Normalize(*document); err != nil {
+				return domain.ErrInvalidDocument
+			}
+		}
+		patch.Document = &normalized
+	}
+	if patch.IsEmpty() {
+		return fmt.Errorf("%w: nothing to update, give --nome, --email or --document", errUsage)
+	}
+
+	if !*dryRun {
+		updated, err := a.customers.Patch(ctx, id, patch)
+		if err != nil {
+			return err
+		}
+		return p.customer(updated)
+	}
+
+	c, err := loadVersion(ctx, a, id, patch.ExpectedVersion)
+	if err != nil {
+		return err
+	}
+
+	// only values that change can conflict with another customer
+	var newEmail, newDocument string
+	if patch.Nome != nil {
+		c.Nome = *patch.Nome
+	}
+	if patch.Email != nil && *patch.Email != c.Email {
+		c.Email, newEmail = *patch.Email, *patch.Email
+	}
+	if patch.Document != nil && *patch.Document != c.Document {
+		c.Document, newDocument = *patch.Document, *patch.Document
+	}
+	if err := checkUnique(ctx, a, id, newEmail, newDocument); err != nil {
+		return err
+	}
+
+	c.Version++
+	dryRunNotice()
+	return p.customer(c)
+}
+
+func runDelete(ctx context.Context, a *app, args []string) error {
+	f := newFlagSet("delete")
+	output := outputFlag(f)
+	dryRun := dryRunFlag(f)
+	version := f.Uint("version", 0, "expected current version, 0 skips the check")
+
+	positional, err := parse(f, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(positional)
+	if err != nil {
+		return err
+	}
+	p, err := newPrinter(a.out, *output)
+	if err != nil {
+		return err
+	}
+
+	c, err := loadVersion(ctx, a, id, *version)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		// the service refuses to delete a customer that still holds funds
+		balances, err := a.customers.GetBalances(ctx, []uint{id})
+		if err != nil {
+			return err
+		}
+		if b, ok := balances[id]; ok && (b.Balance != 0 || b.BlockedBalance != 0) {
+			return domain.ErrBalanceNotEmpty
+		}
+		dryRunNotice()
+		return p.customer(c)
+	}
+
+	if err := a.customers.Delete(ctx, id, *version); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	c.DeletedAt = &now
+	return p.customer(c)
+}
+
+func runRestore(ctx context.Context, a *app, args []string) error {
+	f := newFlagSet("restore")
+	output := outputFlag(f)
+	dryRun := dryRunFlag(f)
+
+	positional, err := parse(f, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(positional)
+	if err != nil {
+		return err
+	}
+	p, err := newPrinter(a.out, *output)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		if _, err := a.customers.GetByID(ctx, id); err == nil {
+			return domain.ErrNotDeleted
+		} else if !errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+
+		c, err := findDeleted(ctx, a, id)
+		if err != nil {
+			return err
+		}
+		if err := checkUnique(ctx, a, 0, c.Email, c.Document); err != nil {
+			return err
+		}
+
+		c.DeletedAt = nil
+		c.Version++
+		dryRunNotice()
+		return p.customer(c)
+	}
+
+	restored, err := a.customers.Restore(ctx, id)
+	if err != nil {
+		return err
+	}
+	return p.customer(restored)
+}
+
+func runResyncBalance(ctx context.Context, a *app, args []string) error {
+	f := newFlagSet("resync-balance")
+	output := outputFlag(f)
+	dryRun := dryRunFlag(f)
+
+	positional, err := parse(f, args)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(positional)
+	if err != nil {
+		return err
+	}
+	p, err := newPrinter(a.out, *output)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		if _, err := a.customers.GetByID(ctx, id); err != nil {
+			return err
+		}
+		balances, err := a.customers.GetBalances(ctx, []uint{id})
+		if err != nil {
+			return err
+		}
+		_, found := balances[id]
+		dryRunNotice()
+		return p.balanceSync(balanceSync{CustomerID: id, Created: !found, DryRun: true})
+	}
+
+	created, err := a.customers.ResyncBalance(ctx, id)
+	if err != nil {
+		return err
+	}
+	return p.balanceSync(balanceSync{CustomerID: id, Created: created})
+}
+
+// exportColumns is the CSV header; the rows follow the same order.
+var exportColumns = []string{"id", "nome", "email", "document", "user_id", "status", "version", "created_at", "deleted_at"}
+
+func runExport(ctx context.Context, a *app, args []string) error {
+	f := newFlagSet("export")
+	filters := addQueryFlags(f)
+	format := f.String("format", "jsonl", "jsonl (one customer per line) or csv")
+
+	if err := parseNoArgs(f, args); err != nil {
+		return err
+	}
+
+	q, err := filters.query()
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "jsonl":
+		enc := json.NewEncoder(a.out)
+		return eachPage(ctx, a, q, func(page *domain.CustomerPage) error {
+			for i := range page.Items {
+				if err := enc.Encode(&page.Items[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	case "csv":
+		w := csv.NewWriter(a.out)
+		if err := w.Write(exportColumns); err != nil {
+			return err
+		}
+		err := eachPage(ctx, a, q, func(page *domain.CustomerPage) error {
+			for _, c := range page.Items {
+				if err := w.Write(csvRow(c)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		w.Flush()
+		if err != nil {
+			return err
+		}
+		return w.Error()
+	}
+
+	return fmt.Errorf("%w: unknown export format %q", errUsage, *format)
+}
+
+func csvRow(c domain.Customer) []string {
+	deletedAt := ""
+	if c.DeletedAt != nil {
+		deletedAt = c.DeletedAt.UTC().Format(time.RFC3339)
+	}
+
+	userID := ""
+	if c.UserID != 0 {
+		userID = strconv.FormatUint(c.UserID, 10)
+	}
+
+	return []string{
+		strconv.FormatUint(uint64(c.ID), 10),
+		c.Nome,
+		c.Email,
+		c.Document,
+		userID,
+		string(c.Status),
+		strconv.FormatUint(uint64(c.Version), 10),
+		c.CreatedAt.UTC().Format(time.RFC3339),
+		deletedAt,
+	}
+}
+
+// eachPage walks every page of q, starting at q.Cursor, in pages of the
+// maximum size unless q sets a smaller limit.
+func eachPage(ctx context.Context, a *app, q domain.CustomerQuery, fn func(page *domain.CustomerPage) error) error {
+	if q.Limit <= 0 || q.Limit > domain.MaxPageLimit {
+		q.Limit = domain.MaxPageLimit
+	}
+
+	for {
+		page, err := a.customers.GetAll(ctx, q)
+		if err != nil {
+			return err
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func parseNoArgs(f *flag.FlagSet, args []string) error {
+	positional, err := parse(f, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", errUsage, positional[0])
+	}
+	return nil
+}
+
+// validate applies the checks of the HTTP and gRPC handlers and returns
+// the normalized document. The document is optional.
+func validate(nome, email, document string) (string, error) {
+	if nome == "" {
+		return "", ErrMissingNome
+	}
+	if !emailValidator.IsValid(email) {
+		return "", validator.ErrInvalidEmail
+	}
+	if document == "" {
+		return "", nil
+	}
+
+	normalized, err := documentValidator.Normalize(document)
+	if err != nil {
+		return "", domain.ErrInvalidDocument
+	}
+	return normalized, nil
+}
+
+// loadVersion fetches a live customer and checks it is at version, unless
+// version is zero.
+func loadVersion(ctx context.Context, a *app, id uint, version uint) (*domain.Customer, error) {
+	c, err := a.customers.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && c.Version != version {
+		return nil, domain.ErrVersionMismatch
+	}
+	return c, nil
+}
+
+// findDeleted scans the deleted listing for id, since GetByID only sees
+// live customers. It reads every deleted customer in the worst case, which
+// is acceptable for a one-off dry run.
+func findDeleted(ctx context.Context, a *app, id uint) (*domain.Customer, error) {
+	var found *domain.Customer
+	errFound := errors.New("found")
+
+	err := eachPage(ctx, a, domain.CustomerQuery{Deleted: true}, func(page *domain.CustomerPage) error {
+		for i := range page.Items {
+			if page.Items[i].ID == id {
+				found = &page.Items[i]
+				return errFound
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errFound) {
+		return nil, err
+	}
+	if found == nil {
+		return nil, domain.ErrNotFound
+	}
+	return found, nil
+}
+
+// checkUnique reports the conflict a write of email or document would run
+// into with a live customer other than id. Empty values are not checked.
+func checkUnique(ctx context.Context, a *app, id uint, email, document string) error {
+	if email != "" {
+		page, err := a.customers.GetAll(ctx, domain.CustomerQuery{
+			Limit: 1,
+			Email: &domain.StringFilter{Value: email, Mode: domain.MatchExact},
+		})
+		if err != nil {
+			return err
+		}
+		if len(page.Items) > 0 && page.Items[0].ID != id {
+			return domain.ErrEmailAlreadyExists
+		}
+	}
+
+	if document != "" {
+		c, err := a.customers.GetByDocument(ctx, document)
+		switch {
+		case err == nil && c.ID != id:
+			return domain.ErrDocumentAlreadyExists
+		case err != nil && !errors.Is(err, domain.ErrNotFound):
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,5 @@
+package main
+
+import "errors"
+
+var ErrMissingNome = errors.New("nome is required")
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+)
+
+func newFlagSet(name string) *flag.FlagSet {
+	f := flag.NewFlagSet(name, flag.ContinueOnError)
+	f.SetOutput(os.Stderr)
+	return f
+}
+
+func outputFlag(f *flag.FlagSet) *string {
+	return f.String("o", formatTable, "output format: table or json")
+}
+
+func dryRunFlag(f *flag.FlagSet) *bool {
+	return f.Bool("dry-run", false, "validate and show the outcome without writing")
+}
+
+// parse accepts positional arguments anywhere among the flags, so both
+// `update 7 --nome X` and `update --nome X 7` work. The standard flag
+// package stops at the first positional argument.
+func parse(f *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := f.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		if f.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, f.Arg(0))
+		args = f.Args()[1:]
+	}
+}
+
+// parseID parses the single customer ID a command expects.
+func parseID(positional []string) (uint, error) {
+	if len(positional) != 1 {
+		return 0, fmt.Errorf("%w: expected one customer ID", errUsage)
+	}
+
+	id, err := strconv.ParseUint(positional[0], 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%w: invalid customer ID %q", errUsage, positional[0])
+	}
+	return uint(id), nil
+}
+
+// isSet reports whether name was given on the command line, which tells an
+// empty value apart from an absent flag.
+func isSet(f *flag.FlagSet, name string) bool {
+	set := false
+	f.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+type queryFlags struct {
+	nome, nomeMode   string
+	email, emailMode string
+	status           string
+	createdFrom      string
+	createdTo        string
+	sort             string
+	deleted          bool
+}
+
+func addQueryFlags(f *flag.FlagSet) *queryFlags {
+	q := &queryFlags{}
+	f.StringVar(&q.nome, "nome", "", "filter by name")
+	f.StringVar(&q.nomeMode, "nome-mode", string(domain.MatchExact), "name match: exact, prefix or contains")
+	f.StringVar(&q.email, "email", "", "filter by email")
+	f.StringVar(&q.emailMode, "email-mode", string(domain.MatchExact), "email match: exact, prefix or contains")
+	f.StringVar(&q.status, "status", "", "filter by status")
+	f.StringVar(&q.createdFrom, "created-from", "", "created at or after, RFC 3339")
+	f.StringVar(&q.createdTo, "created-to", "", "created at or before, RFC 3339")
+	f.StringVar(&q.sort, "sort", "", "sort field, prefixed with - for descending")
+	f.BoolVar(&q.deleted, "deleted", false, "list soft-deleted customers instead of live ones")
+	return q
+}
+
+// query builds the domain query with the same rules as the HTTP listing.
+func (q *queryFlags) query() (domain.CustomerQuery, error) {
+	out := domain.CustomerQuery{Deleted: q.deleted}
+
+	var err error
+	if out.Nome, err = stringFilter(q.nome, q.nomeMode); err != nil {
+		return out, err
+	}
+	if out.Email, err = stringFilter(q.email, q.emailMode); err != nil {
+		return out, err
+	}
+	if out.CreatedFrom, err = parseTime(q.createdFrom); err != nil {
+		return out, err
+	}
+	if out.CreatedTo, err = parseTime(q.createdTo); err != nil {
+		return out, err
+	}
+
+	if q.status != "" {
+		if out.Status, err = domain.ParseStatus(q.status); err != nil {
+			return out, err
+		}
+	}
+
+	if q.sort != "" {
+		field := strings.TrimPrefix(q.sort, "-")
+		out.SortDesc = field != q.sort
+		if out.SortBy, err = domain.ParseSortField(field); err != nil {
+			return out, err
+		}
+	}
+
+	return out, nil
+}
+
+func stringFilter(value, mode string) (*domain.StringFilter, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	m, err := domain.ParseMatchMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.StringFilter{Value: value, Mode: m}, nil
+}
+
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid time %q, expected RFC 3339", errUsage, value)
+	}
+	return &t, nil
+}
@@ -0,0 +1,204 @@
+// Command customerctl is the operator tool for customer records. It talks
+// to the database and the payment and auth services directly through
+// service.CustomerService, without the HTTP API or its RBAC policy, so it
+// is meant to run next to the service with the same configuration.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	pbPaymentClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/payment"
+	pbUserClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/user"
+	"github.com/rasteiro11/MCABankCustomer/migrations"
+	"github.com/rasteiro11/MCABankCustomer/pkg/dbdriver"
+	"github.com/rasteiro11/MCABankCustomer/pkg/migrate"
+	"github.com/rasteiro11/MCABankCustomer/pkg/payment"
+	customerRepo "github.com/rasteiro11/MCABankCustomer/src/customer/repository"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/service"
+	"github.com/rasteiro11/PogCore/pkg/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+const usage = `usage: customerctl <command> [flags]
+
+commands:
+  get ID | get --document DOC | get --user USER_ID
+  list            [filters] [--limit N] [--cursor C] [--all]
+  create          --nome NAME --email EMAIL [--document DOC] [--user-id ID]
+  update ID       [--nome NAME] [--email EMAIL] [--document DOC|""] [--version V]
+  delete ID       [--version V]
+  restore ID
+  resync-balance ID
+  export          [filters] [--format jsonl|csv]
+  reconcile       [--repair] [--batch-size N] [--grace DURATION]
+
+list and export filters:
+  --nome X --nome-mode exact|prefix|contains
+  --email X --email-mode exact|prefix|contains
+  --status S --deleted --created-from T --created-to T (RFC 3339)
+  --sort [-]id|nome|email|created_at, a leading - sorts descending
+
+every command takes -o table|json; mutations also take --dry-run, which
+validates and shows the outcome without writing anything. reconcile only
+reports drift unless --repair is given, and exits 3 when drift remains.
+`
+
+// errUsage is returned for malformed command lines; main prints the usage.
+var errUsage = errors.New("invalid usage")
+
+const codeUsage = 2
+
+type command func(ctx context.Context, app *app, args []string) error
+
+var commands = map[string]command{
+	"get":            runGet,
+	"list":           runList,
+	"create":         runCreate,
+	"update":         runUpdate,
+	"delete":         runDelete,
+	"restore":        runRestore,
+	"resync-balance": runResyncBalance,
+	"export":         runExport,
+	"reconcile":      runReconcile,
+}
+
+// app holds the dependencies shared by the commands.
+type app struct {
+	customers service.CustomerService
+	out       io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	code := execute(ctx, os.Args[1:], newApp, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// execute runs the command line and returns the exit code: codeUsage for a
+// malformed command line, codeDrift for drift left unrepaired and 1 for any
+// other failure. The app is only built for a known command.
+func execute(ctx context.Context, args []string, newApp func(context.Context) (*app, func(), error), stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return codeUsage
+	}
+
+	run, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(stderr, usage)
+		return codeUsage
+	}
+
+	a, closeApp, err := newApp(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "customerctl: %v\n", err)
+		return 1
+	}
+
+	err = run(ctx, a, args[1:])
+	closeApp()
+
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "customerctl %s: %v\n\n%s", args[0], err, usage)
+		return codeUsage
+	case errors.Is(err, errDrift):
+		return codeDrift
+	case err != nil:
+		fmt.Fprintf(stderr, "customerctl %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// newApp wires the customer service the way the server does, minus the
+// metrics, tracing and background workers a short-lived process has no use
+// for.
+func newApp(ctx context.Context) (*app, func(), error) {
+	driver := dbdriver.Driver()
+	dbInstance, err := dbdriver.Open(driver)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	// keep stdout for command output; missing records are reported as errors
+	db := dbInstance.Conn().Session(&gorm.Session{Logger: gormlogger.New(
+		log.New(os.Stderr, "", log.LstdFlags),
+		gormlogger.Config{LogLevel: gormlogger.Error, IgnoreRecordNotFoundError: true},
+	)})
+
+	if err := checkSchema(ctx, db, driver); err != nil {
+		return nil, nil, err
+	}
+
+	credentials := insecure.NewCredentials()
+
+	paymentConn, err := grpc.Dial(config.Instance().RequiredString("PAYMENT_GRPC_SERVICE"),
+		grpc.WithTransportCredentials(credentials))
+	if err != nil {
+		return nil, nil, fmt.Errorf("dialing payment service: %w", err)
+	}
+
+	authConn, err := grpc.Dial(config.Instance().RequiredString("AUTH_GRPC_SERVICE"),
+		grpc.WithTransportCredentials(credentials))
+	if err != nil {
+		paymentConn.Close()
+		return nil, nil, fmt.Errorf("dialing auth service: %w", err)
+	}
+
+	paymentClient := payment.NewResilientClient(pbPaymentClient.NewBalanceServiceClient(paymentConn),
+		payment.WithTimeout(time.Duration(config.Instance().Int("PAYMENT_TIMEOUT_MS"))*time.Millisecond),
+		payment.WithMaxAttempts(config.Instance().Int("PAYMENT_MAX_ATTEMPTS")),
+		payment.WithBackoff(
+			time.Duration(config.Instance().Int("PAYMENT_BACKOFF_BASE_MS"))*time.Millisecond,
+			time.Duration(config.Instance().Int("PAYMENT_BACKOFF_MAX_MS"))*time.Millisecond,
+		),
+	)
+
+	customers := service.NewCustomerService(
+		customerRepo.NewCustomerRepository(db),
+		customerRepo.NewSagaRepository(db),
+		paymentClient,
+		pbUserClient.NewAuthServiceClient(authConn),
+	)
+
+	closeApp := func() {
+		authConn.Close()
+		paymentConn.Close()
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+
+	return &app{customers: customers, out: os.Stdout}, closeApp, nil
+}
+
+// checkSchema refuses to touch a database this build does not understand
+// yet, rather than failing halfway through a command.
+func checkSchema(ctx context.Context, db *gorm.DB, driver string) error {
+	all, err := migrations.Load(driver)
+	if err != nil {
+		return err
+	}
+
+	status, err := migrate.NewMigrator(db, all).Status(ctx)
+	if err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if status.Dirty || status.Behind() {
+		return fmt.Errorf("schema is at version %d, this build needs %d: run `mcabank-customer migrate up` first", status.Current, status.Latest)
+	}
+	return nil
+}
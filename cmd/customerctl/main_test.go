@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth/authtest"
+	"github.com/rasteiro11/MCABankCustomer/pkg/payment/paymenttest"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/reconciler"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/repository"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/service"
+)
+
+// fixture runs customerctl against memory repositories and fake payment
+// and auth services. Customer 1 has an empty balance, customer 2 a funded
+// one, customer 3 none at all and customer 4 is deleted.
+type fixture struct {
+	app    *app
+	out    *bytes.Buffer
+	stderr *bytes.Buffer
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+
+	authClient, stopAuth, err := authtest.NewServer().Start()
+	if err != nil {
+		t.Fatalf("authtest Start() returned error: %v", err)
+	}
+	t.Cleanup(stopAuth)
+
+	paymentSrv := paymenttest.NewServer()
+	paymentClient, stopPayment, err := paymentSrv.Start()
+	if err != nil {
+		t.Fatalf("paymenttest Start() returned error: %v", err)
+	}
+	t.Cleanup(stopPayment)
+
+	db := repository.NewMemoryDB()
+	repo := repository.NewMemoryCustomerRepository(db)
+	for _, c := range []*domain.Customer{
+		{Nome: "Ana", Email: "ana@example.com", Document: "52998224725", Status: domain.StatusActive},
+		{Nome: "Bia", Email: "bia@example.com", Status: domain.StatusActive},
+		{Nome: "Caio", Email: "caio@example.com", Status: domain.StatusActive},
+		{Nome: "Duda", Email: "duda@example.com", Status: domain.StatusActive},
+	} {
+		if _, err := repo.Create(ctx, c); err != nil {
+			t.Fatalf("Create() returned error: %v", err)
+		}
+	}
+	if err := repo.Delete(ctx, 4, 1); err != nil {
+		t.Fatalf("Delete() returned error: %v", err)
+	}
+	paymentSrv.SetBalance(1, 0, 0)
+	paymentSrv.SetBalance(2, 10, 0)
+
+	out := &bytes.Buffer{}
+	return &fixture{
+		app: &app{
+			customers: service.NewCustomerService(repo, repository.NewMemorySagaRepository(db), paymentClient, authClient),
+			out:       out,
+		},
+		out:    out,
+		stderr: &bytes.Buffer{},
+	}
+}
+
+// run executes a command line and returns its exit code, with stdout and
+// stderr of that run only.
+func (f *fixture) run(args ...string) int {
+	f.out.Reset()
+	f.stderr.Reset()
+	newApp := func(context.Context) (*app, func(), error) {
+		return f.app, func() {}, nil
+	}
+	return execute(context.Background(), args, newApp, f.stderr)
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		want      int
+		wantUsage bool
+	}{
+		{name: "no command", want: codeUsage, wantUsage: true},
+		{name: "unknown command", args: []string{"frobnicate"}, want: codeUsage, wantUsage: true},
+		{name: "unknown flag", args: []string{"list", "--bogus"}, want: codeUsage, wantUsage: true},
+		{name: "missing ID", args: []string{"get"}, want: codeUsage, wantUsage: true},
+		{name: "invalid ID", args: []string{"get", "abc"}, want: codeUsage, wantUsage: true},
+		{name: "zero ID", args: []string{"delete", "0"}, want: codeUsage, wantUsage: true},
+		{name: "two IDs", args: []string{"get", "1", "2"}, want: codeUsage, wantUsage: true},
+		{name: "ID and document", args: []string{"get", "1", "--document", "52998224725"}, want: codeUsage, wantUsage: true},
+		{name: "unexpected argument", args: []string{"list", "extra"}, want: codeUsage, wantUsage: true},
+		{name: "unknown output format", args: []string{"get", "1", "-o", "yaml"}, want: codeUsage, wantUsage: true},
+		{name: "unknown export format", args: []string{"export", "--format", "xml"}, want: codeUsage, wantUsage: true},
+		{name: "invalid time filter", args: []string{"list", "--created-from", "yesterday"}, want: codeUsage, wantUsage: true},
+		{name: "update without changes", args: []string{"update", "1"}, want: codeUsage, wantUsage: true},
+		{name: "get", args: []string{"get", "1"}, want: 0},
+		{name: "get by document", args: []string{"get", "--document", "529.982.247-25"}, want: 0},
+		{name: "flags after the ID", args: []string{"update", "1", "--nome", "Ana Maria"}, want: 0},
+		{name: "flags before the ID", args: []string{"update", "--nome", "Ana Maria", "1"}, want: 0},
+		{name: "get a missing customer", args: []string{"get", "99"}, want: 1},
+		{name: "create with an invalid email", args: []string{"create", "--nome", "Eva", "--email", "eva"}, want: 1},
+		{name: "create without a name", args: []string{"create", "--email", "eva@example.com"}, want: 1},
+		{name: "update a stale version", args: []string{"update", "1", "--nome", "Ana Maria", "--version", "5"}, want: 1},
+		{name: "delete a funded customer", args: []string{"delete", "2"}, want: 1},
+		{name: "reconcile within the grace period", args: []string{"reconcile"}, want: 0},
+		{name: "reconcile with drift", args: []string{"reconcile", "--grace", "1ns"}, want: codeDrift},
+		{name: "reconcile and repair", args: []string{"reconcile", "--grace", "1ns", "--repair"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+
+			if got := f.run(tt.args...); got != tt.want {
+				t.Fatalf("exit code %d, want %d; stderr: %s", got, tt.want, f.stderr)
+			}
+			if usage := strings.Contains(f.stderr.String(), "usage: customerctl"); usage != tt.wantUsage {
+				t.Errorf("usage printed: %v, want %v; stderr: %s", usage, tt.wantUsage, f.stderr)
+			}
+			if tt.want == 1 && f.stderr.Len() == 0 {
+				t.Errorf("failure printed nothing to stderr")
+			}
+		})
+	}
+}
+
+func TestNewAppFailure(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	newApp := func(context.Context) (*app, func(), error) {
+		return nil, nil, context.DeadlineExceeded
+	}
+
+	if got := execute(context.Background(), []string{"get", "1"}, newApp, stderr); got != 1 {
+		t.Fatalf("exit code %d, want 1", got)
+	}
+	if !strings.Contains(stderr.String(), context.DeadlineExceeded.Error()) {
+		t.Errorf("stderr %q does not report the error", stderr)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "create", args: []string{"create", "--dry-run", "--nome", "Eva", "--email", "eva@example.com"}},
+		{name: "create with a taken email", args: []string{"create", "--dry-run", "--nome", "Eva", "--email", "ana@example.com"}, want: 1},
+		{name: "create with a taken document", args: []string{"create", "--dry-run", "--nome", "Eva", "--email", "eva@example.com", "--document", "52998224725"}, want: 1},
+		{name: "update", args: []string{"update", "1", "--dry-run", "--nome", "Ana Maria", "--document", ""}},
+		{name: "update to a taken email", args: []string{"update", "1", "--dry-run", "--email", "bia@example.com"}, want: 1},
+		{name: "update a stale version", args: []string{"update", "1", "--dry-run", "--nome", "Ana Maria", "--version", "5"}, want: 1},
+		{name: "delete", args: []string{"delete", "1", "--dry-run"}},
+		{name: "delete a funded customer", args: []string{"delete", "2", "--dry-run"}, want: 1},
+		{name: "restore", args: []string{"restore", "4", "--dry-run"}},
+		{name: "restore a live customer", args: []string{"restore", "1", "--dry-run"}, want: 1},
+		{name: "resync a missing balance", args: []string{"resync-balance", "3", "--dry-run"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			before := f.snapshot(t)
+
+			if got := f.run(tt.args...); got != tt.want {
+				t.Fatalf("exit code %d, want %d; stderr: %s", got, tt.want, f.stderr)
+			}
+			if tt.want == 0 && f.out.Len() == 0 {
+				t.Errorf("dry run printed no outcome")
+			}
+
+			if after := f.snapshot(t); after != before {
+				t.Errorf("dry run changed the customers:\nbefore: %s\nafter:  %s", before, after)
+			}
+			balances, err := f.app.customers.GetBalances(context.Background(), []uint{3})
+			if err != nil {
+				t.Fatalf("GetBalances() returned error: %v", err)
+			}
+			if _, ok := balances[3]; ok {
+				t.Errorf("dry run created a balance")
+			}
+		})
+	}
+}
+
+// snapshot exports the live and deleted customers as JSON lines.
+func (f *fixture) snapshot(t *testing.T) string {
+	t.Helper()
+
+	var b strings.Builder
+	for _, args := range [][]string{{"export"}, {"export", "--deleted"}} {
+		if code := f.run(args...); code != 0 {
+			t.Fatalf("%v exited %d; stderr: %s", args, code, f.stderr)
+		}
+		b.WriteString(f.out.String())
+	}
+	return b.String()
+}
+
+func TestOutputFormats(t *testing.T) {
+	f := newFixture(t)
+
+	t.Run("get as json", func(t *testing.T) {
+		if code := f.run("get", "1", "-o", "json"); code != 0 {
+			t.Fatalf("exit code %d; stderr: %s", code, f.stderr)
+		}
+		var c domain.Customer
+		if err := json.Unmarshal(f.out.Bytes(), &c); err != nil {
+			t.Fatalf("Unmarshal() returned error: %v", err)
+		}
+		if c.ID != 1 || c.Email != "ana@example.com" || c.Version != 1 {
+			t.Errorf("got %+v", c)
+		}
+	})
+
+	t.Run("get as a table", func(t *testing.T) {
+		if code := f.run("get", "1"); code != 0 {
+			t.Fatalf("exit code %d; stderr: %s", code, f.stderr)
+		}
+		lines := strings.Split(strings.TrimSpace(f.out.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("got %d lines, want a header and a row:\n%s", len(lines), f.out)
+		}
+		header, row := strings.Fields(lines[0]), strings.Fields(lines[1])
+		want := []string{"ID", "NOME", "EMAIL", "DOCUMENT", "USER", "STATUS", "VERSION", "CREATED", "DELETED"}
+		if !reflect.DeepEqual(header, want) {
+			t.Errorf("header is %v, want %v", header, want)
+		}
+		if len(row) != len(want) || row[0] != "1" || row[2] != "ana@example.com" || row[4] != "-" || row[8] != "-" {
+			t.Errorf("row is %v", row)
+		}
+	})
+
+	t.Run("list as json", func(t *testing.T) {
+		if code := f.run("list", "-o", "json", "--limit", "2"); code != 0 {
+			t.Fatalf("exit code %d; stderr: %s", code, f.stderr)
+		}
+		var page customerPage
+		if err := json.Unmarshal(f.out.Bytes(), &page); err != nil {
+			t.Fatalf("Unmarshal() returned error: %v", err)
+		}
+		if len(page.Items) != 2 || page.Total != 3 || page.NextCursor == "" {
+			t.Errorf("got %d items of %d, cursor %q", len(page.Items), page.Total, page.NextCursor)
+		}
+	})
+
+	t.Run("list all as json", func(t *testing.T) {
+		if code := f.run("list", "-o", "json", "--limit", "2", "--all"); code != 0 {
+			t.Fatalf("exit code %d; stderr: %s", code, f.stderr)
+		}
+		var page customerPage
+		if err := json.Unmarshal(f.out.Bytes(), &page); err != nil {
+			t.Fatalf("Unmarshal() returned error: %v", err)
+		}
+		if len(page.Items) != 3 || page.NextCursor != "" {
+			t.Errorf("got %d items, cursor %q", len(page.Items), page.NextCursor)
+		}
+	})
+
+	t.Run("export as csv", func(t *testing.T) {
+		if code := f.run("export", "--format", "csv", "--deleted"); code != 0 {
+			t.Fatalf("exit code %d; stderr: %s", code, f.stderr)
+		}
+		records, err := csv.NewReader(f.out).ReadAll()
+		if err != nil {
+			t.Fatalf("ReadAll() returned error: %v", err)
+		}
+		if len(records) != 2 || !reflect.DeepEqual(records[0], exportColumns) {
+			t.Fatalf("got %v", records)
+		}
+		if row := records[1]; row[0] != "4" || row[8] == "" {
+			t.Errorf("deleted row is %v", row)
+		}
+	})
+
+	t.Run("export as jsonl", func(t *testing.T) {
+		if code := f.run("export"); code != 0 {
+			t.Fatalf("exit code %d; stderr: %s", code, f.stderr)
+		}
+		var ids []uint
+		scanner := bufio.NewScanner(f.out)
+		for scanner.Scan() {
+			var c domain.Customer
+			if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+				t.Fatalf("Unmarshal(%q) returned error: %v", scanner.Text(), err)
+			}
+			ids = append(ids, c.ID)
+		}
+		if !reflect.DeepEqual(ids, []uint{1, 2, 3}) {
+			t.Errorf("exported %v, want [1 2 3]", ids)
+		}
+	})
+
+	t.Run("reconcile as json", func(t *testing.T) {
+		if code := f.run("reconcile", "-o", "json", "--grace", "1ns"); code != codeDrift {
+			t.Fatalf("exit code %d, want %d; stderr: %s", code, codeDrift, f.stderr)
+		}
+		var report reconciler.Report
+		if err := json.Unmarshal(f.out.Bytes(), &report); err != nil {
+			t.Fatalf("Unmarshal() returned error: %v", err)
+		}
+		if !reflect.DeepEqual(report.Missing, []uint{3}) || report.CustomersChecked != 3 {
+			t.Errorf("got %+v", report)
+		}
+	})
+
+	t.Run("reconcile as a table", func(t *testing.T) {
+		if code := f.run("reconcile", "--grace", "1ns"); code != codeDrift {
+			t.Fatalf("exit code %d, want %d; stderr: %s", code, codeDrift, f.stderr)
+		}
+		if !strings.Contains(f.out.String(), "MISSING BALANCE") {
+			t.Errorf("report does not list the missing balance:\n%s", f.out)
+		}
+	})
+
+	t.Run("resync-balance as json", func(t *testing.T) {
+		if code := f.run("resync-balance", "3", "-o", "json"); code != 0 {
+			t.Fatalf("exit code %d; stderr: %s", code, f.stderr)
+		}
+		var sync balanceSync
+		if err := json.Unmarshal(f.out.Bytes(), &sync); err != nil {
+			t.Fatalf("Unmarshal() returned error: %v", err)
+		}
+		if sync != (balanceSync{CustomerID: 3, Created: true}) {
+			t.Errorf("got %+v", sync)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case formatTable, formatJSON:
+		return &printer{format: format, w: w}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown output format %q", errUsage, format)
+}
+
+// customerPage is the JSON shape of a listing.
+type customerPage struct {
+	Items      []domain.Customer `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	Total      int64             `json:"total"`
+}
+
+func (p *printer) customer(c *domain.Customer) error {
+	if p.format == formatJSON {
+		return p.json(c)
+	}
+	return p.table([]domain.Customer{*c})
+}
+
+func (p *printer) page(page *customerPage) error {
+	if p.format == formatJSON {
+		return p.json(page)
+	}
+
+	if err := p.table(page.Items); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d of %d customers", len(page.Items), page.Total)
+	if page.NextCursor != "" {
+		fmt.Fprintf(os.Stderr, ", next page: --cursor %s", page.NextCursor)
+	}
+	fmt.Fprintln(os.Stderr)
+	return nil
+}
+
+// balanceSync is the outcome of resync-balance.
+type balanceSync struct {
+	CustomerID uint `json:"customer_id"`
+	Created    bool `json:"created"`
+	DryRun     bool `json:"dry_run,omitempty"`
+}
+
+func (p *printer) balanceSync(s balanceSync) error {
+	if p.format == formatJSON {
+		return p.json(s)
+	}
+
+	switch {
+	case !s.Created:
+		fmt.Fprintf(p.w, "customer %d already has a balance\n", s.CustomerID)
+	case s.DryRun:
+		fmt.Fprintf(p.w, "customer %d has no balance, one would be created\n", s.CustomerID)
+	default:
+		fmt.Fprintf(p.w, "created the balance of customer %d\n", s.CustomerID)
+	}
+	return nil
+}
+
+func (p *printer) json(v any) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (p *printer) table(customers []domain.Customer) error {
+	w := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNOME\tEMAIL\tDOCUMENT\tUSER\tSTATUS\tVERSION\tCREATED\tDELETED")
+	for _, c := range customers {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			c.ID, c.Nome, c.Email, c.Document, userColumn(c.UserID), c.Status, c.Version,
+			timeColumn(&c.CreatedAt), timeColumn(c.DeletedAt))
+	}
+	return w.Flush()
+}
+
+func userColumn(id uint64) string {
+	if id == 0 {
+		return "-"
+	}
+	return strconv.FormatUint(id, 10)
+}
+
+func timeColumn(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// dryRunNotice goes to stderr so JSON output stays parseable.
+func dryRunNotice() {
+	fmt.Fprintln(os.Stderr, "dry run: nothing was written")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/reconciler"
+)
+
+// errDrift makes main exit with codeDrift, so scripts can tell a run that
+// found unrepaired drift from one that failed.
+var errDrift = errors.New("drift found")
+
+const codeDrift = 3
+
+func runReconcile(ctx context.Context, a *app, args []string) error {
+	f := newFlagSet("reconcile")
+	output := outputFlag(f)
+	repair := f.Bool("repair", false, "create the missing balances")
+	batchSize := f.Int("batch-size", domain.MaxPageLimit, "customers per GetBalances call")
+	grace := f.Duration("grace", 10*time.Minute, "skip customers created more recently than this")
+
+	if err := parseNoArgs(f, args); err != nil {
+		return err
+	}
+	p, err := newPrinter(a.out, *output)
+	if err != nil {
+		return err
+	}
+
+	// no lease: a run overlapping the scheduled one only repeats reads, and
+	// repairs check for the balance before creating it
+	report, err := reconciler.NewReconciler(a.customers,
+		reconciler.WithRepair(*repair),
+		reconciler.WithBatchSize(*batchSize),
+		reconciler.WithGracePeriod(*grace),
+	).Reconcile(ctx)
+
+	if printErr := p.report(report); printErr != nil && err == nil {
+		err = printErr
+	}
+	if err != nil {
+		return err
+	}
+	if !report.Clean() {
+		return errDrift
+	}
+	return nil
+}
+
+func (p *printer) report(r *reconciler.Report) error {
+	if p.format == formatJSON {
+		return p.json(r)
+	}
+
+	fmt.Fprintf(p.w, "checked %d live and %d deleted customers, probed %d unknown IDs in %s\n",
+		r.CustomersChecked, r.DeletedChecked, r.UnknownIDsProbed, r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond))
+	if r.SkippedRecent > 0 {
+		fmt.Fprintf(p.w, "skipped %d customers created within the grace period\n", r.SkippedRecent)
+	}
+
+	w := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	if len(r.Missing) > 0 {
+		repaired := make(map[uint]bool, len(r.Repaired))
+		for _, id := range r.Repaired {
+			repaired[id] = true
+		}
+		failed := make(map[uint]string, len(r.RepairFailures))
+		for _, f := range r.RepairFailures {
+			failed[f.CustomerID] = f.Error
+		}
+
+		fmt.Fprintln(w, "\nMISSING BALANCE\tREPAIR")
+		for _, id := range r.Missing {
+			outcome := "-"
+			switch {
+			case repaired[id]:
+				outcome = "created"
+			case failed[id] != "":
+				outcome = "failed: " + failed[id]
+			case r.Repair:
+				outcome = "appeared meanwhile"
+			}
+			fmt.Fprintf(w, "%d\t%s\n", id, outcome)
+		}
+	}
+
+	if len(r.Orphaned) > 0 {
+		fmt.Fprintln(w, "\nORPHANED BALANCE\tREASON\tBALANCE\tBLOCKED")
+		for _, o := range r.Orphaned {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", o.CustomerID, o.Reason,
+				strconv.FormatFloat(o.Balance, 'f', 2, 64), strconv.FormatFloat(o.BlockedBalance, 'f', 2, 64))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Missing) == 0 && len(r.Orphaned) == 0 && r.Error == "" {
+		fmt.Fprintln(p.w, "no drift")
+	}
+	return nil
+}
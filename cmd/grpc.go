@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net"
+
+	"github.com/rasteiro11/PogCore/pkg/transport/grpcserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// grpcServer is grpcserver.Server with the server options applied:
+// grpcserver.NewServer keeps WithServerOption but never passes it to
+// grpc.NewServer, which would leave the RPCs without their interceptors.
+type grpcServer struct {
+	port string
+	srv  *grpc.Server
+}
+
+var _ grpcserver.Server = (*grpcServer)(nil)
+
+func newGRPCServer(port string, opts ...grpc.ServerOption) grpcserver.Server {
+	srv := grpc.NewServer(opts...)
+	reflection.Register(srv)
+	return &grpcServer{port: port, srv: srv}
+}
+
+func (s *grpcServer) Run() error {
+	lis, err := net.Listen("tcp", ":"+s.port)
+	if err != nil {
+		return err
+	}
+	return s.srv.Serve(lis)
+}
+
+func (s *grpcServer) Register(desc grpc.ServiceDesc, service any) {
+	s.srv.RegisterService(&desc, service)
+}
+
+func (s *grpcServer) Close() {
+	s.srv.GracefulStop()
+}
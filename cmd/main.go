@@ -4,75 +4,346 @@
 // @host localhost:5002
 // @BasePath /
 // @schemes http
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 package main
 
 import (
 	"context"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/rasteiro11/MCABankCustomer/entities"
 	pbPaymentClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/payment"
+	pbUserClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/user"
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth"
+	"github.com/rasteiro11/MCABankCustomer/pkg/dbdriver"
+	"github.com/rasteiro11/MCABankCustomer/pkg/health"
+	"github.com/rasteiro11/MCABankCustomer/pkg/idempotency"
+	"github.com/rasteiro11/MCABankCustomer/pkg/leader"
+	"github.com/rasteiro11/MCABankCustomer/pkg/metrics"
+	"github.com/rasteiro11/MCABankCustomer/pkg/outbox"
+	"github.com/rasteiro11/MCABankCustomer/pkg/payment"
+	"github.com/rasteiro11/MCABankCustomer/pkg/rbac"
+	"github.com/rasteiro11/MCABankCustomer/pkg/shutdown"
+	"github.com/rasteiro11/MCABankCustomer/pkg/tracing"
+	customerGrpc "github.com/rasteiro11/MCABankCustomer/src/customer/delivery/grpc"
 	customerHttp "github.com/rasteiro11/MCABankCustomer/src/customer/delivery/http"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/reconciler"
 	customerRepo "github.com/rasteiro11/MCABankCustomer/src/customer/repository"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/saga"
 	customerService "github.com/rasteiro11/MCABankCustomer/src/customer/service"
 	"github.com/rasteiro11/PogCore/pkg/config"
-	"github.com/rasteiro11/PogCore/pkg/database"
 	"github.com/rasteiro11/PogCore/pkg/logger"
 	"github.com/rasteiro11/PogCore/pkg/server"
 	"github.com/rasteiro11/PogCore/pkg/telemetry/opentelemetry"
 	"github.com/rasteiro11/PogCore/pkg/telemetry/tracer"
+	"github.com/rasteiro11/PogCore/pkg/transport/grpcserver"
 	fiberSwagger "github.com/swaggo/fiber-swagger"
+	"go.opentelemetry.io/otel"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"gorm.io/gorm"
 
 	_ "github.com/rasteiro11/MCABankCustomer/docs"
 )
 
+const (
+	defaultPolicyFile = "config/policy.yaml"
+	// leaderLease elects the replica that runs the singleton jobs: saga
+	// resumption and reconciliation.
+	leaderLease = "customer-jobs"
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
 	ctx := context.Background()
 	provider := opentelemetry.NewProvider(ctx)
 	tracer.SetGlobal(provider)
 
-	dbInstance, err := database.NewDatabase(database.GetMysqlEngineBuilder)
+	metricsProvider, err := metrics.NewProvider()
 	if err != nil {
-		logger.Of(ctx).Fatalf("[main] database.NewDatabase() returned error: %+v\n", err)
+		logger.Of(ctx).Fatalf("[main] metrics.NewProvider() returned error: %+v\n", err)
 	}
 
-	if err := dbInstance.Migrate(entities.GetEntities()...); err != nil {
-		logger.Of(ctx).Fatalf("[main] database.Migrate() returned error: %+v\n", err)
+	driver := dbdriver.Driver()
+	dbInstance, err := dbdriver.Open(driver)
+	if err != nil {
+		logger.Of(ctx).Fatalf("[main] dbdriver.Open() returned error: %+v\n", err)
 	}
 
 	db := dbInstance.Conn()
 
+	if err := migrateOnStart(ctx, db, driver); err != nil {
+		logger.Of(ctx).Fatalf("[main] migrateOnStart() returned error: %+v\n", err)
+	}
+
+	if err := instrumentDB(db, driver, metricsProvider); err != nil {
+		logger.Of(ctx).Fatalf("[main] instrumentDB() returned error: %+v\n", err)
+	}
+
+	sagaRepo := customerRepo.NewSagaRepository(db)
 	customerRepo := customerRepo.NewCustomerRepository(db)
 
 	credentials := insecure.NewCredentials()
 
+	paymentInterceptor, err := metrics.UnaryClientInterceptor(metricsProvider.Meter())
+	if err != nil {
+		logger.Of(ctx).Fatalf("[main] metrics.UnaryClientInterceptor() returned error: %+v\n", err)
+	}
+
 	paymentConn, err := grpc.Dial(config.Instance().RequiredString("PAYMENT_GRPC_SERVICE"),
+		grpc.WithTransportCredentials(credentials),
+		grpc.WithChainUnaryInterceptor(paymentInterceptor))
+	if err != nil {
+		logger.Of(ctx).Fatalf(
+			"[main] grpc.Dial returned error: err=%+v", err)
+	}
+
+	paymentClient := payment.NewResilientClient(pbPaymentClient.NewBalanceServiceClient(paymentConn),
+		payment.WithTimeout(time.Duration(config.Instance().Int("PAYMENT_TIMEOUT_MS"))*time.Millisecond),
+		payment.WithMaxAttempts(config.Instance().Int("PAYMENT_MAX_ATTEMPTS")),
+		payment.WithBackoff(
+			time.Duration(config.Instance().Int("PAYMENT_BACKOFF_BASE_MS"))*time.Millisecond,
+			time.Duration(config.Instance().Int("PAYMENT_BACKOFF_MAX_MS"))*time.Millisecond,
+		),
+		payment.WithBreaker(
+			config.Instance().Int("PAYMENT_BREAKER_THRESHOLD"),
+			time.Duration(config.Instance().Int("PAYMENT_BREAKER_COOLDOWN_MS"))*time.Millisecond,
+		),
+	)
+
+	authConn, err := grpc.Dial(config.Instance().RequiredString("AUTH_GRPC_SERVICE"),
 		grpc.WithTransportCredentials(credentials))
 	if err != nil {
 		logger.Of(ctx).Fatalf(
 			"[main] grpc.Dial returned error: err=%+v", err)
 	}
 
-	paymentClient := pbPaymentClient.NewBalanceServiceClient(paymentConn)
+	authClient := pbUserClient.NewAuthServiceClient(authConn)
+
+	relay := outbox.NewRelay(outbox.NewStore(db), newEventPublisher(),
+		outbox.WithInterval(time.Duration(config.Instance().Int("OUTBOX_POLL_INTERVAL_MS"))*time.Millisecond),
+		outbox.WithMaxAttempts(config.Instance().Int("OUTBOX_MAX_ATTEMPTS")),
+	)
+
+	workers := shutdown.NewWorkers(ctx)
+	workers.Go(relay.Run)
+
+	readiness := health.NewHealth(
+		health.WithCheck(driver, health.Database(db)),
+		health.WithCheck("payment", health.GRPCChannel(paymentConn)),
+		health.WithTimeout(time.Duration(config.Instance().Int("HEALTH_CHECK_TIMEOUT_MS"))*time.Millisecond),
+	)
+
+	drainer := shutdown.NewDrainer()
+
+	httpMetrics, err := metrics.HTTPMiddleware(metricsProvider.Meter())
+	if err != nil {
+		logger.Of(ctx).Fatalf("[main] metrics.HTTPMiddleware() returned error: %+v\n", err)
+	}
 
 	app := server.NewServer()
+	app.Use("/*", drainer.Middleware())
+	app.Use("/*", tracing.Middleware(otel.GetTracerProvider()))
+	app.Use("/*", httpMetrics)
 	app.AddHandler("/swagger/*", "", http.MethodGet, fiberSwagger.WrapHandler)
+	app.AddHandler(metrics.Path, "", http.MethodGet, metricsProvider.Handler())
+	health.NewHandler(app, readiness)
 	app.Use("/*", cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "*",
+		AllowOrigins:  "*",
+		AllowHeaders:  "*",
+		ExposeHeaders: "ETag",
 	}))
 
-	customerSvc := customerService.NewCustomerService(customerRepo, paymentClient)
+	customerSvc := customerService.NewCustomerService(customerRepo, sagaRepo, paymentClient, authClient,
+		customerService.WithPurgeRetention(purgeRetention()),
+		customerService.WithSagaGracePeriod(time.Duration(config.Instance().Int("SAGA_RESUME_GRACE_SECONDS"))*time.Second),
+	)
+
+	elector := leader.NewElector(leader.NewLease(db, leaderLease,
+		leader.WithTTL(time.Duration(config.Instance().Int("LEADER_LEASE_SECONDS"))*time.Second),
+	))
+	workers.Go(elector.Run)
+
+	sagaJob := saga.NewJob(customerSvc, elector,
+		saga.WithInterval(time.Duration(config.Instance().Int("SAGA_RESUME_INTERVAL_SECONDS"))*time.Second),
+	)
+	workers.Go(sagaJob.Run)
 
-	customerHttp.NewHandler(app, customerHttp.WithCustomerService(customerSvc))
+	// zero disables the schedule; `customerctl reconcile` still works
+	if interval := time.Duration(config.Instance().Int("RECONCILE_INTERVAL_MINUTES")) * time.Minute; interval > 0 {
+		reconcileJob := reconciler.NewJob(reconciler.NewReconciler(customerSvc,
+			reconciler.WithRepair(config.Instance().Bool("RECONCILE_REPAIR")),
+			reconciler.WithBatchSize(config.Instance().Int("RECONCILE_BATCH_SIZE")),
+			reconciler.WithGracePeriod(time.Duration(config.Instance().Int("RECONCILE_GRACE_MINUTES"))*time.Minute),
+		), elector, reconciler.WithInterval(interval))
+		workers.Go(reconcileJob.Run)
+	}
+
+	app.Use(customerHttp.CustomerGroupPath, auth.NewMiddleware(authClient))
+
+	policy, err := rbac.Load(policyFile())
+	if err != nil {
+		logger.Of(ctx).Fatalf("[main] rbac.Load() returned error: %+v\n", err)
+	}
+
+	idempotencyStore := newIdempotencyStore(db)
+	workers.Go(func(ctx context.Context) {
+		idempotency.RunCleanup(ctx, idempotencyStore, time.Hour)
+	})
+
+	// HTTP and gRPC callers authenticate the same way and go through the
+	// same policy
+	policySvc := customerService.NewPolicyService(customerSvc, policy)
+
+	customerHttp.NewHandler(app,
+		customerHttp.WithCustomerService(policySvc),
+		customerHttp.WithIdempotency(idempotency.NewDecorator(idempotencyStore,
+			idempotency.WithTTL(time.Duration(config.Instance().Int("IDEMPOTENCY_TTL_HOURS"))*time.Hour),
+			idempotency.WithScope(sessionScope),
+		)),
+	)
 
 	app.PrintRouter()
 
-	port := config.Instance().RequiredString("SERVER_PORT")
-	if err := app.Start(port); err != nil {
-		logger.Of(ctx).Fatalf("[main] server.Start() returned error: %+v\n", err)
+	grpcServer := newGRPCServer(config.Instance().RequiredString("SERVICE_PORT"),
+		grpc.ChainUnaryInterceptor(auth.NewUnaryServerInterceptor(authClient)),
+	)
+
+	customerGrpc.NewHandler(grpcServer, customerGrpc.WithCustomerService(policySvc))
+
+	go func() {
+		if err := grpcServer.Run(); err != nil {
+			logger.Of(ctx).Fatalf("[main] grpcServer.Run() returned error: %+v\n", err)
+		}
+	}()
+
+	go func() {
+		port := config.Instance().RequiredString("SERVER_PORT")
+		if err := app.Start(port); err != nil {
+			logger.Of(ctx).Fatalf("[main] server.Start() returned error: %+v\n", err)
+		}
+	}()
+
+	signalCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	<-signalCtx.Done()
+	// a second signal kills the process without waiting for the drain
+	stop()
+
+	sequence := shutdown.NewSequence(
+		shutdown.WithDrainPeriod(time.Duration(config.Instance().Int("SHUTDOWN_DRAIN_SECONDS"))*time.Second),
+		shutdown.WithTimeout(time.Duration(config.Instance().Int("SHUTDOWN_TIMEOUT_SECONDS"))*time.Second),
+	)
+	sequence.OnSignal(readiness.Shutdown)
+	sequence.Then("http", func(ctx context.Context) error {
+		drainer.Close()
+		return drainer.Wait(ctx)
+	})
+	sequence.Then("grpc server", func(ctx context.Context) error {
+		return stopGRPCServer(ctx, grpcServer)
+	})
+	sequence.Then("workers", workers.Stop)
+	if provider != nil {
+		sequence.Then("tracer", provider.Shutdown)
+	}
+	sequence.Then("metrics", metricsProvider.Shutdown)
+	sequence.Then("payment conn", func(context.Context) error { return paymentConn.Close() })
+	sequence.Then("auth conn", func(context.Context) error { return authConn.Close() })
+	sequence.Then("database", func(context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+
+	if !sequence.Run(ctx) {
+		os.Exit(1)
+	}
+}
+
+// instrumentDB times gorm statements and exposes the pool stats.
+func instrumentDB(db *gorm.DB, driver string, provider *metrics.Provider) error {
+	plugin, err := metrics.NewGormPlugin(provider.Meter())
+	if err != nil {
+		return err
+	}
+	if err := db.Use(plugin); err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return provider.RegisterDB(sqlDB, driver)
+}
+
+// stopGRPCServer waits for in-flight RPCs to finish, giving up at the
+// deadline.
+func stopGRPCServer(ctx context.Context, s grpcserver.Server) error {
+	done := make(chan struct{})
+	go func() {
+		s.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newEventPublisher() outbox.EventPublisher {
+	url := config.Instance().String("OUTBOX_WEBHOOK_URL")
+	if url == "" {
+		return outbox.NewLogPublisher()
+	}
+
+	return outbox.NewWebhookPublisher(url,
+		outbox.WithSecret(config.Instance().String("OUTBOX_WEBHOOK_SECRET")))
+}
+
+func purgeRetention() time.Duration {
+	days := config.Instance().Int("CUSTOMER_PURGE_RETENTION_DAYS")
+	if days <= 0 {
+		return domain.DefaultPurgeRetention
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func policyFile() string {
+	if path := config.Instance().String("RBAC_POLICY_FILE"); path != "" {
+		return path
+	}
+	return defaultPolicyFile
+}
+
+func newIdempotencyStore(db *gorm.DB) idempotency.Store {
+	if config.Instance().String("IDEMPOTENCY_STORE") == "memory" {
+		return idempotency.NewMemoryStore()
+	}
+	return idempotency.NewStore(db)
+}
+
+// sessionScope keys idempotency records by caller.
+func sessionScope(c *fiber.Ctx) string {
+	session, err := auth.SessionFromFiber(c)
+	if err != nil {
+		return ""
 	}
+	return strconv.FormatUint(session.UserID, 10)
 }
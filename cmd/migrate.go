@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/migrations"
+	"github.com/rasteiro11/MCABankCustomer/pkg/dbdriver"
+	"github.com/rasteiro11/MCABankCustomer/pkg/migrate"
+	"github.com/rasteiro11/PogCore/pkg/config"
+	"github.com/rasteiro11/PogCore/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	// migrationsUp applies pending migrations while starting, the only
+	// sensible choice for a single replica or a local run.
+	migrationsUp = "up"
+	// migrationsCheck refuses to start while migrations are pending, for
+	// deployments that run `migrate up` as a separate step.
+	migrationsCheck = "check"
+	migrationsOff   = "off"
+)
+
+const migrateUsage = `usage: mcabank-customer migrate <command>
+
+commands:
+  up            apply every pending migration
+  down [N]      roll back the last N migrations (default 1); nothing is
+                rolled back when one of them is irreversible
+  to VERSION    migrate up or down to VERSION
+  status        list migrations and whether they are applied (--json)
+  force VERSION mark the schema clean at VERSION without running anything
+`
+
+func newMigrator(db *gorm.DB, driver string) (*migrate.Migrator, error) {
+	all, err := migrations.Load(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewMigrator(db, all,
+		migrate.WithLockTimeout(time.Duration(config.Instance().Int("DATABASE_MIGRATIONS_LOCK_TIMEOUT_SECONDS"))*time.Second),
+	), nil
+}
+
+// migrateOnStart brings the schema up to date or verifies that it is,
+// depending on DATABASE_MIGRATIONS.
+func migrateOnStart(ctx context.Context, db *gorm.DB, driver string) error {
+	mode := config.Instance().String("DATABASE_MIGRATIONS")
+	if mode == "" {
+		mode = migrationsUp
+	}
+	if mode == migrationsOff {
+		return nil
+	}
+
+	migrator, err := newMigrator(db, driver)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case migrationsUp:
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			logger.Of(ctx).Infof("[main] applied migration %04d_%s", m.Version, m.Name)
+		}
+		return err
+	case migrationsCheck:
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if status.Dirty {
+			return fmt.Errorf("%w: version %d", migrate.ErrDirty, status.Current)
+		}
+		if status.Behind() {
+			return fmt.Errorf("schema is behind: at version %d, this build needs %d; run `migrate up`", status.Current, status.Latest)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown DATABASE_MIGRATIONS mode %q, expected up, check or off", mode)
+}
+
+// runMigrate implements the migrate subcommand and returns the exit code.
+func runMigrate(args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	driver := dbdriver.Driver()
+	dbInstance, err := dbdriver.Open(driver)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: opening database: %v\n", err)
+		return 1
+	}
+
+	migrator, err := newMigrator(dbInstance.Conn(), driver)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: loading migrations: %v\n", err)
+		return 1
+	}
+
+	if err := migrateCommand(ctx, migrator, args[0], args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate %s: %v\n", args[0], err)
+		if errors.Is(err, migrate.ErrIrreversible) {
+			fmt.Fprintln(os.Stderr, "nothing was rolled back; restore a backup instead")
+		}
+		return 1
+	}
+	return 0
+}
+
+func migrateCommand(ctx context.Context, migrator *migrate.Migrator, command string, args []string) error {
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		printMigrations("applied", applied)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[0])
+			}
+			steps = n
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		printMigrations("rolled back", rolledBack)
+		return err
+	case "to":
+		version, err := versionArg(args)
+		if err != nil {
+			return err
+		}
+		applied, rolledBack, err := migrator.To(ctx, version)
+		printMigrations("rolled back", rolledBack)
+		printMigrations("applied", applied)
+		return err
+	case "force":
+		version, err := versionArg(args)
+		if err != nil {
+			return err
+		}
+		return migrator.Force(ctx, version)
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 && args[0] == "--json" {
+			return json.NewEncoder(os.Stdout).Encode(status)
+		}
+		printStatus(status)
+		return nil
+	}
+
+	fmt.Fprint(os.Stderr, migrateUsage)
+	return fmt.Errorf("unknown command")
+}
+
+func versionArg(args []string) (uint, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("missing VERSION")
+	}
+	version, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q", args[0])
+	}
+	return uint(version), nil
+}
+
+func printMigrations(verb string, list []migrate.Migration) {
+	for _, m := range list {
+		fmt.Printf("%s %04d_%s\n", verb, m.Version, m.Name)
+	}
+}
+
+func printStatus(status *migrate.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, m := range status.Migrations {
+		state, appliedAt := "pending", ""
+		switch {
+		case m.Dirty:
+			state = "dirty"
+		case m.Applied:
+			state = "applied"
+		}
+		if m.AppliedAt != nil {
+			appliedAt = m.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", m.Version, m.Name, state, appliedAt)
+	}
+	for _, version := range status.Unknown {
+		fmt.Fprintf(w, "%04d\t\tunknown to this build\t\n", version)
+	}
+	w.Flush()
+}
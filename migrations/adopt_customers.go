@@ -0,0 +1,145 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// adoptCustomers is 0002: it brings a customers table created by the
+// baseline, or by AutoMigrate before versioned migrations, to the current
+// shape. Every step checks the schema first, so tables AutoMigrate had
+// already upgraded part way are adopted as well.
+var adoptCustomers = migrate.Migration{
+	Version:      2,
+	Name:         "adopt_customers",
+	UpFunc:       upAdoptCustomers,
+	Irreversible: true,
+}
+
+type addedColumn struct {
+	name   string
+	mysql  string
+	sqlite string
+}
+
+// customerColumns are the columns customers gained after the baseline.
+var customerColumns = []addedColumn{
+	{name: "document", mysql: "varchar(14)", sqlite: "text"},
+	{name: "user_id", mysql: "bigint unsigned", sqlite: "integer"},
+	{name: "deleted_id", mysql: "bigint unsigned NOT NULL DEFAULT 0", sqlite: "integer NOT NULL DEFAULT 0"},
+	{name: "status", mysql: "varchar(16) NOT NULL DEFAULT 'active'", sqlite: "text NOT NULL DEFAULT 'active'"},
+	{name: "version", mysql: "bigint unsigned NOT NULL DEFAULT 1", sqlite: "integer NOT NULL DEFAULT 1"},
+}
+
+type customerIndex struct {
+	name    string
+	unique  bool
+	columns string
+}
+
+// customerIndexes are created after the backfill, so deleted rows have
+// left the live unique indexes before those are built. SQLite checks the
+// most recently created unique index first; email comes after document so
+// a conflict on both reports the email, as MySQL does.
+var customerIndexes = []customerIndex{
+	{name: "idx_customers_document_live", unique: true, columns: "`document`, `deleted_id`"},
+	{name: "idx_customers_email_live", unique: true, columns: "`email`, `deleted_id`"},
+	{name: "idx_customers_user_live", unique: true, columns: "`user_id`, `deleted_id`"},
+	{name: "idx_customers_status", columns: "`status`"},
+	{name: "idx_customers_deleted_at", columns: "`deleted_at`"},
+}
+
+// legacyEmailIndex is the name MySQL gave the inline UNIQUE on email,
+// which made emails unique across deleted customers too.
+const legacyEmailIndex = "email"
+
+// sqliteCustomersTable is the current customers table. SQLite cannot drop
+// the inline UNIQUE on email, so the table is rebuilt from it.
+const sqliteCustomersTable = "CREATE TABLE `customers_adopt` (" +
+	"`id` integer, `created_at` datetime, `updated_at` datetime, `deleted_at` datetime, " +
+	"`nome` text NOT NULL, `email` text NOT NULL, `document` text, `user_id` integer, " +
+	"`deleted_id` integer NOT NULL DEFAULT 0, `status` text NOT NULL DEFAULT 'active', " +
+	"`version` integer NOT NULL DEFAULT 1, PRIMARY KEY (`id`))"
+
+const sqliteCustomersColumns = "`id`, `created_at`, `updated_at`, `deleted_at`, `nome`, `email`, " +
+	"`document`, `user_id`, `deleted_id`, `status`, `version`"
+
+func upAdoptCustomers(tx *gorm.DB) error {
+	driver := tx.Dialector.Name()
+
+	for _, column := range customerColumns {
+		if tx.Migrator().HasColumn("customers", column.name) {
+			continue
+		}
+
+		definition := column.mysql
+		if driver == "sqlite" {
+			definition = column.sqlite
+		}
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE `customers` ADD COLUMN `%s` %s", column.name, definition)).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := dropLegacyEmailIndex(tx, driver); err != nil {
+		return err
+	}
+
+	// Deleted rows used to keep deleted_id at 0 and still sit in the live
+	// indexes.
+	if err := tx.Exec("UPDATE `customers` SET `deleted_id` = `id` WHERE `deleted_at` IS NOT NULL AND `deleted_id` = 0").Error; err != nil {
+		return err
+	}
+
+	for _, index := range customerIndexes {
+		if tx.Migrator().HasIndex("customers", index.name) {
+			continue
+		}
+
+		kind := "INDEX"
+		if index.unique {
+			kind = "UNIQUE INDEX"
+		}
+		if err := tx.Exec(fmt.Sprintf("CREATE %s `%s` ON `customers` (%s)", kind, index.name, index.columns)).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func dropLegacyEmailIndex(tx *gorm.DB, driver string) error {
+	if driver != "sqlite" {
+		if !tx.Migrator().HasIndex("customers", legacyEmailIndex) {
+			return nil
+		}
+		return tx.Exec("ALTER TABLE `customers` DROP INDEX `" + legacyEmailIndex + "`").Error
+	}
+
+	// An inline UNIQUE shows up as an automatic index; the integer primary
+	// key is the rowid and never gets one.
+	var automatic int64
+	if err := tx.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = 'customers' AND name LIKE 'sqlite_autoindex_customers_%'").
+		Scan(&automatic).Error; err != nil {
+		return err
+	}
+	if automatic == 0 {
+		return nil
+	}
+
+	// Dropping the table drops its indexes too; they are created again
+	// afterwards.
+	for _, statement := range []string{
+		sqliteCustomersTable,
+		"INSERT INTO `customers_adopt` (" + sqliteCustomersColumns + ") SELECT " + sqliteCustomersColumns + " FROM `customers`",
+		"DROP TABLE `customers`",
+		"ALTER TABLE `customers_adopt` RENAME TO `customers`",
+	} {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+// Package migrations embeds the versioned schema migrations, one directory
+// per database driver, plus the migrations written in Go that serve both.
+// Both directories must hold the same versions.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/migrate"
+)
+
+//go:embed mysql/*.sql sqlite/*.sql
+var files embed.FS
+
+// goMigrations inspect the schema before changing it, which SQL files
+// cannot do portably.
+var goMigrations = []migrate.Migration{
+	adoptCustomers,
+}
+
+// Load returns the migrations for driver, ordered by version.
+func Load(driver string) ([]migrate.Migration, error) {
+	dir, err := fs.Sub(files, driver)
+	if err != nil {
+		return nil, err
+	}
+	sqlMigrations, err := migrate.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.Merge(sqlMigrations, goMigrations)
+}
@@ -0,0 +1,190 @@
+package migrations_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/migrations"
+	"github.com/rasteiro11/MCABankCustomer/pkg/dbdriver"
+	"github.com/rasteiro11/MCABankCustomer/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// legacyCustomer is the model AutoMigrate created tables from before
+// versioned migrations.
+type legacyCustomer struct {
+	gorm.Model
+	Nome  string `gorm:"not null"`
+	Email string `gorm:"not null;unique"`
+}
+
+func (legacyCustomer) TableName() string {
+	return "customers"
+}
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := dbdriver.OpenSQLite(dbdriver.InMemory)
+	if err != nil {
+		t.Fatalf("OpenSQLite() returned error: %v", err)
+	}
+	return db.Conn()
+}
+
+func up(t *testing.T, db *gorm.DB) *migrate.Migrator {
+	t.Helper()
+
+	all, err := migrations.Load(dbdriver.SQLite)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	migrator := migrate.NewMigrator(db, all)
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Up() returned error: %v", err)
+	}
+	return migrator
+}
+
+func TestLoadMatchesAcrossDrivers(t *testing.T) {
+	mysql, err := migrations.Load(dbdriver.MySQL)
+	if err != nil {
+		t.Fatalf("Load(mysql) returned error: %v", err)
+	}
+	sqlite, err := migrations.Load(dbdriver.SQLite)
+	if err != nil {
+		t.Fatalf("Load(sqlite) returned error: %v", err)
+	}
+
+	if len(mysql) != len(sqlite) {
+		t.Fatalf("mysql has %d migrations, sqlite %d", len(mysql), len(sqlite))
+	}
+	for i := range mysql {
+		if mysql[i].Version != sqlite[i].Version || mysql[i].Name != sqlite[i].Name {
+			t.Errorf("migration %d: mysql %04d_%s, sqlite %04d_%s",
+				i, mysql[i].Version, mysql[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+	}
+}
+
+func TestUpAdoptsAutoMigrateSchema(t *testing.T) {
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&legacyCustomer{}); err != nil {
+		t.Fatalf("AutoMigrate() returned error: %v", err)
+	}
+
+	live := legacyCustomer{Nome: "Live", Email: "live@example.com"}
+	deleted := legacyCustomer{Nome: "Deleted", Email: "gone@example.com"}
+	if err := db.Create(&live).Error; err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if err := db.Create(&deleted).Error; err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if err := db.Delete(&deleted).Error; err != nil {
+		t.Fatalf("Delete() returned error: %v", err)
+	}
+
+	up(t, db)
+
+	for _, column := range []string{"document", "user_id", "deleted_id", "status", "version"} {
+		if !db.Migrator().HasColumn("customers", column) {
+			t.Errorf("customers has no %s column", column)
+		}
+	}
+	for _, index := range []string{"idx_customers_email_live", "idx_customers_document_live", "idx_customers_user_live", "idx_customers_status", "idx_customers_deleted_at"} {
+		if !db.Migrator().HasIndex("customers", index) {
+			t.Errorf("customers has no %s index", index)
+		}
+	}
+
+	var rows []struct {
+		ID        uint
+		Nome      string
+		Status    string
+		Version   uint
+		DeletedID uint
+	}
+	if err := db.Table("customers").Order("id").Find(&rows).Error; err != nil {
+		t.Fatalf("Find() returned error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d customers after adoption, want 2", len(rows))
+	}
+	if rows[0].Nome != "Live" || rows[0].Status != "active" || rows[0].Version != 1 || rows[0].DeletedID != 0 {
+		t.Errorf("live customer adopted as %+v", rows[0])
+	}
+	if rows[1].DeletedID != deleted.ID {
+		t.Errorf("deleted customer has deleted_id %d, want %d", rows[1].DeletedID, deleted.ID)
+	}
+
+	// The legacy index kept deleted emails unique; the live one does not.
+	reused := map[string]any{"nome": "Again", "email": "gone@example.com", "created_at": time.Now(), "updated_at": time.Now()}
+	if err := db.Table("customers").Create(reused).Error; err != nil {
+		t.Errorf("reusing a deleted customer's email returned error: %v", err)
+	}
+	duplicate := map[string]any{"nome": "Twin", "email": "live@example.com", "created_at": time.Now(), "updated_at": time.Now()}
+	if err := db.Table("customers").Create(duplicate).Error; err == nil {
+		t.Error("a second live customer with the same email was accepted")
+	}
+}
+
+func TestUpOnEmptyDatabase(t *testing.T) {
+	db := openSQLite(t)
+	migrator := up(t, db)
+
+	status, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() returned error: %v", err)
+	}
+	if status.Behind() || status.Current != migrator.Latest() {
+		t.Errorf("status after Up is %+v, want current at %d", status, migrator.Latest())
+	}
+
+	for _, table := range []string{"customers", "saga_steps", "customer_status_changes", "outbox_events", "idempotency_keys", "leader_leases"} {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("table %s was not created", table)
+		}
+	}
+
+	// Running again must be a no-op.
+	applied, err := migrator.Up(context.Background())
+	if err != nil || len(applied) != 0 {
+		t.Errorf("second Up() applied %d migrations, error %v", len(applied), err)
+	}
+}
+
+func TestBaselineIsIrreversible(t *testing.T) {
+	db := openSQLite(t)
+	migrator := up(t, db)
+
+	if _, _, err := migrator.To(context.Background(), 0); !errors.Is(err, migrate.ErrIrreversible) {
+		t.Fatalf("To(0) returned %v, want ErrIrreversible", err)
+	}
+	if !db.Migrator().HasTable("customers") || !db.Migrator().HasTable("leader_leases") {
+		t.Fatal("a refused rollback dropped tables")
+	}
+
+	// The reversible tail still rolls back, one step at a time, down to
+	// the service tables.
+	for {
+		rolledBack, err := migrator.Down(context.Background(), 1)
+		if errors.Is(err, migrate.ErrIrreversible) {
+			break
+		}
+		if err != nil || len(rolledBack) != 1 {
+			t.Fatalf("Down(1) rolled back %d migrations, error %v", len(rolledBack), err)
+		}
+	}
+
+	status, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() returned error: %v", err)
+	}
+	if status.Current != 3 {
+		t.Errorf("rollbacks stopped at version %d, want 3", status.Current)
+	}
+}
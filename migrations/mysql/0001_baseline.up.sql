@@ -0,0 +1,14 @@
+-- The customers table exactly as AutoMigrate created it before versioned
+-- migrations. On databases it created this is a no-op; 0002_adopt_customers
+-- then brings both kinds of database to the current shape.
+
+CREATE TABLE IF NOT EXISTS `customers` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `nome` longtext NOT NULL,
+  `email` varchar(191) NOT NULL UNIQUE,
+  PRIMARY KEY (`id`),
+  INDEX `idx_customers_deleted_at` (`deleted_at`)
+);
@@ -0,0 +1,4 @@
+-- irreversible
+-- These tables hold the saga log, the status audit trail, undelivered
+-- events and idempotency records. Dropping them loses history that no up
+-- migration can rebuild.
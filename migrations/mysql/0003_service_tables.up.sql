@@ -0,0 +1,64 @@
+-- Tables added alongside the customers changes. IF NOT EXISTS adopts the
+-- ones AutoMigrate already created.
+
+CREATE TABLE IF NOT EXISTS `saga_steps` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `customer_id` bigint unsigned NOT NULL,
+  `action` varchar(32) NOT NULL,
+  `step` varchar(64) NOT NULL,
+  `status` varchar(32) NOT NULL,
+  `error` text,
+  PRIMARY KEY (`id`),
+  INDEX `idx_saga_steps_deleted_at` (`deleted_at`),
+  INDEX `idx_saga_steps_customer_id` (`customer_id`),
+  INDEX `idx_saga_steps_status` (`status`)
+);
+
+CREATE TABLE IF NOT EXISTS `customer_status_changes` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `customer_id` bigint unsigned NOT NULL,
+  `action` varchar(16) NOT NULL,
+  `from_status` varchar(16) NOT NULL,
+  `to_status` varchar(16) NOT NULL,
+  `reason` text,
+  `changed_by` bigint unsigned NOT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_customer_status_changes_deleted_at` (`deleted_at`),
+  INDEX `idx_customer_status_changes_customer_id` (`customer_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `aggregate_type` varchar(64) NOT NULL,
+  `aggregate_id` bigint unsigned NOT NULL,
+  `type` varchar(128) NOT NULL,
+  `payload` longblob NOT NULL,
+  `status` varchar(32) NOT NULL,
+  `next_attempt_at` datetime(3) NOT NULL,
+  `attempts` bigint NOT NULL DEFAULT 0,
+  `last_error` text,
+  PRIMARY KEY (`id`),
+  INDEX `idx_outbox_due` (`status`, `next_attempt_at`),
+  INDEX `idx_outbox_events_deleted_at` (`deleted_at`),
+  INDEX `idx_outbox_events_aggregate_id` (`aggregate_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `idempotency_keys` (
+  `key` varchar(255),
+  `fingerprint` varchar(64) NOT NULL,
+  `state` varchar(16) NOT NULL,
+  `response` blob,
+  `expires_at` datetime(3) NOT NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`key`),
+  INDEX `idx_idempotency_keys_expires_at` (`expires_at`)
+);
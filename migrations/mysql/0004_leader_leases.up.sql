@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS `leader_leases` (
+  `name` varchar(64) NOT NULL,
+  `holder` varchar(255) NOT NULL,
+  `expires_at` datetime(3) NOT NULL,
+  PRIMARY KEY (`name`)
+);
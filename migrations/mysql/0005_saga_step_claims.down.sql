@@ -0,0 +1 @@
+ALTER TABLE `saga_steps` DROP COLUMN `claimed_until`;
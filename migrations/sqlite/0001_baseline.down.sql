@@ -0,0 +1,3 @@
+-- irreversible
+-- Rolling the baseline back would drop every customer. Restore a backup
+-- instead.
@@ -0,0 +1,15 @@
+-- The customers table exactly as AutoMigrate created it before versioned
+-- migrations. On databases it created this is a no-op; 0002_adopt_customers
+-- then brings both kinds of database to the current shape.
+
+CREATE TABLE IF NOT EXISTS `customers` (
+  `id` integer,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `nome` text NOT NULL,
+  `email` text NOT NULL UNIQUE,
+  PRIMARY KEY (`id`)
+);
+
+CREATE INDEX IF NOT EXISTS `idx_customers_deleted_at` ON `customers` (`deleted_at`);
@@ -0,0 +1,73 @@
+-- Tables added alongside the customers changes. IF NOT EXISTS adopts the
+-- ones AutoMigrate already created.
+
+CREATE TABLE IF NOT EXISTS `saga_steps` (
+  `id` integer,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `customer_id` integer NOT NULL,
+  `action` text NOT NULL,
+  `step` text NOT NULL,
+  `status` text NOT NULL,
+  `error` text,
+  PRIMARY KEY (`id`)
+);
+
+CREATE INDEX IF NOT EXISTS `idx_saga_steps_deleted_at` ON `saga_steps` (`deleted_at`);
+
+CREATE INDEX IF NOT EXISTS `idx_saga_steps_customer_id` ON `saga_steps` (`customer_id`);
+
+CREATE INDEX IF NOT EXISTS `idx_saga_steps_status` ON `saga_steps` (`status`);
+
+CREATE TABLE IF NOT EXISTS `customer_status_changes` (
+  `id` integer,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `customer_id` integer NOT NULL,
+  `action` text NOT NULL,
+  `from_status` text NOT NULL,
+  `to_status` text NOT NULL,
+  `reason` text,
+  `changed_by` integer NOT NULL,
+  PRIMARY KEY (`id`)
+);
+
+CREATE INDEX IF NOT EXISTS `idx_customer_status_changes_deleted_at` ON `customer_status_changes` (`deleted_at`);
+
+CREATE INDEX IF NOT EXISTS `idx_customer_status_changes_customer_id` ON `customer_status_changes` (`customer_id`);
+
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+  `id` integer,
+  `created_at` datetime,
+  `updated_at` datetime,
+  `deleted_at` datetime,
+  `aggregate_type` text NOT NULL,
+  `aggregate_id` integer NOT NULL,
+  `type` text NOT NULL,
+  `payload` blob NOT NULL,
+  `status` text NOT NULL,
+  `next_attempt_at` datetime NOT NULL,
+  `attempts` integer NOT NULL DEFAULT 0,
+  `last_error` text,
+  PRIMARY KEY (`id`)
+);
+
+CREATE INDEX IF NOT EXISTS `idx_outbox_due` ON `outbox_events` (`status`, `next_attempt_at`);
+
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_deleted_at` ON `outbox_events` (`deleted_at`);
+
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_aggregate_id` ON `outbox_events` (`aggregate_id`);
+
+CREATE TABLE IF NOT EXISTS `idempotency_keys` (
+  `key` text,
+  `fingerprint` text NOT NULL,
+  `state` text NOT NULL,
+  `response` blob,
+  `expires_at` datetime NOT NULL,
+  `created_at` datetime,
+  PRIMARY KEY (`key`)
+);
+
+CREATE INDEX IF NOT EXISTS `idx_idempotency_keys_expires_at` ON `idempotency_keys` (`expires_at`);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS `leader_leases` (
+  `name` text NOT NULL,
+  `holder` text NOT NULL,
+  `expires_at` datetime NOT NULL,
+  PRIMARY KEY (`name`)
+);
@@ -0,0 +1,4 @@
+-- Resumers claim a pending step until this time so two never work on it
+-- at once.
+
+ALTER TABLE `saga_steps` ADD COLUMN `claimed_until` datetime;
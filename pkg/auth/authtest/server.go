@@ -0,0 +1,109 @@
+// Package authtest provides an in-memory AuthService to run the customer
+// service against in tests, without a real auth deployment.
+package authtest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	pbUserClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const bufSize = 1 << 20
+
+type session struct {
+	userID    uint64
+	expiresAt time.Time
+}
+
+// Server is a stub AuthService holding users and sessions in memory.
+type Server struct {
+	pbUserClient.UnimplementedAuthServiceServer
+
+	mu       sync.RWMutex
+	users    map[int32]*pbUserClient.GetUserResponse
+	sessions map[string]session
+}
+
+var _ pbUserClient.AuthServiceServer = (*Server)(nil)
+
+func NewServer() *Server {
+	return &Server{
+		users:    make(map[int32]*pbUserClient.GetUserResponse),
+		sessions: make(map[string]session),
+	}
+}
+
+func (s *Server) AddUser(id int32, email, document string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[id] = &pbUserClient.GetUserResponse{Id: id, Email: email, Document: document}
+}
+
+// AddSession makes token verify as userID until expiresAt.
+func (s *Server) AddSession(token string, userID uint64, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[token] = session{userID: userID, expiresAt: expiresAt}
+}
+
+func (s *Server) GetUser(_ context.Context, req *pbUserClient.GetUserRequest) (*pbUserClient.GetUserResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[req.GetId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return user, nil
+}
+
+func (s *Server) VerifySession(_ context.Context, req *pbUserClient.VerifySessionRequest) (*pbUserClient.VerifySessionResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[req.GetToken()]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid session")
+	}
+	return &pbUserClient.VerifySessionResponse{
+		UserId:    sess.userID,
+		ExpiresAt: timestamppb.New(sess.expiresAt),
+	}, nil
+}
+
+// Start serves s over an in-memory listener and returns a client connected
+// to it. The returned func stops the server and closes the connection.
+func (s *Server) Start() (pbUserClient.AuthServiceClient, func(), error) {
+	lis := bufconn.Listen(bufSize)
+
+	srv := grpc.NewServer()
+	pbUserClient.RegisterAuthServiceServer(srv, s)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		srv.Stop()
+		return nil, nil, err
+	}
+
+	stop := func() {
+		conn.Close()
+		srv.Stop()
+	}
+	return pbUserClient.NewAuthServiceClient(conn), stop, nil
+}
@@ -0,0 +1,11 @@
+package auth
+
+import "errors"
+
+var (
+	ErrMissingToken    = errors.New("missing bearer token")
+	ErrInvalidToken    = errors.New("invalid session token")
+	ErrExpiredToken    = errors.New("session token expired")
+	ErrAuthUnavailable = errors.New("auth service unavailable")
+	ErrSessionNotInCtx = errors.New("session not found in context")
+)
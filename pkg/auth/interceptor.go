@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	pbUserClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/user"
+	"github.com/rasteiro11/PogCore/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadataKey carries the bearer token of gRPC calls, the same
+// "Bearer <token>" value HTTP callers send in the Authorization header.
+const metadataKey = "authorization"
+
+// NewUnaryServerInterceptor authenticates every unary RPC the way
+// NewMiddleware authenticates HTTP requests and stores the Session on the
+// call context, so gRPC goes through the same policy as HTTP.
+func NewUnaryServerInterceptor(authClient pbUserClient.AuthServiceClient) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var header string
+		if values := metadata.ValueFromIncomingContext(ctx, metadataKey); len(values) > 0 {
+			header = values[0]
+		}
+
+		token, err := bearerToken(header)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		session, err := verify(ctx, authClient, token)
+		switch {
+		case errors.Is(err, ErrAuthUnavailable):
+			logger.Of(ctx).Errorf("[auth.interceptor] verify() returned error: %+v\n", err)
+			return nil, status.Error(codes.Unavailable, ErrAuthUnavailable.Error())
+		case err != nil:
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		return handler(ContextWithSession(ctx, session), req)
+	}
+}
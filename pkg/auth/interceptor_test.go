@@ -0,0 +1,55 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	pbUserClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/user"
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	client := startAuth(t)
+
+	tests := []struct {
+		name          string
+		client        pbUserClient.AuthServiceClient
+		authorization string
+		wantCode      codes.Code
+	}{
+		{name: "valid session", client: client, authorization: "Bearer valid", wantCode: codes.OK},
+		{name: "missing metadata", client: client, wantCode: codes.Unauthenticated},
+		{name: "not a bearer token", client: client, authorization: "Basic dXNlcjpwYXNz", wantCode: codes.Unauthenticated},
+		{name: "invalid token", client: client, authorization: "Bearer forged", wantCode: codes.Unauthenticated},
+		{name: "expired token", client: client, authorization: "Bearer expired", wantCode: codes.Unauthenticated},
+		{name: "auth is down", client: fakeAuth{err: status.Error(codes.Unavailable, "down")}, authorization: "Bearer valid", wantCode: codes.Unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.authorization))
+			}
+
+			var session *auth.Session
+			handler := func(ctx context.Context, _ any) (any, error) {
+				var err error
+				session, err = auth.SessionFromContext(ctx)
+				return nil, err
+			}
+
+			_, err := auth.NewUnaryServerInterceptor(tt.client)(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("got %v, want %v: %v", code, tt.wantCode, err)
+			}
+			if tt.wantCode == codes.OK && session.UserID != 10 {
+				t.Errorf("handler got session %+v, want user 10", session)
+			}
+		})
+	}
+}
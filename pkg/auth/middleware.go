@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	pbUserClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/user"
+	"github.com/rasteiro11/MCABankCustomer/pkg/problem"
+	"github.com/rasteiro11/PogCore/pkg/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	bearerPrefix = "bearer "
+
+	codeUnauthorized    = "unauthorized"
+	codeAuthUnavailable = "auth_unavailable"
+)
+
+type middleware struct {
+	authClient pbUserClient.AuthServiceClient
+}
+
+// NewMiddleware returns a fiber handler that authenticates the request
+// bearer token against AuthService.VerifySession and stores the resulting
+// Session on the request context.
+func NewMiddleware(authClient pbUserClient.AuthServiceClient) fiber.Handler {
+	m := &middleware{authClient: authClient}
+	return m.handle
+}
+
+func (m *middleware) handle(c *fiber.Ctx) error {
+	token, err := bearerToken(c.Get(fiber.HeaderAuthorization))
+	if err != nil {
+		return unauthorized(c, err)
+	}
+
+	session, err := verify(c.UserContext(), m.authClient, token)
+	switch {
+	case errors.Is(err, ErrAuthUnavailable):
+		logger.Of(c.Context()).Errorf("[auth.middleware] verify() returned error: %+v\n", err)
+		return problem.New(c, http.StatusServiceUnavailable,
+			problem.WithCode(codeAuthUnavailable),
+			problem.WithDetail(ErrAuthUnavailable.Error()))
+	case err != nil:
+		return unauthorized(c, err)
+	}
+
+	setSession(c, session)
+
+	return c.Next()
+}
+
+// verify resolves token to a session. It fails with ErrInvalidToken or
+// ErrExpiredToken when the token is no good, and with an error wrapping
+// ErrAuthUnavailable when AuthService could not tell.
+func verify(ctx context.Context, authClient pbUserClient.AuthServiceClient, token string) (*Session, error) {
+	res, err := authClient.VerifySession(ctx, &pbUserClient.VerifySessionRequest{Token: token})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unauthenticated, codes.InvalidArgument, codes.NotFound, codes.PermissionDenied:
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("%w: %v", ErrAuthUnavailable, err)
+	}
+
+	if res.GetUserId() == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	session := &Session{UserID: res.GetUserId()}
+	if res.GetExpiresAt() != nil {
+		session.ExpiresAt = res.GetExpiresAt().AsTime()
+		if !session.ExpiresAt.After(time.Now()) {
+			return nil, ErrExpiredToken
+		}
+	}
+
+	return session, nil
+}
+
+func bearerToken(header string) (string, error) {
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", ErrMissingToken
+	}
+
+	token := strings.TrimSpace(header[len(bearerPrefix):])
+	if token == "" {
+		return "", ErrMissingToken
+	}
+
+	return token, nil
+}
+
+func unauthorized(c *fiber.Ctx, err error) error {
+	c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+	return problem.New(c, http.StatusUnauthorized,
+		problem.WithCode(codeUnauthorized),
+		problem.WithDetail(err.Error()))
+}
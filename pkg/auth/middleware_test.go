@@ -0,0 +1,144 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	pbUserClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/user"
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth"
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth/authtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeAuth answers VerifySession from a fixed table of tokens, or fails
+// every call with err.
+type fakeAuth struct {
+	pbUserClient.AuthServiceClient
+	sessions map[string]*pbUserClient.VerifySessionResponse
+	err      error
+}
+
+func (f fakeAuth) VerifySession(_ context.Context, req *pbUserClient.VerifySessionRequest, _ ...grpc.CallOption) (*pbUserClient.VerifySessionResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	res, ok := f.sessions[req.GetToken()]
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unknown token")
+	}
+	return res, nil
+}
+
+// newApp serves GET /customers behind the middleware; the handler echoes
+// the user id of the session it got from the fiber locals, the fasthttp
+// context and the user context.
+func newApp(client pbUserClient.AuthServiceClient) *fiber.App {
+	app := fiber.New()
+	app.Use("/customers", auth.NewMiddleware(client))
+	app.Get("/customers", func(c *fiber.Ctx) error {
+		fromFiber, err := auth.SessionFromFiber(c)
+		if err != nil {
+			return err
+		}
+		fromContext, err := auth.SessionFromContext(c.Context())
+		if err != nil {
+			return err
+		}
+		fromUserContext, err := auth.SessionFromContext(c.UserContext())
+		if err != nil {
+			return err
+		}
+		if fromFiber != fromContext || fromFiber != fromUserContext {
+			return errors.New("fiber and context sessions differ")
+		}
+		return c.SendString(strconv.FormatUint(fromFiber.UserID, 10))
+	})
+	return app
+}
+
+func startAuth(t *testing.T) pbUserClient.AuthServiceClient {
+	t.Helper()
+
+	srv := authtest.NewServer()
+	srv.AddSession("valid", 10, time.Now().Add(time.Hour))
+	srv.AddSession("expired", 11, time.Now().Add(-time.Minute))
+
+	client, stop, err := srv.Start()
+	if err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	t.Cleanup(stop)
+	return client
+}
+
+func TestMiddleware(t *testing.T) {
+	client := startAuth(t)
+
+	tests := []struct {
+		name          string
+		client        pbUserClient.AuthServiceClient
+		authorization string
+		wantStatus    int
+		wantBody      string
+	}{
+		{name: "valid session", client: client, authorization: "Bearer valid", wantStatus: http.StatusOK, wantBody: "10"},
+		{name: "scheme is case insensitive", client: client, authorization: "bearer valid", wantStatus: http.StatusOK, wantBody: "10"},
+		{name: "missing header", client: client, wantStatus: http.StatusUnauthorized, wantBody: "unauthorized"},
+		{name: "not a bearer token", client: client, authorization: "Basic dXNlcjpwYXNz", wantStatus: http.StatusUnauthorized},
+		{name: "empty token", client: client, authorization: "Bearer   ", wantStatus: http.StatusUnauthorized},
+		{name: "invalid token", client: client, authorization: "Bearer forged", wantStatus: http.StatusUnauthorized, wantBody: "unauthorized"},
+		{name: "expired token", client: client, authorization: "Bearer expired", wantStatus: http.StatusUnauthorized},
+		{name: "session without a user", client: fakeAuth{sessions: map[string]*pbUserClient.VerifySessionResponse{"valid": {}}}, authorization: "Bearer valid", wantStatus: http.StatusUnauthorized},
+		{name: "auth rejects the token", client: fakeAuth{err: status.Error(codes.PermissionDenied, "revoked")}, authorization: "Bearer valid", wantStatus: http.StatusUnauthorized},
+		{name: "auth is down", client: fakeAuth{err: status.Error(codes.Unavailable, "down")}, authorization: "Bearer valid", wantStatus: http.StatusServiceUnavailable, wantBody: "auth_unavailable"},
+		{name: "auth times out", client: fakeAuth{err: status.Error(codes.DeadlineExceeded, "slow")}, authorization: "Bearer valid", wantStatus: http.StatusServiceUnavailable, wantBody: "auth_unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/customers", nil)
+			if tt.authorization != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.authorization)
+			}
+
+			res, err := newApp(tt.client).Test(req, -1)
+			if err != nil {
+				t.Fatalf("Test() returned error: %v", err)
+			}
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("got %d, want %d: %s", res.StatusCode, tt.wantStatus, body)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body %s does not contain %s", body, tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && res.Header.Get(fiber.HeaderWWWAuthenticate) != "Bearer" {
+				t.Error("401 without a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestSessionFromContext(t *testing.T) {
+	if _, err := auth.SessionFromContext(context.Background()); !errors.Is(err, auth.ErrSessionNotInCtx) {
+		t.Errorf("SessionFromContext() without a session returned %v", err)
+	}
+
+	want := &auth.Session{UserID: 10}
+	got, err := auth.SessionFromContext(auth.ContextWithSession(context.Background(), want))
+	if err != nil || got != want {
+		t.Errorf("SessionFromContext() returned %+v, %v, want %+v", got, err, want)
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sessionKey is a string on purpose: fiber locals are stored as fasthttp
+// user values, which are also what c.Context().Value(key) looks up. The
+// session is copied into c.UserContext() as well, which is what handlers
+// hand to services.
+const sessionKey = "auth.session"
+
+type Session struct {
+	UserID    uint64
+	ExpiresAt time.Time
+}
+
+func setSession(c *fiber.Ctx, s *Session) {
+	c.Locals(sessionKey, s)
+	c.SetUserContext(ContextWithSession(c.UserContext(), s))
+}
+
+func SessionFromFiber(c *fiber.Ctx) (*Session, error) {
+	s, ok := c.Locals(sessionKey).(*Session)
+	if !ok {
+		return nil, ErrSessionNotInCtx
+	}
+	return s, nil
+}
+
+func SessionFromContext(ctx context.Context) (*Session, error) {
+	s, ok := ctx.Value(sessionKey).(*Session)
+	if !ok {
+		return nil, ErrSessionNotInCtx
+	}
+	return s, nil
+}
+
+// ContextWithSession returns a copy of ctx carrying s.
+func ContextWithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey, s)
+}
@@ -0,0 +1,89 @@
+// Package dbdriver opens the database engine selected by configuration:
+// MySQL in deployments, SQLite to run the service or its integration tests
+// without external services.
+package dbdriver
+
+import (
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"github.com/rasteiro11/PogCore/pkg/config"
+	"github.com/rasteiro11/PogCore/pkg/database"
+	"gorm.io/gorm"
+)
+
+const (
+	MySQL  = "mysql"
+	SQLite = "sqlite"
+
+	// InMemory is a SQLite path that keeps the database in memory for the
+	// life of the process.
+	InMemory = ":memory:"
+
+	defaultSQLitePath = "mcabank-customer.db"
+
+	// sqlitePragmas are applied to every connection: wait on locks instead
+	// of failing with SQLITE_BUSY and enforce foreign keys like MySQL does.
+	sqlitePragmas = "_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+)
+
+type sqliteDatabase struct {
+	engine *gorm.DB
+}
+
+var _ database.Database = (*sqliteDatabase)(nil)
+
+func (d *sqliteDatabase) Conn() *gorm.DB {
+	return d.engine
+}
+
+func (d *sqliteDatabase) Migrate(entities ...any) error {
+	return d.engine.AutoMigrate(entities...)
+}
+
+// Driver returns DATABASE_DRIVER, defaulting to MySQL.
+func Driver() string {
+	if driver := config.Instance().String("DATABASE_DRIVER"); driver != "" {
+		return driver
+	}
+	return MySQL
+}
+
+// Open connects to the given driver. MySQL reads the usual DATABASE_*
+// settings; SQLite reads DATABASE_PATH, which may be InMemory.
+func Open(driver string, opts ...database.EngineOpt) (database.Database, error) {
+	switch driver {
+	case MySQL:
+		return database.NewDatabase(database.GetMysqlEngineBuilder, opts...)
+	case SQLite:
+		path := config.Instance().String("DATABASE_PATH")
+		if path == "" {
+			path = defaultSQLitePath
+		}
+		return OpenSQLite(path, opts...)
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownDriver, driver)
+}
+
+// OpenSQLite opens the SQLite database at path. The pool is limited to one
+// connection: SQLite allows a single writer anyway, and an in-memory
+// database only lives as long as its connection.
+func OpenSQLite(path string, opts ...database.EngineOpt) (database.Database, error) {
+	engine, err := gorm.Open(sqlite.Open(path+"?"+sqlitePragmas), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := engine.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	for _, opt := range opts {
+		opt(engine)
+	}
+
+	return &sqliteDatabase{engine: engine}, nil
+}
@@ -0,0 +1,117 @@
+package dbdriver_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/rasteiro11/MCABankCustomer/migrations"
+	"github.com/rasteiro11/MCABankCustomer/pkg/dbdriver"
+	"github.com/rasteiro11/MCABankCustomer/pkg/migrate"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/repository"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/repository/repositorytest"
+	"gorm.io/gorm"
+)
+
+// legacyCustomer is the customers table AutoMigrate created before
+// versioned migrations, with a unique index on email alone.
+type legacyCustomer struct {
+	gorm.Model
+	Nome  string `gorm:"not null"`
+	Email string `gorm:"not null;unique"`
+}
+
+func (legacyCustomer) TableName() string {
+	return "customers"
+}
+
+// schemas are the states a SQLite database can be in when the service
+// first runs migrations on it.
+var schemas = []struct {
+	name  string
+	setup func(db *gorm.DB, all []migrate.Migration) error
+}{
+	{"empty", func(*gorm.DB, []migrate.Migration) error { return nil }},
+	{"baseline", func(db *gorm.DB, all []migrate.Migration) error {
+		_, err := migrate.NewMigrator(db, all[:1]).Up(context.Background())
+		return err
+	}},
+	{"AutoMigrate", func(db *gorm.DB, _ []migrate.Migration) error {
+		return db.AutoMigrate(&legacyCustomer{})
+	}},
+}
+
+func TestOpen(t *testing.T) {
+	t.Setenv("DATABASE_PATH", filepath.Join(t.TempDir(), "customer.db"))
+
+	db, err := dbdriver.Open(dbdriver.SQLite)
+	if err != nil {
+		t.Fatalf("Open(sqlite) returned error: %v", err)
+	}
+	if sqlDB, err := db.Conn().DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	if err := db.Conn().Exec("SELECT 1").Error; err != nil {
+		t.Errorf("query on the opened database returned error: %v", err)
+	}
+
+	if _, err := dbdriver.Open("postgres"); !errors.Is(err, dbdriver.ErrUnknownDriver) {
+		t.Errorf("Open(postgres) returned %v, want ErrUnknownDriver", err)
+	}
+}
+
+func TestDriverDefaultsToMySQL(t *testing.T) {
+	t.Setenv("DATABASE_DRIVER", "")
+	if driver := dbdriver.Driver(); driver != dbdriver.MySQL {
+		t.Errorf("Driver() = %q, want %q", driver, dbdriver.MySQL)
+	}
+
+	t.Setenv("DATABASE_DRIVER", dbdriver.SQLite)
+	if driver := dbdriver.Driver(); driver != dbdriver.SQLite {
+		t.Errorf("Driver() = %q, want %q", driver, dbdriver.SQLite)
+	}
+}
+
+// TestSQLiteConformance migrates SQLite from every starting schema and
+// holds the gorm repository to the same contract as the memory one.
+func TestSQLiteConformance(t *testing.T) {
+	all, err := migrations.Load(dbdriver.SQLite)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	for _, schema := range schemas {
+		t.Run(schema.name, func(t *testing.T) {
+			newRepo := func(t *testing.T) repository.CustomerRepository {
+				t.Helper()
+
+				db, err := dbdriver.OpenSQLite(dbdriver.InMemory)
+				if err != nil {
+					t.Fatalf("OpenSQLite() returned error: %v", err)
+				}
+				if err := schema.setup(db.Conn(), all); err != nil {
+					t.Fatalf("setup returned error: %v", err)
+				}
+
+				migrator := migrate.NewMigrator(db.Conn(), all)
+				if _, err := migrator.Up(context.Background()); err != nil {
+					t.Fatalf("Up() returned error: %v", err)
+				}
+				status, err := migrator.Status(context.Background())
+				if err != nil || status.Behind() {
+					t.Fatalf("Status() after Up is %+v, error %v", status, err)
+				}
+				return repository.NewCustomerRepository(db.Conn())
+			}
+
+			for _, c := range repositorytest.Cases {
+				t.Run(c.Name, func(t *testing.T) {
+					if err := c.Run(context.Background(), newRepo(t)); err != nil {
+						t.Fatal(err)
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+package dbdriver
+
+import "errors"
+
+var ErrUnknownDriver = errors.New("unknown database driver")
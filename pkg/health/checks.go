@@ -0,0 +1,44 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"gorm.io/gorm"
+)
+
+// Database pings the pool behind the gorm connection.
+func Database(db *gorm.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// GRPCChannel reports whether a client connection can carry RPCs. Idle
+// channels are kicked into connecting and given until the check deadline to
+// become ready, so a lazily dialed connection does not fail the first probe.
+func GRPCChannel(conn *grpc.ClientConn) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		for {
+			state := conn.GetState()
+			switch state {
+			case connectivity.Ready:
+				return nil
+			case connectivity.Shutdown, connectivity.TransientFailure:
+				return fmt.Errorf("%w: %s", ErrChannelNotReady, state)
+			case connectivity.Idle:
+				conn.Connect()
+			}
+
+			if !conn.WaitForStateChange(ctx, state) {
+				return fmt.Errorf("%w: %s", ErrChannelNotReady, state)
+			}
+		}
+	})
+}
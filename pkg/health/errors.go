@@ -0,0 +1,8 @@
+package health
+
+import "errors"
+
+var (
+	ErrShuttingDown    = errors.New("service is shutting down")
+	ErrChannelNotReady = errors.New("grpc channel is not ready")
+)
@@ -0,0 +1,56 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rasteiro11/PogCore/pkg/server"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+type handler struct {
+	health *Health
+}
+
+// NewHandler registers the liveness and readiness probes. They sit outside
+// the customer group so they are not behind authentication.
+func NewHandler(server server.Server, health *Health) {
+	h := &handler{health: health}
+
+	server.AddHandler(LivenessPath, "", http.MethodGet, h.Liveness)
+	server.AddHandler(ReadinessPath, "", http.MethodGet, h.Readiness)
+}
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description Reports that the process is up; it does not touch dependencies
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func (h *handler) Liveness(c *fiber.Ctx) error {
+	return c.Status(http.StatusOK).JSON(fiber.Map{"status": StatusUp})
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description Checks the database and the payment gRPC channel and reports per-dependency latency. Returns 503 when a dependency is down or the service is shutting down
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *handler) Readiness(c *fiber.Ctx) error {
+	report := h.health.Readiness(c.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	return c.Status(status).JSON(report)
+}
@@ -0,0 +1,145 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rasteiro11/MCABankCustomer/pkg/dbdriver"
+	"github.com/rasteiro11/MCABankCustomer/pkg/health"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fiberServer adapts a bare fiber app to server.Server.
+type fiberServer struct {
+	app *fiber.App
+}
+
+func (s *fiberServer) AddHandler(path, group, method string, handler fiber.Handler, middlewares ...fiber.Handler) {
+	s.app.Add(method, group+path, append(middlewares, handler)...)
+}
+
+func (s *fiberServer) Use(group string, middlewares ...fiber.Handler) {
+	for _, middleware := range middlewares {
+		s.app.Use(group, middleware)
+	}
+}
+
+func (s *fiberServer) Start(port string) error {
+	return s.app.Listen(port)
+}
+
+func (s *fiberServer) PrintRouter() {}
+
+// paymentConn dials an empty gRPC server over an in-memory listener. A
+// payment service that is down has its listener closed before the dial.
+func paymentConn(t *testing.T, up bool) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	if up {
+		srv := grpc.NewServer()
+		go func() {
+			_ = srv.Serve(lis)
+		}()
+		t.Cleanup(srv.Stop)
+	} else {
+		lis.Close()
+	}
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial() returned error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestProbes(t *testing.T) {
+	tests := []struct {
+		name          string
+		dbDown        bool
+		paymentDown   bool
+		shuttingDown  bool
+		wantReadiness int
+		wantDown      string
+	}{
+		{name: "all dependencies up", wantReadiness: http.StatusOK},
+		{name: "database down", dbDown: true, wantReadiness: http.StatusServiceUnavailable, wantDown: "sqlite"},
+		{name: "payment down", paymentDown: true, wantReadiness: http.StatusServiceUnavailable, wantDown: "payment"},
+		{name: "shutting down", shuttingDown: true, wantReadiness: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := dbdriver.OpenSQLite(dbdriver.InMemory)
+			if err != nil {
+				t.Fatalf("OpenSQLite() returned error: %v", err)
+			}
+			db := conn.Conn()
+			sqlDB, err := db.DB()
+			if err != nil {
+				t.Fatalf("DB() returned error: %v", err)
+			}
+			if tt.dbDown {
+				sqlDB.Close()
+			} else {
+				t.Cleanup(func() { sqlDB.Close() })
+			}
+
+			h := health.NewHealth(
+				health.WithCheck("sqlite", health.Database(db)),
+				health.WithCheck("payment", health.GRPCChannel(paymentConn(t, !tt.paymentDown))),
+				health.WithTimeout(time.Second),
+			)
+			if tt.shuttingDown {
+				h.Shutdown()
+			}
+
+			srv := &fiberServer{app: fiber.New()}
+			health.NewHandler(srv, h)
+
+			res, err := srv.app.Test(httptest.NewRequest(http.MethodGet, health.LivenessPath, nil), -1)
+			if err != nil {
+				t.Fatalf("Test() returned error: %v", err)
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("liveness got %d, want 200", res.StatusCode)
+			}
+
+			res, err = srv.app.Test(httptest.NewRequest(http.MethodGet, health.ReadinessPath, nil), -1)
+			if err != nil {
+				t.Fatalf("Test() returned error: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.wantReadiness {
+				t.Fatalf("readiness got %d, want %d", res.StatusCode, tt.wantReadiness)
+			}
+
+			var report health.Report
+			if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+				t.Fatalf("Decode() returned error: %v", err)
+			}
+			if report.ShuttingDown != tt.shuttingDown {
+				t.Errorf("report shutting_down is %v, want %v", report.ShuttingDown, tt.shuttingDown)
+			}
+			for name, check := range report.Checks {
+				if down := name == tt.wantDown; down != (check.Status == health.StatusDown) {
+					t.Errorf("check %s is %s: %+v", name, check.Status, check)
+				}
+			}
+		})
+	}
+}
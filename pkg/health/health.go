@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultTimeout = 2 * time.Second
+
+type (
+	Opt func(*Health)
+
+	// Health aggregates dependency checks into a readiness report. Once
+	// Shutdown is called the service reports not-ready regardless of its
+	// dependencies so load balancers stop routing new traffic to it.
+	Health struct {
+		checks       map[string]Checker
+		timeout      time.Duration
+		shuttingDown atomic.Bool
+	}
+)
+
+func WithCheck(name string, checker Checker) Opt {
+	return func(h *Health) {
+		h.checks[name] = checker
+	}
+}
+
+func WithTimeout(d time.Duration) Opt {
+	return func(h *Health) {
+		if d > 0 {
+			h.timeout = d
+		}
+	}
+}
+
+func NewHealth(opts ...Opt) *Health {
+	h := &Health{
+		checks:  make(map[string]Checker),
+		timeout: defaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Shutdown flips the service to not-ready. It cannot be undone.
+func (h *Health) Shutdown() {
+	h.shuttingDown.Store(true)
+}
+
+func (h *Health) ShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
+
+// Readiness runs every check concurrently, each bounded by the configured
+// timeout, and reports the service up only if all of them pass.
+func (h *Health) Readiness(ctx context.Context) *Report {
+	report := &Report{
+		Status:       StatusUp,
+		ShuttingDown: h.ShuttingDown(),
+		Checks:       make(map[string]CheckResult, len(h.checks)),
+	}
+
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			results[i] = h.run(ctx, checker)
+		}(i, h.checks[name])
+	}
+	wg.Wait()
+
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	if report.ShuttingDown {
+		report.Status = StatusDown
+	}
+
+	return report
+}
+
+func (h *Health) run(ctx context.Context, checker Checker) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := checker.Check(ctx)
+	result := CheckResult{
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,17 @@
+package health
+
+import "context"
+
+type (
+	// Checker probes a single dependency. A nil error means the dependency
+	// can serve traffic.
+	Checker interface {
+		Check(ctx context.Context) error
+	}
+
+	CheckerFunc func(ctx context.Context) error
+)
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
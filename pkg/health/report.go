@@ -0,0 +1,24 @@
+package health
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+type (
+	CheckResult struct {
+		Status    string  `json:"status"`
+		LatencyMs float64 `json:"latency_ms"`
+		Error     string  `json:"error,omitempty"`
+	}
+
+	Report struct {
+		Status       string                 `json:"status"`
+		ShuttingDown bool                   `json:"shutting_down,omitempty"`
+		Checks       map[string]CheckResult `json:"checks"`
+	}
+)
+
+func (r *Report) Ready() bool {
+	return r.Status == StatusUp
+}
@@ -0,0 +1,30 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasteiro11/PogCore/pkg/logger"
+)
+
+// RunCleanup deletes expired records every interval until ctx is done.
+func RunCleanup(ctx context.Context, store Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := store.DeleteExpired(ctx)
+			if err != nil {
+				logger.Of(ctx).Errorf("[idempotency.RunCleanup] store.DeleteExpired() returned error: %+v\n", err)
+				continue
+			}
+			if n > 0 {
+				logger.Of(ctx).Infof("Deleted %d expired idempotency keys", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rasteiro11/MCABankCustomer/pkg/problem"
+	"github.com/rasteiro11/PogCore/pkg/logger"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+
+	defaultTTL          = 24 * time.Hour
+	defaultLockTTL      = 30 * time.Second
+	defaultWait         = 10 * time.Second
+	defaultPollInterval = 50 * time.Millisecond
+
+	codeKeyInvalid     = "idempotency_key_invalid"
+	codeKeyReused      = "idempotency_key_reused"
+	codeKeyInProgress  = "idempotency_key_in_progress"
+	codeKeyUnavailable = "idempotency_unavailable"
+)
+
+// replayedHeaders are copied from the first response into replays.
+var replayedHeaders = []string{fiber.HeaderETag, fiber.HeaderLocation}
+
+type (
+	Opt func(*decorator)
+
+	// Decorator wraps a single route handler. It is not a fiber middleware
+	// on purpose: middlewares mounted with Use match every method under a
+	// path prefix, while only specific routes should be idempotent.
+	Decorator func(next fiber.Handler) fiber.Handler
+
+	decorator struct {
+		store        Store
+		ttl          time.Duration
+		lockTTL      time.Duration
+		wait         time.Duration
+		pollInterval time.Duration
+		scope        func(c *fiber.Ctx) string
+	}
+)
+
+// WithTTL sets how long a completed response is replayed.
+func WithTTL(d time.Duration) Opt {
+	return func(m *decorator) {
+		if d > 0 {
+			m.ttl = d
+		}
+	}
+}
+
+// WithLockTTL bounds how long an in progress key blocks retries, so a key
+// held by a crashed instance frees up eventually. A live request refreshes
+// its lock every third of lockTTL, however long the handler runs.
+func WithLockTTL(d time.Duration) Opt {
+	return func(m *decorator) {
+		if d > 0 {
+			m.lockTTL = d
+		}
+	}
+}
+
+// WithWait sets how long a concurrent request waits for the first one to
+// finish before giving up with 409.
+func WithWait(d time.Duration) Opt {
+	return func(m *decorator) {
+		if d > 0 {
+			m.wait = d
+		}
+	}
+}
+
+// WithScope namespaces keys, typically by caller, so two clients picking
+// the same key never see each other's responses.
+func WithScope(scope func(c *fiber.Ctx) string) Opt {
+	return func(m *decorator) {
+		m.scope = scope
+	}
+}
+
+// NewDecorator returns a Decorator honouring the Idempotency-Key header.
+// The first request with a key runs and its response is stored; identical
+// retries get the stored response, retries with a different payload get 422
+// and concurrent ones wait for the first to finish. Requests without the
+// header pass through untouched.
+func NewDecorator(store Store, opts ...Opt) Decorator {
+	m := &decorator{
+		store:        store,
+		ttl:          defaultTTL,
+		lockTTL:      defaultLockTTL,
+		wait:         defaultWait,
+		pollInterval: defaultPollInterval,
+		scope:        func(*fiber.Ctx) string { return "" },
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return func(next fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			return m.handle(c, next)
+		}
+	}
+}
+
+func (m *decorator) handle(c *fiber.Ctx, next fiber.Handler) error {
+	key := c.Get(HeaderKey)
+	if key == "" {
+		return next(c)
+	}
+	if len(key) > maxKeyLength {
+		return problem.New(c, http.StatusBadRequest,
+			problem.WithCode(codeKeyInvalid),
+			problem.WithDetail(ErrKeyTooLong.Error()))
+	}
+
+	ctx := c.UserContext()
+	key = m.scope(c) + ":" + key
+	fingerprint := fingerprint(c)
+	deadline := time.Now().Add(m.wait)
+
+	for {
+		rec, acquired, err := m.store.Acquire(ctx, key, fingerprint, m.lockTTL)
+		if err != nil {
+			logger.Of(ctx).Errorf("[idempotency.decorator] store.Acquire() returned error: %+v\n", err)
+			return problem.New(c, http.StatusServiceUnavailable,
+				problem.WithCode(codeKeyUnavailable),
+				problem.WithDetail(ErrStoreUnavailable.Error()))
+		}
+
+		switch {
+		case acquired:
+			return m.run(c, next, key)
+		case rec.Fingerprint != fingerprint:
+			return problem.New(c, http.StatusUnprocessableEntity,
+				problem.WithCode(codeKeyReused),
+				problem.WithDetail(ErrKeyReused.Error()))
+		case rec.State == StateCompleted:
+			return replay(c, rec.Response)
+		}
+
+		if time.Now().After(deadline) {
+			return problem.New(c, http.StatusConflict,
+				problem.WithCode(codeKeyInProgress),
+				problem.WithDetail(ErrKeyInProgress.Error()))
+		}
+		time.Sleep(m.pollInterval)
+	}
+}
+
+// run executes the request holding key. Server errors release the key so
+// the client can retry; anything else is final and gets stored.
+func (m *decorator) run(c *fiber.Ctx, next fiber.Handler, key string) error {
+	// the request may outlive the client, the key must not stay locked
+	ctx := context.Background()
+
+	stop := m.keepLocked(ctx, key)
+	err := next(c)
+	stop()
+
+	if err != nil {
+		m.release(ctx, key)
+		return err
+	}
+
+	status := c.Response().StatusCode()
+	if status >= http.StatusInternalServerError {
+		m.release(ctx, key)
+		return nil
+	}
+
+	res := Response{
+		Status:      status,
+		ContentType: string(c.Response().Header.ContentType()),
+		Headers:     map[string]string{},
+		Body:        append([]byte(nil), c.Response().Body()...),
+	}
+	for _, h := range replayedHeaders {
+		if v := c.GetRespHeader(h); v != "" {
+			res.Headers[h] = v
+		}
+	}
+
+	if err := m.store.Complete(ctx, key, res, m.ttl); err != nil {
+		logger.Of(ctx).Errorf("[idempotency.decorator] store.Complete() returned error: %+v\n", err)
+		m.release(ctx, key)
+	}
+	return nil
+}
+
+// keepLocked extends the lock on key until the returned func is called.
+func (m *decorator) keepLocked(ctx context.Context, key string) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(m.lockTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			if err := m.store.Extend(ctx, key, m.lockTTL); err != nil {
+				logger.Of(ctx).Warnf("[idempotency.decorator] store.Extend() returned error: %+v\n", err)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (m *decorator) release(ctx context.Context, key string) {
+	if err := m.store.Release(ctx, key); err != nil {
+		logger.Of(ctx).Errorf("[idempotency.decorator] store.Release() returned error: %+v\n", err)
+	}
+}
+
+func replay(c *fiber.Ctx, res Response) error {
+	for h, v := range res.Headers {
+		c.Set(h, v)
+	}
+	c.Set(HeaderReplayed, "true")
+	c.Set(fiber.HeaderContentType, res.ContentType)
+	return c.Status(res.Status).Send(res.Body)
+}
+
+// fingerprint identifies the request payload a key was first used with.
+func fingerprint(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method()))
+	h.Write([]byte{0})
+	h.Write([]byte(c.Path()))
+	h.Write([]byte{0})
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
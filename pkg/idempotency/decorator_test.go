@@ -0,0 +1,90 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newApp(store Store, handler fiber.Handler, opts ...Opt) *fiber.App {
+	app := fiber.New()
+	app.Post("/customers", NewDecorator(store, opts...)(handler))
+	return app
+}
+
+func post(key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/customers", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(HeaderKey, key)
+	return req
+}
+
+func TestDecoratorReplaysCompletedRequests(t *testing.T) {
+	runs := 0
+	app := newApp(NewMemoryStore(), func(c *fiber.Ctx) error {
+		runs++
+		return c.Status(http.StatusCreated).SendString("created")
+	})
+
+	for i := 0; i < 2; i++ {
+		res, err := app.Test(post("k", `{"nome":"Ana"}`))
+		if err != nil {
+			t.Fatalf("Test() returned error: %v", err)
+		}
+		if res.StatusCode != http.StatusCreated {
+			t.Fatalf("request %d got %d, want 201", i, res.StatusCode)
+		}
+		if replayed := res.Header.Get(HeaderReplayed) == "true"; replayed != (i == 1) {
+			t.Errorf("request %d replayed %v", i, replayed)
+		}
+	}
+	if runs != 1 {
+		t.Errorf("handler ran %d times, want 1", runs)
+	}
+
+	res, err := app.Test(post("k", `{"nome":"Bia"}`))
+	if err != nil {
+		t.Fatalf("Test() returned error: %v", err)
+	}
+	if res.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("reused key got %d, want 422", res.StatusCode)
+	}
+}
+
+func TestDecoratorKeepsLongRequestsLocked(t *testing.T) {
+	const lockTTL = 30 * time.Millisecond
+
+	store := NewMemoryStore()
+	running := make(chan struct{})
+	app := newApp(store, func(c *fiber.Ctx) error {
+		close(running)
+		time.Sleep(5 * lockTTL)
+		return c.SendStatus(http.StatusCreated)
+	}, WithLockTTL(lockTTL))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := app.Test(post("k", `{}`), -1); err != nil {
+			t.Errorf("Test() returned error: %v", err)
+		}
+	}()
+
+	<-running
+	time.Sleep(3 * lockTTL)
+
+	// the handler has run for longer than lockTTL and still holds the key
+	_, acquired, err := store.Acquire(context.Background(), ":k", "", lockTTL)
+	if err != nil {
+		t.Fatalf("Acquire() returned error: %v", err)
+	}
+	if acquired {
+		t.Error("the lock of a running request expired")
+	}
+	<-done
+}
@@ -0,0 +1,10 @@
+package idempotency
+
+import "errors"
+
+var (
+	ErrKeyTooLong       = errors.New("Idempotency-Key must be at most 255 characters")
+	ErrKeyReused        = errors.New("Idempotency-Key was already used with a different request")
+	ErrKeyInProgress    = errors.New("a request with this Idempotency-Key is still being processed")
+	ErrStoreUnavailable = errors.New("idempotency store unavailable")
+)
@@ -0,0 +1,26 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// Store keeps one Record per idempotency key. Acquire must be atomic:
+	// of several concurrent calls for the same key exactly one acquires it.
+	Store interface {
+		// Acquire creates an in progress record for key, locked until
+		// lockTTL elapses. When a live record already exists it is returned
+		// with acquired false instead.
+		Acquire(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (rec *Record, acquired bool, err error)
+		// Extend pushes the lock of an in progress key to lockTTL from now,
+		// so a request running longer than lockTTL keeps holding it.
+		Extend(ctx context.Context, key string, lockTTL time.Duration) error
+		// Complete stores the response of an acquired key and keeps it for ttl.
+		Complete(ctx context.Context, key string, res Response, ttl time.Duration) error
+		// Release drops an acquired key so the request can be retried.
+		Release(ctx context.Context, key string) error
+		// DeleteExpired removes records past their expiry.
+		DeleteExpired(ctx context.Context) (int64, error)
+	}
+)
@@ -0,0 +1,90 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryStore keeps records in process. It suits a single instance and
+// tests; replicas behind a load balancer need the gorm store.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+var _ Store = (*memoryStore)(nil)
+
+func NewMemoryStore() Store {
+	return &memoryStore{records: make(map[string]*Record)}
+}
+
+func (s *memoryStore) Acquire(_ context.Context, key, fingerprint string, lockTTL time.Duration) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if rec, ok := s.records[key]; ok && !rec.expired(now) {
+		copied := *rec
+		return &copied, false, nil
+	}
+
+	rec := &Record{
+		Key:         key,
+		Fingerprint: fingerprint,
+		State:       StateInProgress,
+		ExpiresAt:   now.Add(lockTTL),
+	}
+	s.records[key] = rec
+
+	copied := *rec
+	return &copied, true, nil
+}
+
+func (s *memoryStore) Extend(_ context.Context, key string, lockTTL time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[key]; ok && rec.State == StateInProgress {
+		rec.ExpiresAt = time.Now().Add(lockTTL)
+	}
+	return nil
+}
+
+func (s *memoryStore) Complete(_ context.Context, key string, res Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[key]; ok {
+		rec.State = StateCompleted
+		rec.Response = res
+		rec.ExpiresAt = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+func (s *memoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[key]; ok && rec.State == StateInProgress {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteExpired(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var n int64
+	for key, rec := range s.records {
+		if rec.expired(now) {
+			delete(s.records, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,31 @@
+package idempotency
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type IdempotencyKey struct {
+	Key         string    `gorm:"primaryKey;size:255"`
+	Fingerprint string    `gorm:"not null;size:64"`
+	State       string    `gorm:"not null;size:16"`
+	Response    []byte    `gorm:"type:blob"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   time.Time
+}
+
+func toRecord(m *IdempotencyKey) (*Record, error) {
+	rec := &Record{
+		Key:         m.Key,
+		Fingerprint: m.Fingerprint,
+		State:       State(m.State),
+		ExpiresAt:   m.ExpiresAt,
+	}
+
+	if len(m.Response) > 0 {
+		if err := json.Unmarshal(m.Response, &rec.Response); err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
@@ -0,0 +1,30 @@
+package idempotency
+
+import "time"
+
+type State string
+
+const (
+	StateInProgress State = "in_progress"
+	StateCompleted  State = "completed"
+)
+
+// Response is what gets replayed to retries of a completed request.
+type Response struct {
+	Status      int               `json:"status"`
+	ContentType string            `json:"content_type"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        []byte            `json:"body"`
+}
+
+type Record struct {
+	Key         string
+	Fingerprint string
+	State       State
+	Response    Response
+	ExpiresAt   time.Time
+}
+
+func (r *Record) expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
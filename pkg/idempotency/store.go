@@ -0,0 +1,100 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+var _ Store = (*gormStore)(nil)
+
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+// Acquire relies on the primary key: the insert that wins acquires the key,
+// everybody else reads the winner's record. An expired record is removed
+// first so its key can be reused.
+func (s *gormStore) Acquire(ctx context.Context, key, fingerprint string, lockTTL time.Duration) (*Record, bool, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now().UTC()
+
+	if err := db.
+		Where(&IdempotencyKey{Key: key}).
+		Where("expires_at <= ?", now).
+		Delete(&IdempotencyKey{}).Error; err != nil {
+		return nil, false, err
+	}
+
+	m := &IdempotencyKey{
+		Key:         key,
+		Fingerprint: fingerprint,
+		State:       string(StateInProgress),
+		ExpiresAt:   now.Add(lockTTL),
+	}
+
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(m)
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		rec, err := toRecord(m)
+		return rec, true, err
+	}
+
+	var existing IdempotencyKey
+	if err := db.Where(&IdempotencyKey{Key: key}).First(&existing).Error; err != nil {
+		// the holder released the key between our insert and read
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.Acquire(ctx, key, fingerprint, lockTTL)
+		}
+		return nil, false, err
+	}
+
+	rec, err := toRecord(&existing)
+	return rec, false, err
+}
+
+func (s *gormStore) Extend(ctx context.Context, key string, lockTTL time.Duration) error {
+	return s.db.WithContext(ctx).
+		Model(&IdempotencyKey{}).
+		Where(&IdempotencyKey{Key: key, State: string(StateInProgress)}).
+		Update("expires_at", time.Now().UTC().Add(lockTTL)).Error
+}
+
+func (s *gormStore) Complete(ctx context.Context, key string, res Response, ttl time.Duration) error {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).
+		Model(&IdempotencyKey{}).
+		Where(&IdempotencyKey{Key: key}).
+		Updates(map[string]any{
+			"state":      string(StateCompleted),
+			"response":   b,
+			"expires_at": time.Now().UTC().Add(ttl),
+		}).Error
+}
+
+func (s *gormStore) Release(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).
+		Where(&IdempotencyKey{Key: key, State: string(StateInProgress)}).
+		Delete(&IdempotencyKey{}).Error
+}
+
+func (s *gormStore) DeleteExpired(ctx context.Context) (int64, error) {
+	res := s.db.WithContext(ctx).
+		Where("expires_at <= ?", time.Now().UTC()).
+		Delete(&IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,67 @@
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/rasteiro11/PogCore/pkg/logger"
+)
+
+const releaseTimeout = 5 * time.Second
+
+// Elector keeps trying to hold a lease and renews it while it does, a third
+// of the TTL at a time so one failed renewal does not cost leadership.
+type Elector struct {
+	lease  *Lease
+	leader atomic.Bool
+}
+
+func NewElector(lease *Lease) *Elector {
+	return &Elector{lease: lease}
+}
+
+// IsLeader reports whether the last acquisition or renewal succeeded.
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run campaigns until ctx is cancelled, then releases the lease.
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.lease.TTL() / 3)
+	defer ticker.Stop()
+
+	for {
+		e.campaign(ctx)
+
+		select {
+		case <-ctx.Done():
+			e.leader.Store(false)
+
+			releaseCtx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+			defer cancel()
+			if err := e.lease.Release(releaseCtx); err != nil {
+				logger.Of(ctx).Errorf("[leader.Elector] lease.Release() returned error: %+v\n", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) campaign(ctx context.Context) {
+	acquired, err := e.lease.TryAcquire(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Of(ctx).Errorf("[leader.Elector] lease.TryAcquire() returned error: %+v\n", err)
+		}
+		acquired = false
+	}
+
+	switch was := e.leader.Swap(acquired); {
+	case acquired && !was:
+		logger.Of(ctx).Infof("[leader.Elector] became leader of %s", e.lease.name)
+	case !acquired && was:
+		logger.Of(ctx).Infof("[leader.Elector] lost leadership of %s", e.lease.name)
+	}
+}
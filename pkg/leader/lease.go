@@ -0,0 +1,113 @@
+// Package leader elects one replica to run a singleton job, using a row in
+// the leader_leases table as a lease that its holder keeps renewing.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultTTL = 30 * time.Second
+
+type leaderLease struct {
+	Name      string `gorm:"primaryKey"`
+	Holder    string
+	ExpiresAt time.Time
+}
+
+func (leaderLease) TableName() string {
+	return "leader_leases"
+}
+
+type (
+	LeaseOpt func(*Lease)
+
+	// Lease is a named lock that expires unless renewed, so a crashed
+	// holder cannot keep it. Expiry is judged by the clocks of the
+	// replicas, which must agree to well within the TTL.
+	Lease struct {
+		db     *gorm.DB
+		name   string
+		holder string
+		ttl    time.Duration
+	}
+)
+
+func WithTTL(d time.Duration) LeaseOpt {
+	return func(l *Lease) {
+		if d > 0 {
+			l.ttl = d
+		}
+	}
+}
+
+// WithHolder overrides the holder identity, hostname:pid by default.
+func WithHolder(holder string) LeaseOpt {
+	return func(l *Lease) {
+		if holder != "" {
+			l.holder = holder
+		}
+	}
+}
+
+func NewLease(db *gorm.DB, name string, opts ...LeaseOpt) *Lease {
+	hostname, _ := os.Hostname()
+
+	l := &Lease{
+		db:     db,
+		name:   name,
+		holder: fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		ttl:    defaultTTL,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+func (l *Lease) TTL() time.Duration {
+	return l.ttl
+}
+
+// TryAcquire takes the lease when it is free or expired and extends it
+// when already held by this holder. It reports whether the caller holds
+// the lease for the next TTL.
+func (l *Lease) TryAcquire(ctx context.Context) (bool, error) {
+	db := l.db.WithContext(ctx)
+	now := time.Now().UTC()
+
+	result := db.Model(&leaderLease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", l.name, l.holder, now).
+		Updates(map[string]any{"holder": l.holder, "expires_at": now.Add(l.ttl)})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	result = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&leaderLease{
+		Name:      l.name,
+		Holder:    l.holder,
+		ExpiresAt: now.Add(l.ttl),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Release gives the lease up if this holder has it, so another replica
+// does not have to wait for it to expire.
+func (l *Lease) Release(ctx context.Context) error {
+	return l.db.WithContext(ctx).
+		Where("name = ? AND holder = ?", l.name, l.holder).
+		Delete(&leaderLease{}).Error
+}
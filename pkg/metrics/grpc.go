@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor records the duration and status code of every
+// outgoing unary RPC on the connection it is installed on.
+func UnaryClientInterceptor(meter metric.Meter) (grpc.UnaryClientInterceptor, error) {
+	duration, err := meter.Float64Histogram("rpc.client.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of outgoing gRPC calls"),
+		metric.WithExplicitBucketBoundaries(latencyBuckets...))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+			attribute.String("rpc.method", method),
+			attribute.String("rpc.grpc.status_code", status.Code(err).String()),
+		))
+
+		return err
+	}, nil
+}
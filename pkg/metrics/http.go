@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// unmatchedRoute labels requests no route handled, so scanners probing
+// random paths do not blow up the label cardinality.
+const unmatchedRoute = "unmatched"
+
+// HTTPMiddleware records the rate, errors and duration of every request by
+// method, route template and status. Register it with app.Use before any
+// route.
+func HTTPMiddleware(meter metric.Meter) (fiber.Handler, error) {
+	duration, err := meter.Float64Histogram("http.server.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP requests handled by the server"),
+		metric.WithExplicitBucketBoundaries(latencyBuckets...))
+	if err != nil {
+		return nil, err
+	}
+
+	inFlight, err := meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithDescription("HTTP requests currently being handled"))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *fiber.Ctx) error {
+		self := c.Route()
+		start := time.Now()
+		ctx := c.UserContext()
+
+		inFlight.Add(ctx, 1)
+		defer inFlight.Add(ctx, -1)
+
+		err := c.Next()
+
+		route := c.Route().Path
+		if c.Route() == self {
+			route = unmatchedRoute
+		}
+
+		duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+			attribute.String("http.request.method", c.Method()),
+			attribute.String("http.route", route),
+			attribute.String("http.response.status_code", strconv.Itoa(statusOf(c, err))),
+		))
+
+		return err
+	}, nil
+}
+
+// statusOf returns the status the error handler will write for err, which
+// has not been applied to the response yet when the middleware sees it.
+func statusOf(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
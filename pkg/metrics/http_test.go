@@ -0,0 +1,71 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rasteiro11/MCABankCustomer/pkg/metrics"
+)
+
+const durationCount = "http_server_request_duration_seconds_count"
+
+func TestHTTPMiddlewareLabelsRoutes(t *testing.T) {
+	provider, err := metrics.NewProvider()
+	if err != nil {
+		t.Fatalf("NewProvider() returned error: %v", err)
+	}
+	middleware, err := metrics.HTTPMiddleware(provider.Meter())
+	if err != nil {
+		t.Fatalf("HTTPMiddleware() returned error: %v", err)
+	}
+
+	app := fiber.New()
+	app.Use(middleware)
+	app.Get(metrics.Path, provider.Handler())
+	app.Get("/customers/:id", func(c *fiber.Ctx) error {
+		return c.SendString(c.Params("id"))
+	})
+
+	for _, path := range []string{"/customers/7", "/customers/8", "/wp-login.php", "/.env"} {
+		res, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), -1)
+		if err != nil {
+			t.Fatalf("Test() returned error: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, metrics.Path, nil), -1)
+	if err != nil {
+		t.Fatalf("Test() returned error: %v", err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+
+	counts := map[string]string{}
+	for _, line := range strings.Split(string(body), "\n") {
+		if !strings.HasPrefix(line, durationCount+"{") {
+			continue
+		}
+		for _, route := range []string{"/customers/:id", "unmatched"} {
+			if strings.Contains(line, `http_route="`+route+`"`) {
+				counts[route] = line[strings.LastIndex(line, " ")+1:]
+			}
+		}
+		for _, raw := range []string{"/customers/7", "/wp-login.php", "/.env"} {
+			if strings.Contains(line, `"`+raw+`"`) {
+				t.Errorf("raw path %s is a label: %s", raw, line)
+			}
+		}
+	}
+
+	if counts["/customers/:id"] != "2" {
+		t.Errorf("route /customers/:id counted %q requests, want 2:\n%s", counts["/customers/:id"], body)
+	}
+	if counts["unmatched"] != "2" {
+		t.Errorf("unmatched paths counted %q requests, want 2:\n%s", counts["unmatched"], body)
+	}
+}
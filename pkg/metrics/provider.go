@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+const (
+	Path = "/metrics"
+
+	instrumentationName = "github.com/rasteiro11/MCABankCustomer/pkg/metrics"
+)
+
+// latencyBuckets are in seconds, from 1ms to 10s.
+var latencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Provider owns the Prometheus registry and the OpenTelemetry meter provider
+// bridged into it. Instruments created through otel.Meter anywhere in the
+// process are exposed on the same endpoint as the runtime collectors.
+type Provider struct {
+	registry      *prometheus.Registry
+	meterProvider *sdkmetric.MeterProvider
+	meter         metric.Meter
+}
+
+// NewProvider builds the provider and installs it as the global meter
+// provider.
+func NewProvider() (*Provider, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collectors.NewGoCollector()); err != nil {
+		return nil, err
+	}
+	if err := registry.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+		return nil, err
+	}
+
+	exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, err
+	}
+
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(exporter))
+	otel.SetMeterProvider(meterProvider)
+
+	return &Provider{
+		registry:      registry,
+		meterProvider: meterProvider,
+		meter:         meterProvider.Meter(instrumentationName),
+	}, nil
+}
+
+func (p *Provider) Meter() metric.Meter {
+	return p.meter
+}
+
+// RegisterDB exposes the connection pool stats of db labelled with name.
+func (p *Provider) RegisterDB(db *sql.DB, name string) error {
+	return p.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (p *Provider) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{}))
+}
+
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.meterProvider.Shutdown(ctx)
+}
@@ -0,0 +1,14 @@
+package migrate
+
+import "errors"
+
+var (
+	ErrBadFilename    = errors.New("migration file name must look like 0001_name.up.sql or 0001_name.down.sql")
+	ErrMissingPair    = errors.New("migration is missing its up or down file")
+	ErrDuplicate      = errors.New("migration version is defined twice")
+	ErrUnknownVersion = errors.New("unknown migration version")
+	ErrDirty          = errors.New("a migration failed halfway, repair the schema and clear its dirty flag")
+	ErrLockTimeout    = errors.New("timed out waiting for the migration lock")
+	ErrIrreversible   = errors.New("migration cannot be rolled back")
+	ErrTooManySteps   = errors.New("fewer migrations are applied than asked to roll back")
+)
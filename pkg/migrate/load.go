@@ -0,0 +1,177 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var filenamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// irreversibleMarker is a down file line that forbids rolling the
+// migration back.
+const irreversibleMarker = "-- irreversible"
+
+// Migration is one schema version, read from a pair of up and down files
+// or written in Go.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      []string
+	Down    []string
+	// UpFunc and DownFunc run after Up and Down, in the same transaction,
+	// for changes that depend on what the schema already holds.
+	UpFunc   func(tx *gorm.DB) error
+	DownFunc func(tx *gorm.DB) error
+	// Irreversible migrations refuse to roll back: undoing them would
+	// destroy data that no down migration can bring back. A migration with
+	// neither Down nor DownFunc is irreversible too, so a forgotten down is
+	// never recorded as rolled back while its schema stays.
+	Irreversible bool
+}
+
+type migrationFiles struct {
+	Migration
+	hasUp   bool
+	hasDown bool
+}
+
+// Load reads the *.sql files at the root of fsys and returns them ordered by
+// version. Every version needs both its up and its down file. A down file
+// with a "-- irreversible" line, or without statements, refuses to roll
+// back at all.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*migrationFiles)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		match := filenamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s", ErrBadFilename, entry.Name())
+		}
+
+		parsed, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil || parsed == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrBadFilename, entry.Name())
+		}
+		version := uint(parsed)
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migrationFiles{Migration: Migration{Version: version, Name: match[2]}}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicate, entry.Name())
+		}
+
+		switch {
+		case match[3] == "up" && !m.hasUp:
+			m.Up, m.hasUp = Split(string(content)), true
+		case match[3] == "down" && !m.hasDown:
+			m.Down, m.hasDown = Split(string(content)), true
+			m.Irreversible = irreversible(string(content))
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrDuplicate, entry.Name())
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if !m.hasUp || !m.hasDown {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrMissingPair, m.Version, m.Name)
+		}
+		migrations = append(migrations, m.Migration)
+	}
+
+	sortByVersion(migrations)
+
+	return migrations, nil
+}
+
+// Merge combines sets of migrations, typically the ones Load read from
+// files and the ones written in Go, ordered by version.
+func Merge(sets ...[]Migration) ([]Migration, error) {
+	seen := make(map[uint]bool)
+
+	var merged []Migration
+	for _, set := range sets {
+		for _, m := range set {
+			if seen[m.Version] {
+				return nil, fmt.Errorf("%w: %04d_%s", ErrDuplicate, m.Version, m.Name)
+			}
+			seen[m.Version] = true
+			merged = append(merged, m)
+		}
+	}
+
+	sortByVersion(merged)
+
+	return merged, nil
+}
+
+func sortByVersion(migrations []Migration) {
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
+
+func irreversible(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == irreversibleMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// Split breaks a migration file into statements. The MySQL driver runs one
+// statement per call, so a statement ends at a semicolon that closes its
+// line. Comment lines and blank statements are dropped.
+func Split(content string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		if strings.HasSuffix(trimmed, ";") {
+			current.WriteString(strings.TrimSuffix(trimmed, ";"))
+			flush()
+			continue
+		}
+
+		current.WriteString(trimmed)
+		current.WriteString("\n")
+	}
+	flush()
+
+	return statements
+}
@@ -0,0 +1,440 @@
+// Package migrate applies versioned SQL migrations and records them in the
+// schema_migrations table. A row lock in schema_migrations_lock keeps
+// replicas starting together from migrating the same database twice.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultLockTimeout = time.Minute
+	defaultStaleLock   = 10 * time.Minute
+	lockPollInterval   = 500 * time.Millisecond
+	lockID             = 1
+)
+
+// bookkeeping creates the migrator's own tables. The statements are valid
+// on both MySQL and SQLite; plain datetime is the only time type the SQLite
+// driver scans back into time.Time.
+var bookkeeping = []string{
+	"CREATE TABLE IF NOT EXISTS `schema_migrations` (" +
+		"`version` bigint NOT NULL, `name` varchar(255) NOT NULL, " +
+		"`dirty` boolean NOT NULL DEFAULT false, `applied_at` datetime NOT NULL, " +
+		"PRIMARY KEY (`version`))",
+	"CREATE TABLE IF NOT EXISTS `schema_migrations_lock` (" +
+		"`id` int NOT NULL, `owner` varchar(255) NOT NULL, `acquired_at` datetime NOT NULL, " +
+		"PRIMARY KEY (`id`))",
+}
+
+type schemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type schemaLock struct {
+	ID         uint `gorm:"primaryKey;autoIncrement:false"`
+	Owner      string
+	AcquiredAt time.Time
+}
+
+func (schemaLock) TableName() string {
+	return "schema_migrations_lock"
+}
+
+type (
+	Opt func(*Migrator)
+
+	// Migrator moves a database between the versions of a set of
+	// migrations. Each migration runs in a transaction; on engines where
+	// DDL commits implicitly (MySQL) a failure leaves the version marked
+	// dirty and further runs refuse to proceed until it is forced clean.
+	Migrator struct {
+		db               *gorm.DB
+		migrations       []Migration
+		lockTimeout      time.Duration
+		staleLock        time.Duration
+		owner            string
+		transactionalDDL bool
+	}
+)
+
+// WithLockTimeout bounds how long a run waits for another one to finish.
+func WithLockTimeout(d time.Duration) Opt {
+	return func(m *Migrator) {
+		if d > 0 {
+			m.lockTimeout = d
+		}
+	}
+}
+
+// WithStaleLock sets the age after which a lock is presumed to belong to a
+// crashed run and is taken over. It must exceed the longest migration.
+func WithStaleLock(d time.Duration) Opt {
+	return func(m *Migrator) {
+		if d > 0 {
+			m.staleLock = d
+		}
+	}
+}
+
+// NewMigrator expects migrations ordered by version, as Load returns them.
+func NewMigrator(db *gorm.DB, migrations []Migration, opts ...Opt) *Migrator {
+	hostname, _ := os.Hostname()
+
+	m := &Migrator{
+		db:               db,
+		migrations:       migrations,
+		lockTimeout:      defaultLockTimeout,
+		staleLock:        defaultStaleLock,
+		owner:            fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		transactionalDDL: db.Dialector.Name() == "sqlite",
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Latest is the highest known version, 0 when there are no migrations.
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status reports which migrations are applied. It takes no lock.
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	if err := m.ensureTables(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStatus(m.migrations, applied), nil
+}
+
+// Pending returns the known migrations that are not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for i, s := range status.Migrations {
+		if !s.Applied {
+			pending = append(pending, m.migrations[i])
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration in version order.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied, _, err := m.To(ctx, m.Latest())
+	return applied, err
+}
+
+// Down rolls back the given number of most recently applied migrations.
+// Nothing is rolled back when one of them is irreversible or fewer are
+// applied.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.locked(ctx, func(applied map[uint]schemaMigration) error {
+		var targets []Migration
+		for i := len(m.migrations) - 1; i >= 0 && len(targets) < steps; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				targets = append(targets, m.migrations[i])
+			}
+		}
+
+		if len(targets) < steps {
+			return fmt.Errorf("%w: asked for %d, %d applied", ErrTooManySteps, steps, len(targets))
+		}
+		if err := reversible(targets); err != nil {
+			return err
+		}
+
+		for _, migration := range targets {
+			if err := m.rollback(ctx, migration); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// To applies every pending migration up to version and rolls back every
+// applied one above it. Version 0 rolls everything back. Nothing is rolled
+// back when one of the migrations above version is irreversible.
+func (m *Migrator) To(ctx context.Context, version uint) (applied, rolledBack []Migration, err error) {
+	if version != 0 && !m.known(version) {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	err = m.locked(ctx, func(done map[uint]schemaMigration) error {
+		var targets []Migration
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := done[m.migrations[i].Version]; ok && m.migrations[i].Version > version {
+				targets = append(targets, m.migrations[i])
+			}
+		}
+
+		if err := reversible(targets); err != nil {
+			return err
+		}
+
+		for _, migration := range targets {
+			if err := m.rollback(ctx, migration); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if err := m.apply(ctx, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, rolledBack, err
+}
+
+// Force records the schema as being exactly at version with no dirty flag,
+// without running any migration. It is the way out after a failed
+// migration has been repaired by hand.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if version != 0 && !m.known(version) {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	if err := m.ensureTables(ctx); err != nil {
+		return err
+	}
+
+	return m.withLock(ctx, func() error {
+		return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("version > ?", version).Delete(&schemaMigration{}).Error; err != nil {
+				return err
+			}
+
+			for _, migration := range m.migrations {
+				if migration.Version > version {
+					break
+				}
+				row := schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}
+				if err := tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "version"}},
+					DoUpdates: clause.Assignments(map[string]any{"dirty": false}),
+				}).Create(&row).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func reversible(migrations []Migration) error {
+	for _, migration := range migrations {
+		if migration.Irreversible || (len(migration.Down) == 0 && migration.DownFunc == nil) {
+			return fmt.Errorf("%w: %04d_%s", ErrIrreversible, migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) known(version uint) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// locked runs fn under the lock with the applied versions read after
+// acquiring it, refusing to touch a dirty schema.
+func (m *Migrator) locked(ctx context.Context, fn func(applied map[uint]schemaMigration) error) error {
+	if err := m.ensureTables(ctx); err != nil {
+		return err
+	}
+
+	return m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for version, row := range applied {
+			if row.Dirty {
+				return fmt.Errorf("%w: version %d", ErrDirty, version)
+			}
+		}
+
+		return fn(applied)
+	})
+}
+
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, statement := range migration.Up {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if migration.UpFunc != nil {
+			if err := migration.UpFunc(tx); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&schemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		m.markDirty(migration)
+		return fmt.Errorf("applying %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) rollback(ctx context.Context, migration Migration) error {
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, statement := range migration.Down {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if migration.DownFunc != nil {
+			if err := migration.DownFunc(tx); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&schemaMigration{}, migration.Version).Error
+	})
+	if err != nil {
+		m.markDirty(migration)
+		return fmt.Errorf("rolling back %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// markDirty records a migration that failed after some of its statements
+// may have been committed. Engines with transactional DDL rolled the
+// whole migration back, so there is nothing to record.
+func (m *Migrator) markDirty(migration Migration) {
+	if m.transactionalDDL {
+		return
+	}
+
+	m.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "version"}},
+		DoUpdates: clause.Assignments(map[string]any{"dirty": true}),
+	}).Create(&schemaMigration{
+		Version:   migration.Version,
+		Name:      migration.Name,
+		Dirty:     true,
+		AppliedAt: time.Now().UTC(),
+	})
+}
+
+func (m *Migrator) ensureTables(ctx context.Context) error {
+	for _, statement := range bookkeeping {
+		if err := m.db.WithContext(ctx).Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[uint]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := m.db.WithContext(ctx).Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[uint]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// withLock holds the migration lock while fn runs. A lock older than the
+// stale threshold is assumed abandoned by a crashed run and taken over.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	lockCtx, cancel := context.WithTimeout(ctx, m.lockTimeout)
+	defer cancel()
+
+	for {
+		acquired, err := m.tryLock(lockCtx)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			break
+		}
+
+		select {
+		case <-lockCtx.Done():
+			if errors.Is(lockCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+				return ErrLockTimeout
+			}
+			return lockCtx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	defer m.db.Where("id = ? AND owner = ?", lockID, m.owner).Delete(&schemaLock{})
+
+	return fn()
+}
+
+func (m *Migrator) tryLock(ctx context.Context) (bool, error) {
+	db := m.db.WithContext(ctx)
+
+	stale := time.Now().UTC().Add(-m.staleLock)
+	if err := db.Where("id = ? AND acquired_at < ?", lockID, stale).Delete(&schemaLock{}).Error; err != nil {
+		return false, err
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&schemaLock{
+		ID:         lockID,
+		Owner:      m.owner,
+		AcquiredAt: time.Now().UTC(),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
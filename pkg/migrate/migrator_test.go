@@ -0,0 +1,395 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/dbdriver"
+	"github.com/rasteiro11/MCABankCustomer/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// testFiles creates a table per version; version 3 has no down statements
+// and version 4 is marked irreversible.
+var testFiles = fstest.MapFS{
+	"0002_second.up.sql":       {Data: []byte("CREATE TABLE `second` (`id` int);")},
+	"0002_second.down.sql":     {Data: []byte("DROP TABLE `second`;")},
+	"0001_first.up.sql":        {Data: []byte("CREATE TABLE `first` (`id` int);")},
+	"0001_first.down.sql":      {Data: []byte("DROP TABLE `first`;")},
+	"0010_tenth.up.sql":        {Data: []byte("CREATE TABLE `tenth` (`id` int);")},
+	"0010_tenth.down.sql":      {Data: []byte("DROP TABLE `tenth`;")},
+	"0003_empty_down.up.sql":   {Data: []byte("CREATE TABLE `third` (`id` int);")},
+	"0003_empty_down.down.sql": {Data: []byte("-- nothing to undo\n")},
+	"0004_marked.up.sql":       {Data: []byte("CREATE TABLE `fourth` (`id` int);")},
+	"0004_marked.down.sql":     {Data: []byte("-- irreversible\nDROP TABLE `fourth`;")},
+	"README.md":                {Data: []byte("not a migration")},
+}
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := dbdriver.OpenSQLite(dbdriver.InMemory)
+	if err != nil {
+		t.Fatalf("OpenSQLite() returned error: %v", err)
+	}
+	return db.Conn()
+}
+
+// reversibleMigrations are versions 1, 2 and 10, all of which roll back.
+func reversibleMigrations(t *testing.T) []migrate.Migration {
+	t.Helper()
+
+	files := fstest.MapFS{}
+	for _, name := range []string{"0001_first", "0002_second", "0010_tenth"} {
+		files[name+".up.sql"] = testFiles[name+".up.sql"]
+		files[name+".down.sql"] = testFiles[name+".down.sql"]
+	}
+
+	all, err := migrate.Load(files)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	return all
+}
+
+func versions(migrations []migrate.Migration) []uint {
+	var versions []uint
+	for _, migration := range migrations {
+		versions = append(versions, migration.Version)
+	}
+	return versions
+}
+
+func equalVersions(got []migrate.Migration, want ...uint) bool {
+	v := versions(got)
+	if len(v) != len(want) {
+		return false
+	}
+	for i := range v {
+		if v[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTable(db *gorm.DB, name string) bool {
+	return db.Migrator().HasTable(name)
+}
+
+func TestLoad(t *testing.T) {
+	all, err := migrate.Load(testFiles)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	if !equalVersions(all, 1, 2, 3, 4, 10) {
+		t.Fatalf("Load() returned versions %v, want [1 2 3 4 10]", versions(all))
+	}
+	if all[0].Name != "first" || len(all[0].Up) != 1 || len(all[0].Down) != 1 {
+		t.Errorf("first migration is %+v", all[0])
+	}
+	if !all[3].Irreversible {
+		t.Errorf("migration with the marker is not irreversible")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr error
+	}{
+		{name: "bad filename", files: fstest.MapFS{
+			"first.up.sql": {},
+		}, wantErr: migrate.ErrBadFilename},
+		{name: "version zero", files: fstest.MapFS{
+			"0000_zero.up.sql":   {},
+			"0000_zero.down.sql": {},
+		}, wantErr: migrate.ErrBadFilename},
+		{name: "missing down", files: fstest.MapFS{
+			"0001_first.up.sql": {},
+		}, wantErr: migrate.ErrMissingPair},
+		{name: "missing up", files: fstest.MapFS{
+			"0001_first.down.sql": {},
+		}, wantErr: migrate.ErrMissingPair},
+		{name: "two names for a version", files: fstest.MapFS{
+			"0001_first.up.sql":   {},
+			"0001_first.down.sql": {},
+			"0001_other.up.sql":   {},
+		}, wantErr: migrate.ErrDuplicate},
+		{name: "padded and unpadded version", files: fstest.MapFS{
+			"0001_first.up.sql":   {},
+			"0001_first.down.sql": {},
+			"1_first.up.sql":      {},
+		}, wantErr: migrate.ErrDuplicate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := migrate.Load(tt.files); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Load() returned error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpAppliesInVersionOrder(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+
+	all, err := migrate.Merge(reversibleMigrations(t), []migrate.Migration{{
+		Version: 5,
+		Name:    "in_go",
+		UpFunc: func(tx *gorm.DB) error {
+			if !hasTable(tx, "second") || hasTable(tx, "tenth") {
+				t.Errorf("version 5 ran out of order")
+			}
+			return nil
+		},
+		DownFunc: func(*gorm.DB) error { return nil },
+	}})
+	if err != nil {
+		t.Fatalf("Merge() returned error: %v", err)
+	}
+
+	applied, err := migrate.NewMigrator(db, all).Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() returned error: %v", err)
+	}
+	if !equalVersions(applied, 1, 2, 5, 10) {
+		t.Fatalf("Up() applied %v, want [1 2 5 10]", versions(applied))
+	}
+
+	applied, err = migrate.NewMigrator(db, all).Up(ctx)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("second Up() applied %v, %v; want nothing", versions(applied), err)
+	}
+}
+
+func TestDown(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator := migrate.NewMigrator(db, reversibleMigrations(t))
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up() returned error: %v", err)
+	}
+
+	rolledBack, err := migrator.Down(ctx, 1)
+	if err != nil {
+		t.Fatalf("Down(1) returned error: %v", err)
+	}
+	if !equalVersions(rolledBack, 10) || hasTable(db, "tenth") {
+		t.Fatalf("Down(1) rolled back %v", versions(rolledBack))
+	}
+
+	if _, err := migrator.Down(ctx, 3); !errors.Is(err, migrate.ErrTooManySteps) {
+		t.Fatalf("Down(3) with two applied returned error %v, want %v", err, migrate.ErrTooManySteps)
+	}
+	if !hasTable(db, "first") || !hasTable(db, "second") {
+		t.Fatalf("refused Down(3) rolled migrations back")
+	}
+
+	rolledBack, err = migrator.Down(ctx, 2)
+	if err != nil {
+		t.Fatalf("Down(2) returned error: %v", err)
+	}
+	if !equalVersions(rolledBack, 2, 1) {
+		t.Fatalf("Down(2) rolled back %v, want [2 1]", versions(rolledBack))
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() returned error: %v", err)
+	}
+	if status.Current != 0 || !status.Behind() {
+		t.Errorf("status after rolling everything back is %+v", status)
+	}
+}
+
+func TestDownRefusesIrreversible(t *testing.T) {
+	tests := []struct {
+		name    string
+		version uint
+	}{
+		{name: "down without statements", version: 3},
+		{name: "down marked irreversible", version: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := openSQLite(t)
+
+			all, err := migrate.Load(testFiles)
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			migrator := migrate.NewMigrator(db, all)
+			if _, _, err := migrator.To(ctx, tt.version); err != nil {
+				t.Fatalf("To(%d) returned error: %v", tt.version, err)
+			}
+
+			rolledBack, err := migrator.Down(ctx, 2)
+			if !errors.Is(err, migrate.ErrIrreversible) {
+				t.Fatalf("Down(2) returned error %v, want %v", err, migrate.ErrIrreversible)
+			}
+			if len(rolledBack) != 0 {
+				t.Fatalf("Down(2) rolled back %v, want nothing", versions(rolledBack))
+			}
+
+			_, rolledBack, err = migrator.To(ctx, 1)
+			if !errors.Is(err, migrate.ErrIrreversible) || len(rolledBack) != 0 {
+				t.Fatalf("To(1) rolled back %v, %v; want nothing and %v", versions(rolledBack), err, migrate.ErrIrreversible)
+			}
+
+			status, err := migrator.Status(ctx)
+			if err != nil {
+				t.Fatalf("Status() returned error: %v", err)
+			}
+			if status.Current != tt.version {
+				t.Errorf("current version is %d, want %d", status.Current, tt.version)
+			}
+		})
+	}
+}
+
+func TestTo(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator := migrate.NewMigrator(db, reversibleMigrations(t))
+
+	applied, rolledBack, err := migrator.To(ctx, 2)
+	if err != nil {
+		t.Fatalf("To(2) returned error: %v", err)
+	}
+	if !equalVersions(applied, 1, 2) || len(rolledBack) != 0 {
+		t.Fatalf("To(2) applied %v and rolled back %v", versions(applied), versions(rolledBack))
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up() returned error: %v", err)
+	}
+
+	applied, rolledBack, err = migrator.To(ctx, 1)
+	if err != nil {
+		t.Fatalf("To(1) returned error: %v", err)
+	}
+	if len(applied) != 0 || !equalVersions(rolledBack, 10, 2) {
+		t.Fatalf("To(1) applied %v and rolled back %v", versions(applied), versions(rolledBack))
+	}
+
+	if _, _, err := migrator.To(ctx, 7); !errors.Is(err, migrate.ErrUnknownVersion) {
+		t.Errorf("To(7) returned error %v, want %v", err, migrate.ErrUnknownVersion)
+	}
+}
+
+func TestDirty(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator := migrate.NewMigrator(db, reversibleMigrations(t))
+
+	if _, _, err := migrator.To(ctx, 1); err != nil {
+		t.Fatalf("To(1) returned error: %v", err)
+	}
+
+	// What a failed migration leaves behind on MySQL.
+	if err := db.Exec("INSERT INTO `schema_migrations` (`version`, `name`, `dirty`, `applied_at`) VALUES (2, 'second', true, ?)", time.Now().UTC()).Error; err != nil {
+		t.Fatalf("marking version 2 dirty returned error: %v", err)
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() returned error: %v", err)
+	}
+	if !status.Dirty || !status.Migrations[1].Dirty || status.Migrations[1].Applied {
+		t.Fatalf("status of a dirty schema is %+v", status)
+	}
+
+	if _, err := migrator.Up(ctx); !errors.Is(err, migrate.ErrDirty) {
+		t.Errorf("Up() returned error %v, want %v", err, migrate.ErrDirty)
+	}
+	if _, err := migrator.Down(ctx, 1); !errors.Is(err, migrate.ErrDirty) {
+		t.Errorf("Down(1) returned error %v, want %v", err, migrate.ErrDirty)
+	}
+
+	if err := migrator.Force(ctx, 1); err != nil {
+		t.Fatalf("Force(1) returned error: %v", err)
+	}
+	status, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() returned error: %v", err)
+	}
+	if status.Dirty || status.Current != 1 {
+		t.Fatalf("status after Force(1) is %+v", status)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() after Force returned error: %v", err)
+	}
+	if !equalVersions(applied, 2, 10) {
+		t.Errorf("Up() after Force applied %v, want [2 10]", versions(applied))
+	}
+}
+
+func TestFailedMigrationIsNotRecorded(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+
+	all, err := migrate.Merge(reversibleMigrations(t), []migrate.Migration{{
+		Version: 3,
+		Name:    "broken",
+		Up:      []string{"CREATE TABLE `broken` (`id` int)", "NOT SQL"},
+		Down:    []string{"DROP TABLE `broken`"},
+	}})
+	if err != nil {
+		t.Fatalf("Merge() returned error: %v", err)
+	}
+	migrator := migrate.NewMigrator(db, all)
+
+	applied, err := migrator.Up(ctx)
+	if err == nil {
+		t.Fatalf("Up() with a broken migration returned no error")
+	}
+	if !equalVersions(applied, 1, 2) {
+		t.Fatalf("Up() applied %v, want [1 2]", versions(applied))
+	}
+	if hasTable(db, "broken") {
+		t.Errorf("the broken migration's first statement was kept")
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() returned error: %v", err)
+	}
+	if status.Dirty || status.Current != 2 {
+		t.Errorf("status after a failed migration on SQLite is %+v", status)
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator := migrate.NewMigrator(db, reversibleMigrations(t), migrate.WithLockTimeout(50*time.Millisecond))
+
+	if _, err := migrator.Status(ctx); err != nil {
+		t.Fatalf("Status() returned error: %v", err)
+	}
+	if err := db.Exec("INSERT INTO `schema_migrations_lock` (`id`, `owner`, `acquired_at`) VALUES (1, 'other', ?)", time.Now().UTC()).Error; err != nil {
+		t.Fatalf("taking the lock returned error: %v", err)
+	}
+
+	if _, err := migrator.Up(ctx); !errors.Is(err, migrate.ErrLockTimeout) {
+		t.Fatalf("Up() under another run's lock returned error %v, want %v", err, migrate.ErrLockTimeout)
+	}
+
+	stale := migrate.NewMigrator(db, reversibleMigrations(t), migrate.WithStaleLock(time.Nanosecond))
+	if _, err := stale.Up(ctx); err != nil {
+		t.Fatalf("Up() over a stale lock returned error: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"sort"
+	"time"
+)
+
+type (
+	MigrationStatus struct {
+		Version   uint       `json:"version"`
+		Name      string     `json:"name"`
+		Applied   bool       `json:"applied"`
+		Dirty     bool       `json:"dirty,omitempty"`
+		AppliedAt *time.Time `json:"appliedAt,omitempty"`
+	}
+
+	// Status describes the schema against the known migrations. Unknown
+	// lists versions recorded in the database that this build does not
+	// ship, which happens after rolling back to an older release.
+	Status struct {
+		Current    uint              `json:"current"`
+		Latest     uint              `json:"latest"`
+		Dirty      bool              `json:"dirty"`
+		Migrations []MigrationStatus `json:"migrations"`
+		Unknown    []uint            `json:"unknown,omitempty"`
+	}
+)
+
+// Behind reports whether any known migration is still pending.
+func (s *Status) Behind() bool {
+	for _, migration := range s.Migrations {
+		if !migration.Applied {
+			return true
+		}
+	}
+	return false
+}
+
+func newStatus(migrations []Migration, applied map[uint]schemaMigration) *Status {
+	status := &Status{Migrations: make([]MigrationStatus, 0, len(migrations))}
+	if len(migrations) > 0 {
+		status.Latest = migrations[len(migrations)-1].Version
+	}
+
+	known := make(map[uint]bool, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = true
+
+		s := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			s.Applied = !row.Dirty
+			s.Dirty = row.Dirty
+			s.AppliedAt = &appliedAt
+		}
+		status.Migrations = append(status.Migrations, s)
+	}
+
+	for version, row := range applied {
+		if version > status.Current {
+			status.Current = version
+		}
+		if row.Dirty {
+			status.Dirty = true
+		}
+		if !known[version] {
+			status.Unknown = append(status.Unknown, version)
+		}
+	}
+	sort.Slice(status.Unknown, func(i, j int) bool {
+		return status.Unknown[i] < status.Unknown[j]
+	})
+
+	return status
+}
@@ -0,0 +1,34 @@
+package outbox
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffBounds(t *testing.T) {
+	const (
+		base    = time.Second
+		ceiling = 10 * time.Second
+	)
+	r := NewRelay(nil, nil, WithBackoff(base, ceiling))
+
+	tests := []struct {
+		attempts int
+		step     time.Duration
+	}{
+		{attempts: 1, step: base},
+		{attempts: 2, step: 2 * base},
+		{attempts: 3, step: 4 * base},
+		{attempts: 4, step: 8 * base},
+		{attempts: 5, step: ceiling},
+		{attempts: 64, step: ceiling},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 1000; i++ {
+			if got := r.backoff(tt.attempts); got < tt.step/2 || got > tt.step {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.attempts, got, tt.step/2, tt.step)
+			}
+		}
+	}
+}
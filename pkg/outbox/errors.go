@@ -0,0 +1,5 @@
+package outbox
+
+import "errors"
+
+var ErrUnexpectedStatus = errors.New("webhook responded with unexpected status")
@@ -0,0 +1,41 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending    Status = "pending"
+	StatusPublished  Status = "published"
+	StatusDeadLetter Status = "dead_letter"
+)
+
+type Event struct {
+	ID            uint            `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uint            `json:"aggregate_id"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        Status          `json:"-"`
+	Attempts      int             `json:"-"`
+	LastError     string          `json:"-"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+}
+
+func NewEvent(aggregateType string, aggregateID uint, eventType string, payload any) (*Event, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Type:          eventType,
+		Payload:       b,
+		OccurredAt:    time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,21 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// EventPublisher delivers an outbox event to downstream consumers.
+	// Delivery is at-least-once, so consumers should dedupe on Event.ID.
+	EventPublisher interface {
+		Publish(ctx context.Context, event *Event) error
+	}
+
+	Store interface {
+		Add(ctx context.Context, event *Event) error
+		Claim(ctx context.Context, limit int, lease time.Duration) ([]Event, error)
+		MarkPublished(ctx context.Context, id uint) error
+		MarkFailed(ctx context.Context, id uint, cause error, retryAt *time.Time) error
+	}
+)
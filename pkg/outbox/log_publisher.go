@@ -0,0 +1,21 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/rasteiro11/PogCore/pkg/logger"
+)
+
+type logPublisher struct{}
+
+var _ EventPublisher = (*logPublisher)(nil)
+
+func NewLogPublisher() EventPublisher {
+	return &logPublisher{}
+}
+
+func (p *logPublisher) Publish(ctx context.Context, event *Event) error {
+	logger.Of(ctx).Infof("[outbox] event id=%d type=%s aggregate=%s/%d payload=%s",
+		event.ID, event.Type, event.AggregateType, event.AggregateID, event.Payload)
+	return nil
+}
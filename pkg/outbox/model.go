@@ -0,0 +1,45 @@
+package outbox
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OutboxEvent struct {
+	gorm.Model
+	AggregateType string    `gorm:"not null;size:64"`
+	AggregateID   uint      `gorm:"not null;index"`
+	Type          string    `gorm:"not null;size:128"`
+	Payload       []byte    `gorm:"not null"`
+	Status        string    `gorm:"not null;size:32;index:idx_outbox_due,priority:1"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_outbox_due,priority:2"`
+	Attempts      int       `gorm:"not null;default:0"`
+	LastError     string    `gorm:"type:text"`
+}
+
+func fromEvent(e *Event) *OutboxEvent {
+	return &OutboxEvent{
+		Model:         gorm.Model{ID: e.ID, CreatedAt: e.OccurredAt},
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		Type:          e.Type,
+		Payload:       e.Payload,
+		Status:        string(StatusPending),
+		NextAttemptAt: e.OccurredAt,
+	}
+}
+
+func toEvent(m *OutboxEvent) *Event {
+	return &Event{
+		ID:            m.ID,
+		AggregateType: m.AggregateType,
+		AggregateID:   m.AggregateID,
+		Type:          m.Type,
+		Payload:       m.Payload,
+		Status:        Status(m.Status),
+		Attempts:      m.Attempts,
+		LastError:     m.LastError,
+		OccurredAt:    m.CreatedAt,
+	}
+}
@@ -0,0 +1,173 @@
+package outbox
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/rasteiro11/PogCore/pkg/logger"
+)
+
+const (
+	defaultInterval    = time.Second
+	defaultBatchSize   = 100
+	defaultMaxAttempts = 10
+	defaultBaseBackoff = time.Second
+	defaultMaxBackoff  = 5 * time.Minute
+	defaultLease       = time.Minute
+)
+
+type (
+	RelayOpt func(*Relay)
+
+	// Relay polls the outbox store and hands due events to the publisher.
+	// Events are retried with exponential backoff and moved to the
+	// dead-letter state after maxAttempts failed deliveries.
+	Relay struct {
+		store       Store
+		publisher   EventPublisher
+		interval    time.Duration
+		batchSize   int
+		maxAttempts int
+		baseBackoff time.Duration
+		maxBackoff  time.Duration
+		lease       time.Duration
+	}
+)
+
+func WithInterval(d time.Duration) RelayOpt {
+	return func(r *Relay) {
+		if d > 0 {
+			r.interval = d
+		}
+	}
+}
+
+func WithBatchSize(n int) RelayOpt {
+	return func(r *Relay) {
+		if n > 0 {
+			r.batchSize = n
+		}
+	}
+}
+
+func WithMaxAttempts(n int) RelayOpt {
+	return func(r *Relay) {
+		if n > 0 {
+			r.maxAttempts = n
+		}
+	}
+}
+
+func WithBackoff(base, ceiling time.Duration) RelayOpt {
+	return func(r *Relay) {
+		if base > 0 {
+			r.baseBackoff = base
+		}
+		if ceiling > 0 {
+			r.maxBackoff = ceiling
+		}
+	}
+}
+
+func WithLease(d time.Duration) RelayOpt {
+	return func(r *Relay) {
+		if d > 0 {
+			r.lease = d
+		}
+	}
+}
+
+func NewRelay(store Store, publisher EventPublisher, opts ...RelayOpt) *Relay {
+	r := &Relay{
+		store:       store,
+		publisher:   publisher,
+		interval:    defaultInterval,
+		batchSize:   defaultBatchSize,
+		maxAttempts: defaultMaxAttempts,
+		baseBackoff: defaultBaseBackoff,
+		maxBackoff:  defaultMaxBackoff,
+		lease:       defaultLease,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Run polls until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+			logger.Of(ctx).Errorf("[outbox.Relay] Flush() returned error: %+v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush publishes one batch of due events and returns how many were
+// delivered.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	events, err := r.store.Claim(ctx, r.batchSize, r.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for i := range events {
+		event := &events[i]
+
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			r.fail(ctx, event, err)
+			continue
+		}
+
+		if err := r.store.MarkPublished(ctx, event.ID); err != nil {
+			// the event stays claimed and is redelivered after the lease
+			logger.Of(ctx).Errorf("[outbox.Relay] store.MarkPublished() returned error: %+v\n", err)
+			continue
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+func (r *Relay) fail(ctx context.Context, event *Event, cause error) {
+	attempts := event.Attempts + 1
+
+	var retryAt *time.Time
+	if attempts < r.maxAttempts {
+		t := time.Now().Add(r.backoff(attempts))
+		retryAt = &t
+	} else {
+		logger.Of(ctx).Errorf("[outbox.Relay] event %d (%s) dead-lettered after %d attempts: %+v\n", event.ID, event.Type, attempts, cause)
+	}
+
+	if err := r.store.MarkFailed(ctx, event.ID, cause, retryAt); err != nil {
+		logger.Of(ctx).Errorf("[outbox.Relay] store.MarkFailed() returned error: %+v\n", err)
+	}
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.baseBackoff
+	for i := 1; i < attempts && d < r.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.maxBackoff {
+		d = r.maxBackoff
+	}
+	// equal jitter keeps replicas from retrying in lockstep while still
+	// waiting at least half the step
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,189 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/migrations"
+	"github.com/rasteiro11/MCABankCustomer/pkg/dbdriver"
+	"github.com/rasteiro11/MCABankCustomer/pkg/migrate"
+	"github.com/rasteiro11/MCABankCustomer/pkg/outbox"
+	"gorm.io/gorm"
+)
+
+// recordingPublisher remembers delivered events and fails while err is set.
+type recordingPublisher struct {
+	mu        sync.Mutex
+	err       error
+	published []uint
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event *outbox.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, event.ID)
+	return nil
+}
+
+func migratedSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := dbdriver.OpenSQLite(dbdriver.InMemory)
+	if err != nil {
+		t.Fatalf("OpenSQLite() returned error: %v", err)
+	}
+
+	all, err := migrations.Load(dbdriver.SQLite)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if _, err := migrate.NewMigrator(db.Conn(), all).Up(context.Background()); err != nil {
+		t.Fatalf("Up() returned error: %v", err)
+	}
+	return db.Conn()
+}
+
+func addEvents(t *testing.T, store outbox.Store, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		event, err := outbox.NewEvent("customer", uint(i+1), "customer.created", map[string]int{"id": i + 1})
+		if err != nil {
+			t.Fatalf("NewEvent() returned error: %v", err)
+		}
+		if err := store.Add(context.Background(), event); err != nil {
+			t.Fatalf("Add() returned error: %v", err)
+		}
+	}
+}
+
+func stored(t *testing.T, db *gorm.DB, id uint) outbox.OutboxEvent {
+	t.Helper()
+
+	var m outbox.OutboxEvent
+	if err := db.First(&m, id).Error; err != nil {
+		t.Fatalf("First() returned error: %v", err)
+	}
+	return m
+}
+
+// makeDue moves the next attempt of every event to the past, as if their
+// lease or backoff had run out.
+func makeDue(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	if err := db.Model(&outbox.OutboxEvent{}).
+		Where("1 = 1").
+		Update("next_attempt_at", time.Now().UTC().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("Update() returned error: %v", err)
+	}
+}
+
+func TestClaimLeasesEvents(t *testing.T) {
+	ctx := context.Background()
+	db := migratedSQLite(t)
+	store := outbox.NewStore(db)
+	addEvents(t, store, 3)
+
+	first, err := store.Claim(ctx, 2, time.Minute)
+	if err != nil || len(first) != 2 {
+		t.Fatalf("Claim(2) returned %d events, error %v", len(first), err)
+	}
+	rest, err := store.Claim(ctx, 10, time.Minute)
+	if err != nil || len(rest) != 1 || rest[0].ID != 3 {
+		t.Fatalf("second Claim() returned %+v, error %v, want event 3 only", rest, err)
+	}
+	if again, err := store.Claim(ctx, 10, time.Minute); err != nil || len(again) != 0 {
+		t.Fatalf("Claim() of leased events returned %d events, error %v", len(again), err)
+	}
+
+	// a relay that died mid-publish leaves them claimable once the lease ends
+	makeDue(t, db)
+	if expired, err := store.Claim(ctx, 10, time.Minute); err != nil || len(expired) != 3 {
+		t.Fatalf("Claim() after the lease returned %d events, error %v", len(expired), err)
+	}
+}
+
+func TestRelayPublishes(t *testing.T) {
+	ctx := context.Background()
+	db := migratedSQLite(t)
+	store := outbox.NewStore(db)
+	addEvents(t, store, 3)
+
+	publisher := &recordingPublisher{}
+	relay := outbox.NewRelay(store, publisher, outbox.WithBatchSize(2))
+
+	for _, want := range []int{2, 1, 0} {
+		n, err := relay.Flush(ctx)
+		if err != nil || n != want {
+			t.Fatalf("Flush() published %d events, error %v, want %d", n, err, want)
+		}
+	}
+
+	if len(publisher.published) != 3 {
+		t.Errorf("publisher got events %v, want 3", publisher.published)
+	}
+	for id := uint(1); id <= 3; id++ {
+		if m := stored(t, db, id); m.Status != string(outbox.StatusPublished) {
+			t.Errorf("event %d is %s, want published", id, m.Status)
+		}
+	}
+}
+
+func TestRelayBacksOffAndDeadLetters(t *testing.T) {
+	const base = time.Hour
+
+	ctx := context.Background()
+	db := migratedSQLite(t)
+	store := outbox.NewStore(db)
+	addEvents(t, store, 1)
+
+	publisher := &recordingPublisher{err: errors.New("receiver down")}
+	relay := outbox.NewRelay(store, publisher,
+		outbox.WithMaxAttempts(3),
+		outbox.WithBackoff(base, 10*base))
+
+	// each failure waits between half and all of base * 2^(attempts-1)
+	for attempt, ceiling := range []time.Duration{base, 2 * base} {
+		before := time.Now()
+		if n, err := relay.Flush(ctx); err != nil || n != 0 {
+			t.Fatalf("Flush() published %d events, error %v", n, err)
+		}
+
+		m := stored(t, db, 1)
+		if m.Attempts != attempt+1 || m.Status != string(outbox.StatusPending) || m.LastError != "receiver down" {
+			t.Fatalf("after attempt %d the event is %+v", attempt+1, m)
+		}
+		wait := m.NextAttemptAt.Sub(before)
+		if wait < ceiling/2-time.Second || wait > ceiling+time.Second {
+			t.Errorf("attempt %d retries in %s, want between %s and %s", attempt+1, wait, ceiling/2, ceiling)
+		}
+
+		// not due until the backoff has passed
+		if n, err := relay.Flush(ctx); err != nil || n != 0 || stored(t, db, 1).Attempts != attempt+1 {
+			t.Fatalf("an event in backoff was retried")
+		}
+		makeDue(t, db)
+	}
+
+	if _, err := relay.Flush(ctx); err != nil {
+		t.Fatalf("Flush() returned error: %v", err)
+	}
+	if m := stored(t, db, 1); m.Status != string(outbox.StatusDeadLetter) || m.Attempts != 3 {
+		t.Fatalf("after the last attempt the event is %+v, want dead-lettered", m)
+	}
+
+	// dead letters are never claimed again
+	makeDue(t, db)
+	publisher.err = nil
+	if n, err := relay.Flush(ctx); err != nil || n != 0 {
+		t.Errorf("Flush() published %d dead-lettered events, error %v", n, err)
+	}
+}
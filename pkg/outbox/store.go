@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasteiro11/PogCore/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+var _ Store = (*gormStore)(nil)
+
+func NewStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+// conn joins the caller's transaction when there is one, which is what
+// makes Add atomic with the aggregate mutation.
+func (s *gormStore) conn(ctx context.Context) *gorm.DB {
+	if tx, err := database.FromContext(ctx); err == nil {
+		return tx.WithContext(ctx)
+	}
+	return s.db.WithContext(ctx)
+}
+
+func (s *gormStore) Add(ctx context.Context, event *Event) error {
+	m := fromEvent(event)
+	if err := s.conn(ctx).Create(m).Error; err != nil {
+		return err
+	}
+	event.ID = m.ID
+	return nil
+}
+
+// Claim locks due events and pushes their next attempt past the lease, so
+// concurrent relays skip them while they are being published. An event
+// whose relay dies mid-publish becomes due again once the lease expires.
+func (s *gormStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]Event, error) {
+	var ms []OutboxEvent
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", string(StatusPending), now).
+			Order("id").
+			Limit(limit).
+			Find(&ms).Error; err != nil {
+			return err
+		}
+
+		if len(ms) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(ms))
+		for _, m := range ms {
+			ids = append(ids, m.ID)
+		}
+
+		return tx.Model(&OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, len(ms))
+	for _, m := range ms {
+		events = append(events, *toEvent(&m))
+	}
+	return events, nil
+}
+
+func (s *gormStore) MarkPublished(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).
+		Model(&OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":     string(StatusPublished),
+			"last_error": "",
+		}).Error
+}
+
+// MarkFailed records a failed attempt. A nil retryAt moves the event to
+// the dead-letter state instead of scheduling another attempt.
+func (s *gormStore) MarkFailed(ctx context.Context, id uint, cause error, retryAt *time.Time) error {
+	updates := map[string]any{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": cause.Error(),
+	}
+
+	if retryAt != nil {
+		updates["next_attempt_at"] = retryAt.UTC()
+	} else {
+		updates["status"] = string(StatusDeadLetter)
+	}
+
+	return s.db.WithContext(ctx).
+		Model(&OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
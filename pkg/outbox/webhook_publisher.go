@@ -0,0 +1,95 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderEventID   = "X-Event-ID"
+	HeaderEventType = "X-Event-Type"
+	HeaderSignature = "X-Signature-SHA256"
+
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+type (
+	WebhookOpt func(*webhookPublisher)
+
+	webhookPublisher struct {
+		url    string
+		secret []byte
+		client *http.Client
+	}
+)
+
+var _ EventPublisher = (*webhookPublisher)(nil)
+
+// WithSecret signs each body with HMAC-SHA256 so receivers can verify
+// the sender.
+func WithSecret(secret string) WebhookOpt {
+	return func(p *webhookPublisher) {
+		p.secret = []byte(secret)
+	}
+}
+
+func WithHTTPClient(client *http.Client) WebhookOpt {
+	return func(p *webhookPublisher) {
+		p.client = client
+	}
+}
+
+func NewWebhookPublisher(url string, opts ...WebhookOpt) EventPublisher {
+	p := &webhookPublisher{
+		url:    url,
+		client: &http.Client{Timeout: defaultWebhookTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, strconv.FormatUint(uint64(event.ID), 10))
+	req.Header.Set(HeaderEventType, event.Type)
+
+	if len(p.secret) > 0 {
+		mac := hmac.New(sha256.New, p.secret)
+		mac.Write(body)
+		req.Header.Set(HeaderSignature, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("%w: %s", ErrUnexpectedStatus, res.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package outbox_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/outbox"
+)
+
+const secret = "webhook-secret"
+
+// receiver verifies the signature and headers the way a consumer would,
+// and answers 401 to anything it cannot authenticate.
+type receiver struct {
+	status   int
+	received []outbox.Event
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	signature, err := hex.DecodeString(req.Header.Get(outbox.HeaderSignature))
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var event outbox.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if req.Header.Get(outbox.HeaderEventID) != strconv.FormatUint(uint64(event.ID), 10) ||
+		req.Header.Get(outbox.HeaderEventType) != event.Type {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	r.received = append(r.received, event)
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+	}
+}
+
+func newEvent(t *testing.T) *outbox.Event {
+	t.Helper()
+
+	event, err := outbox.NewEvent("customer", 7, "customer.created", map[string]string{"nome": "Ana"})
+	if err != nil {
+		t.Fatalf("NewEvent() returned error: %v", err)
+	}
+	event.ID = 42
+	return event
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		status  int
+		wantErr bool
+	}{
+		{name: "signed delivery", secret: secret},
+		{name: "wrong secret", secret: "guessed", wantErr: true},
+		{name: "unsigned delivery", wantErr: true},
+		{name: "receiver error", secret: secret, status: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recv := &receiver{status: tt.status}
+			srv := httptest.NewServer(recv)
+			defer srv.Close()
+
+			var opts []outbox.WebhookOpt
+			if tt.secret != "" {
+				opts = append(opts, outbox.WithSecret(tt.secret))
+			}
+			publisher := outbox.NewWebhookPublisher(srv.URL, opts...)
+
+			err := publisher.Publish(context.Background(), newEvent(t))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Publish() returned %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, outbox.ErrUnexpectedStatus) {
+				t.Errorf("Publish() returned %v, want ErrUnexpectedStatus", err)
+			}
+			if !tt.wantErr && (len(recv.received) != 1 || recv.received[0].AggregateID != 7) {
+				t.Errorf("receiver got %+v", recv.received)
+			}
+		})
+	}
+}
+
+func TestRelayDeliversToWebhook(t *testing.T) {
+	recv := &receiver{}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	db := migratedSQLite(t)
+	store := outbox.NewStore(db)
+	addEvents(t, store, 2)
+
+	relay := outbox.NewRelay(store, outbox.NewWebhookPublisher(srv.URL, outbox.WithSecret(secret)))
+	if n, err := relay.Flush(context.Background()); err != nil || n != 2 {
+		t.Fatalf("Flush() published %d events, error %v", n, err)
+	}
+	if len(recv.received) != 2 || recv.received[0].ID != 1 || string(recv.received[1].Payload) != `{"id":2}` {
+		t.Errorf("receiver got %+v", recv.received)
+	}
+}
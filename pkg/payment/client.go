@@ -0,0 +1,216 @@
+package payment
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	pbPaymentClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/payment"
+	"github.com/rasteiro11/PogCore/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultTimeout          = 2 * time.Second
+	defaultMaxAttempts      = 3
+	defaultBaseBackoff      = 100 * time.Millisecond
+	defaultMaxBackoff       = 2 * time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+var (
+	// readRetryable are the codes worth retrying for calls without side
+	// effects.
+	readRetryable = map[codes.Code]bool{
+		codes.Unavailable:       true,
+		codes.DeadlineExceeded:  true,
+		codes.ResourceExhausted: true,
+		codes.Aborted:           true,
+	}
+
+	// writeRetryable leaves out DeadlineExceeded and Aborted: the write may
+	// have been applied, and CreateBalance is not idempotent.
+	writeRetryable = map[codes.Code]bool{
+		codes.Unavailable:       true,
+		codes.ResourceExhausted: true,
+	}
+
+	// outage are the codes that count against the breaker. Anything else is
+	// an answer from a healthy service.
+	outage = map[codes.Code]bool{
+		codes.Unavailable:       true,
+		codes.DeadlineExceeded:  true,
+		codes.ResourceExhausted: true,
+		codes.Internal:          true,
+		codes.Unknown:           true,
+	}
+)
+
+type (
+	Opt func(*client)
+
+	// client decorates a BalanceServiceClient with a deadline per attempt,
+	// retries with jittered exponential backoff and a circuit breaker shared
+	// by both methods.
+	client struct {
+		next        pbPaymentClient.BalanceServiceClient
+		timeout     time.Duration
+		maxAttempts int
+		baseBackoff time.Duration
+		maxBackoff  time.Duration
+		breaker     *breaker
+	}
+)
+
+var _ pbPaymentClient.BalanceServiceClient = (*client)(nil)
+
+// WithTimeout bounds every attempt. The caller's deadline still applies if
+// it is shorter.
+func WithTimeout(d time.Duration) Opt {
+	return func(c *client) {
+		if d > 0 {
+			c.timeout = d
+		}
+	}
+}
+
+func WithMaxAttempts(n int) Opt {
+	return func(c *client) {
+		if n > 0 {
+			c.maxAttempts = n
+		}
+	}
+}
+
+func WithBackoff(base, ceiling time.Duration) Opt {
+	return func(c *client) {
+		if base > 0 {
+			c.baseBackoff = base
+		}
+		if ceiling > 0 {
+			c.maxBackoff = ceiling
+		}
+	}
+}
+
+// WithBreaker opens the circuit after threshold consecutive failed calls
+// and keeps it open for cooldown.
+func WithBreaker(threshold int, cooldown time.Duration) Opt {
+	return func(c *client) {
+		if threshold > 0 {
+			c.breaker.threshold = threshold
+		}
+		if cooldown > 0 {
+			c.breaker.cooldown = cooldown
+		}
+	}
+}
+
+func NewResilientClient(next pbPaymentClient.BalanceServiceClient, opts ...Opt) pbPaymentClient.BalanceServiceClient {
+	c := &client{
+		next:        next,
+		timeout:     defaultTimeout,
+		maxAttempts: defaultMaxAttempts,
+		baseBackoff: defaultBaseBackoff,
+		maxBackoff:  defaultMaxBackoff,
+		breaker:     newBreaker(defaultBreakerThreshold, defaultBreakerCooldown),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *client) GetBalances(
+	ctx context.Context,
+	in *pbPaymentClient.GetBalancesRequest,
+	opts ...grpc.CallOption,
+) (*pbPaymentClient.GetBalancesResponse, error) {
+	var res *pbPaymentClient.GetBalancesResponse
+	err := c.call(ctx, "GetBalances", readRetryable, func(ctx context.Context) error {
+		var err error
+		res, err = c.next.GetBalances(ctx, in, opts...)
+		return err
+	})
+	return res, err
+}
+
+func (c *client) CreateBalance(
+	ctx context.Context,
+	in *pbPaymentClient.CreateBalanceRequest,
+	opts ...grpc.CallOption,
+) (*pbPaymentClient.CreateBalanceResponse, error) {
+	var res *pbPaymentClient.CreateBalanceResponse
+	err := c.call(ctx, "CreateBalance", writeRetryable, func(ctx context.Context) error {
+		var err error
+		res, err = c.next.CreateBalance(ctx, in, opts...)
+		return err
+	})
+	return res, err
+}
+
+func (c *client) call(
+	ctx context.Context,
+	method string,
+	retryable map[codes.Code]bool,
+	invoke func(ctx context.Context) error,
+) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if !c.breaker.allow() {
+			return ErrCircuitOpen
+		}
+
+		err = c.attempt(ctx, invoke)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= c.maxAttempts || !retryable[status.Code(err)] || ctx.Err() != nil {
+			return err
+		}
+
+		wait := c.backoff(attempt)
+		logger.Of(ctx).Warnf("[payment.client] %s attempt %d failed, retrying in %s: %+v", method, attempt, wait, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *client) attempt(ctx context.Context, invoke func(ctx context.Context) error) error {
+	attemptCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	err := invoke(attemptCtx)
+	switch {
+	case err == nil:
+		c.breaker.success()
+	case ctx.Err() != nil:
+		// the caller gave up; that says nothing about the payment service
+		c.breaker.release()
+	case outage[status.Code(err)]:
+		c.breaker.failure()
+	default:
+		c.breaker.success()
+	}
+
+	return err
+}
+
+// backoff returns a full-jitter delay: uniform in [0, min(max, base*2^n)).
+func (c *client) backoff(attempt int) time.Duration {
+	ceiling := c.baseBackoff << (attempt - 1)
+	if ceiling <= 0 || ceiling > c.maxBackoff {
+		ceiling = c.maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
@@ -0,0 +1,168 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pbPaymentClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/payment"
+	"github.com/rasteiro11/MCABankCustomer/pkg/payment/paymenttest"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// start serves a fake payment service and returns a resilient client in
+// front of it, with backoffs short enough for tests.
+func start(t *testing.T, opts ...Opt) (*paymenttest.Server, *client) {
+	t.Helper()
+
+	srv := paymenttest.NewServer()
+	conn, stop, err := srv.Start()
+	if err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	t.Cleanup(stop)
+
+	opts = append([]Opt{WithBackoff(time.Millisecond, 2*time.Millisecond)}, opts...)
+	return srv, NewResilientClient(conn, opts...).(*client)
+}
+
+func getBalances(c *client) error {
+	_, err := c.GetBalances(context.Background(), &pbPaymentClient.GetBalancesRequest{CustomerIds: []uint32{1}})
+	return err
+}
+
+func createBalance(c *client) error {
+	_, err := c.CreateBalance(context.Background(), &pbPaymentClient.CreateBalanceRequest{CustomerId: 1})
+	return err
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		call      func(*client) error
+		failures  []codes.Code
+		wantCode  codes.Code
+		wantCalls int
+	}{
+		{"read retries an outage", getBalances, []codes.Code{codes.Unavailable, codes.Unavailable}, codes.OK, 3},
+		{"read retries a timeout", getBalances, []codes.Code{codes.DeadlineExceeded}, codes.OK, 2},
+		{"read gives up after max attempts", getBalances, []codes.Code{codes.Unavailable, codes.Unavailable, codes.Unavailable}, codes.Unavailable, 3},
+		{"read does not retry a rejection", getBalances, []codes.Code{codes.InvalidArgument}, codes.InvalidArgument, 1},
+		{"write retries an outage", createBalance, []codes.Code{codes.Unavailable}, codes.OK, 2},
+		{"write does not retry a timeout", createBalance, []codes.Code{codes.DeadlineExceeded}, codes.DeadlineExceeded, 1},
+		{"write does not retry an abort", createBalance, []codes.Code{codes.Aborted}, codes.Aborted, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, c := start(t, WithMaxAttempts(3))
+			for _, code := range tt.failures {
+				srv.FailNext(status.Error(code, "injected"))
+			}
+
+			err := tt.call(c)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("returned %v, want code %v", err, tt.wantCode)
+			}
+			if calls := srv.Calls(); calls != tt.wantCalls {
+				t.Errorf("server got %d calls, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClientBoundsEveryAttempt(t *testing.T) {
+	srv, c := start(t, WithTimeout(10*time.Millisecond), WithMaxAttempts(2))
+	srv.SetLatency(time.Second)
+
+	begin := time.Now()
+	err := getBalances(c)
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("returned %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+		t.Errorf("two attempts took %s", elapsed)
+	}
+	if calls := srv.Calls(); calls != 2 {
+		t.Errorf("server got %d calls, want 2", calls)
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	const cooldown = time.Minute
+
+	srv, c := start(t, WithMaxAttempts(1), WithBreaker(2, cooldown))
+	now := time.Now()
+	c.breaker.now = func() time.Time { return now }
+
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	// a rejection is an answer from a healthy service and never trips it
+	srv.FailNext(status.Error(codes.InvalidArgument, "bad"), unavailable, unavailable)
+	for i := 0; i < 3; i++ {
+		if err := getBalances(c); err == nil {
+			t.Fatalf("call %d succeeded, want the injected failure", i)
+		}
+	}
+
+	err := getBalances(c)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("returned %v after two outages, want ErrCircuitOpen", err)
+	}
+	if !errors.Is(err, domain.ErrDependencyUnavailable) || status.Code(err) != codes.Unavailable {
+		t.Errorf("ErrCircuitOpen is %v, want a dependency outage with code Unavailable", err)
+	}
+	if calls := srv.Calls(); calls != 3 {
+		t.Errorf("server got %d calls, want the open circuit to skip it", calls)
+	}
+
+	// after the cooldown one probe goes out; its failure reopens the circuit
+	now = now.Add(cooldown)
+	srv.FailNext(unavailable)
+	if err := getBalances(c); errors.Is(err, ErrCircuitOpen) || status.Code(err) != codes.Unavailable {
+		t.Fatalf("half-open probe returned %v, want the server's failure", err)
+	}
+	if err := getBalances(c); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("returned %v after a failed probe, want ErrCircuitOpen", err)
+	}
+
+	// a successful probe closes it again
+	now = now.Add(cooldown)
+	for i := 0; i < 3; i++ {
+		if err := getBalances(c); err != nil {
+			t.Fatalf("call %d after a successful probe returned error: %v", i, err)
+		}
+	}
+	if calls := srv.Calls(); calls != 7 {
+		t.Errorf("server got %d calls, want 7", calls)
+	}
+}
+
+func TestClientHalfOpenAllowsOneProbe(t *testing.T) {
+	srv, c := start(t, WithMaxAttempts(1), WithBreaker(1, time.Minute))
+	now := time.Now()
+	c.breaker.now = func() time.Time { return now }
+
+	srv.FailNext(status.Error(codes.Unavailable, "down"))
+	_ = getBalances(c)
+
+	now = now.Add(time.Minute)
+	srv.SetLatency(50 * time.Millisecond)
+
+	probe := make(chan error)
+	go func() { probe <- getBalances(c) }()
+
+	// wait for the probe to reach the server before racing it
+	for srv.Calls() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := getBalances(c); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("concurrent call during the probe returned %v, want ErrCircuitOpen", err)
+	}
+	if err := <-probe; err != nil {
+		t.Errorf("probe returned error: %v", err)
+	}
+}
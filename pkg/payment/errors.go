@@ -0,0 +1,14 @@
+package payment
+
+import (
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrCircuitOpen is returned without calling the payment service while the
+// breaker is open. It matches domain.ErrDependencyUnavailable with
+// errors.Is and still carries codes.Unavailable for status.Code, so callers
+// handle it like any other outage of the dependency.
+var ErrCircuitOpen = domain.Wrap(domain.ErrDependencyUnavailable,
+	status.Error(codes.Unavailable, "payment service circuit breaker is open"))
@@ -0,0 +1,161 @@
+// Package paymenttest provides an in-memory BalanceService that can inject
+// latency and errors, to exercise the payment client without a real payment
+// deployment.
+package paymenttest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	pbPaymentClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/payment"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1 << 20
+
+// Server is a stub BalanceService holding balances in memory.
+type Server struct {
+	pbPaymentClient.UnimplementedBalanceServiceServer
+
+	mu       sync.Mutex
+	nextID   uint32
+	balances map[uint32]*pbPaymentClient.Balance
+	latency  time.Duration
+	failures []error
+	calls    int
+}
+
+var _ pbPaymentClient.BalanceServiceServer = (*Server)(nil)
+
+func NewServer() *Server {
+	return &Server{
+		balances: make(map[uint32]*pbPaymentClient.Balance),
+	}
+}
+
+// SetBalance stores a balance for customerID, replacing any existing one.
+func (s *Server) SetBalance(customerID uint32, balance, blocked float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.balances[customerID] = &pbPaymentClient.Balance{
+		Id:             s.nextID,
+		CustomerId:     customerID,
+		Balance:        balance,
+		BlockedBalance: blocked,
+	}
+}
+
+// SetLatency delays every following call by d before it is handled.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// FailNext makes the next len(errs) calls return errs in order, e.g.
+// status.Error(codes.Unavailable, "down").
+func (s *Server) FailNext(errs ...error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, errs...)
+}
+
+// Calls returns how many calls reached the server.
+func (s *Server) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *Server) GetBalances(ctx context.Context, req *pbPaymentClient.GetBalancesRequest) (*pbPaymentClient.GetBalancesResponse, error) {
+	if err := s.enter(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := &pbPaymentClient.GetBalancesResponse{}
+	for _, id := range req.GetCustomerIds() {
+		if b, ok := s.balances[id]; ok {
+			res.Balances = append(res.Balances, b)
+		}
+	}
+	return res, nil
+}
+
+func (s *Server) CreateBalance(ctx context.Context, req *pbPaymentClient.CreateBalanceRequest) (*pbPaymentClient.CreateBalanceResponse, error) {
+	if err := s.enter(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.balances[req.GetCustomerId()]; ok {
+		return nil, status.Error(codes.AlreadyExists, "balance already exists")
+	}
+
+	s.nextID++
+	b := &pbPaymentClient.Balance{Id: s.nextID, CustomerId: req.GetCustomerId()}
+	s.balances[req.GetCustomerId()] = b
+	return &pbPaymentClient.CreateBalanceResponse{Balance: b}, nil
+}
+
+// enter counts the call, applies the configured latency and pops the next
+// injected failure.
+func (s *Server) enter(ctx context.Context) error {
+	s.mu.Lock()
+	s.calls++
+	latency := s.latency
+	var err error
+	if len(s.failures) > 0 {
+		err, s.failures = s.failures[0], s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-time.After(latency):
+		}
+	}
+
+	return err
+}
+
+// Start serves s over an in-memory listener and returns a client connected
+// to it. The returned func stops the server and closes the connection.
+func (s *Server) Start() (pbPaymentClient.BalanceServiceClient, func(), error) {
+	lis := bufconn.Listen(bufSize)
+
+	srv := grpc.NewServer()
+	pbPaymentClient.RegisterBalanceServiceServer(srv, s)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		srv.Stop()
+		return nil, nil, err
+	}
+
+	stop := func() {
+		conn.Close()
+		srv.Stop()
+	}
+	return pbPaymentClient.NewBalanceServiceClient(conn), stop, nil
+}
@@ -0,0 +1,101 @@
+package problem
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ContentType = "application/problem+json"
+
+	// TypeBase prefixes the code of typed problems, so "customer_not_found"
+	// becomes the relative URI "/problems/customer_not_found".
+	TypeBase = "/problems/"
+
+	typeDefault = "about:blank"
+)
+
+type (
+	Opt func(*Problem)
+
+	FieldError struct {
+		Field   string `json:"field"`
+		Rule    string `json:"rule"`
+		Message string `json:"message"`
+	}
+
+	// Problem is an RFC 7807 problem details document.
+	Problem struct {
+		Type     string       `json:"type"`
+		Title    string       `json:"title"`
+		Status   int          `json:"status"`
+		Detail   string       `json:"detail,omitempty"`
+		Instance string       `json:"instance"`
+		Code     string       `json:"code,omitempty"`
+		TraceID  string       `json:"trace_id"`
+		Errors   []FieldError `json:"errors,omitempty"`
+	}
+)
+
+func WithDetail(detail string) Opt {
+	return func(p *Problem) {
+		p.Detail = detail
+	}
+}
+
+func WithCode(code string) Opt {
+	return func(p *Problem) {
+		p.Type = TypeBase + code
+		p.Code = code
+	}
+}
+
+func WithErrors(errs ...FieldError) Opt {
+	return func(p *Problem) {
+		p.Errors = append(p.Errors, errs...)
+	}
+}
+
+func New(c *fiber.Ctx, status int, opts ...Opt) error {
+	p := &Problem{
+		Type:     typeDefault,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: c.Path(),
+		TraceID:  traceID(c),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if err := c.Status(status).JSON(p); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, ContentType)
+
+	return nil
+}
+
+// traceID prefers the active span, then the caller's W3C traceparent, and
+// otherwise mints an id so the client still has something to report.
+func traceID(c *fiber.Ctx) string {
+	if sc := trace.SpanContextFromContext(c.UserContext()); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+
+	if parts := strings.Split(c.Get("traceparent"), "-"); len(parts) == 4 && len(parts[1]) == 32 {
+		return parts[1]
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,9 @@
+package rbac
+
+import "errors"
+
+var (
+	ErrUnknownRole     = errors.New("policy references an undefined role")
+	ErrMissingDefault  = errors.New("policy default_role is not defined")
+	ErrNoAuthenticated = errors.New("no authenticated session")
+)
@@ -0,0 +1,120 @@
+// Package rbac evaluates role based access policies loaded from a YAML file.
+//
+// A role grants actions either on every record (allow) or only on records
+// the caller owns (own). Users get the roles listed for their id under users,
+// or default_role when they are not listed at all.
+package rbac
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Wildcard grants every action.
+const Wildcard = "*"
+
+type (
+	Role struct {
+		Allow []string `yaml:"allow"`
+		Own   []string `yaml:"own"`
+	}
+
+	Policy struct {
+		DefaultRole string              `yaml:"default_role"`
+		Users       map[string][]uint64 `yaml:"users"`
+		Roles       map[string]Role     `yaml:"roles"`
+
+		rolesByUser map[uint64][]string
+	}
+
+	Decision struct {
+		Allowed bool
+		Reason  string
+	}
+)
+
+// Load reads and validates the policy file at path.
+func Load(path string) (*Policy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(raw)
+}
+
+func Parse(raw []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.Unmarshal(raw, p); err != nil {
+		return nil, err
+	}
+
+	if _, ok := p.Roles[p.DefaultRole]; !ok {
+		return nil, ErrMissingDefault
+	}
+
+	p.rolesByUser = make(map[uint64][]string)
+	for role, ids := range p.Users {
+		if _, ok := p.Roles[role]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRole, role)
+		}
+		for _, id := range ids {
+			p.rolesByUser[id] = append(p.rolesByUser[id], role)
+		}
+	}
+	for id := range p.rolesByUser {
+		sort.Strings(p.rolesByUser[id])
+	}
+
+	return p, nil
+}
+
+func (p *Policy) RolesOf(userID uint64) []string {
+	if roles, ok := p.rolesByUser[userID]; ok {
+		return roles
+	}
+	return []string{p.DefaultRole}
+}
+
+// Authorize decides whether userID may perform action. owns is only called
+// when no role grants action on every record but one grants it on own
+// records, so callers can defer loading the record until it matters.
+func (p *Policy) Authorize(userID uint64, action string, owns func() (bool, error)) (Decision, error) {
+	roles := p.RolesOf(userID)
+
+	var ownRoles []string
+	for _, name := range roles {
+		role := p.Roles[name]
+		if grants(role.Allow, action) {
+			return Decision{Allowed: true}, nil
+		}
+		if grants(role.Own, action) {
+			ownRoles = append(ownRoles, name)
+		}
+	}
+
+	if len(ownRoles) == 0 {
+		return Decision{Reason: fmt.Sprintf("roles %v do not grant %s", roles, action)}, nil
+	}
+
+	owner, err := owns()
+	if err != nil {
+		return Decision{}, err
+	}
+	if !owner {
+		return Decision{Reason: fmt.Sprintf("roles %v only grant %s on the caller's own records", ownRoles, action)}, nil
+	}
+
+	return Decision{Allowed: true}, nil
+}
+
+func grants(actions []string, action string) bool {
+	for _, a := range actions {
+		if a == Wildcard || a == action {
+			return true
+		}
+	}
+	return false
+}
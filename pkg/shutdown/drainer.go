@@ -0,0 +1,88 @@
+package shutdown
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const drainPollInterval = 50 * time.Millisecond
+
+// Drainer tracks in-flight HTTP requests. The PogCore server does not expose
+// its listener, so once Close is called new requests are refused with 503
+// and "Connection: close" instead, which makes keep-alive clients reconnect
+// to another replica.
+type Drainer struct {
+	mu       sync.Mutex
+	inFlight int
+	closed   bool
+}
+
+func NewDrainer() *Drainer {
+	return &Drainer{}
+}
+
+// Middleware must be registered before any route so every request is
+// counted.
+func (d *Drainer) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !d.enter() {
+			c.Context().SetConnectionClose()
+			c.Set(fiber.HeaderRetryAfter, "1")
+			return c.SendStatus(http.StatusServiceUnavailable)
+		}
+		defer d.leave()
+
+		return c.Next()
+	}
+}
+
+// Close stops admitting new requests.
+func (d *Drainer) Close() {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+}
+
+// Wait blocks until every admitted request has finished or ctx is done.
+func (d *Drainer) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if d.InFlight() == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrDrainTimeout
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Drainer) InFlight() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inFlight
+}
+
+func (d *Drainer) enter() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return false
+	}
+	d.inFlight++
+	return true
+}
+
+func (d *Drainer) leave() {
+	d.mu.Lock()
+	d.inFlight--
+	d.mu.Unlock()
+}
@@ -0,0 +1,70 @@
+package shutdown_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rasteiro11/MCABankCustomer/pkg/shutdown"
+)
+
+func TestDrainerFinishesInFlightRequests(t *testing.T) {
+	drainer := shutdown.NewDrainer()
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	app := fiber.New()
+	app.Use(drainer.Middleware())
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		close(started)
+		<-release
+		return c.SendString("done")
+	})
+	app.Get("/fast", func(c *fiber.Ctx) error {
+		return c.SendString("done")
+	})
+
+	inFlight := make(chan int, 1)
+	go func() {
+		res, err := app.Test(httptest.NewRequest(http.MethodGet, "/slow", nil), -1)
+		if err != nil {
+			inFlight <- 0
+			return
+		}
+		res.Body.Close()
+		inFlight <- res.StatusCode
+	}()
+	<-started
+
+	drainer.Close()
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/fast", nil), -1)
+	if err != nil {
+		t.Fatalf("Test() returned error: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("request after Close got %d, want 503", res.StatusCode)
+	}
+	if res.Header.Get(fiber.HeaderRetryAfter) == "" || !res.Close {
+		t.Errorf("refused request has headers %v, want Retry-After and Connection: close", res.Header)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := drainer.Wait(ctx); !errors.Is(err, shutdown.ErrDrainTimeout) {
+		t.Fatalf("Wait() with a request in flight returned %v, want ErrDrainTimeout", err)
+	}
+
+	close(release)
+	if status := <-inFlight; status != http.StatusOK {
+		t.Errorf("in-flight request got %d, want 200", status)
+	}
+	if err := drainer.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() after the last request returned %v", err)
+	}
+}
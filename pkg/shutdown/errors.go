@@ -0,0 +1,5 @@
+package shutdown
+
+import "errors"
+
+var ErrDrainTimeout = errors.New("in-flight requests did not finish before the deadline")
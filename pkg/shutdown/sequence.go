@@ -0,0 +1,102 @@
+package shutdown
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasteiro11/PogCore/pkg/logger"
+)
+
+const (
+	defaultDrainPeriod = 5 * time.Second
+	defaultTimeout     = 20 * time.Second
+)
+
+type (
+	Opt func(*Sequence)
+
+	step struct {
+		name string
+		stop func(ctx context.Context) error
+	}
+
+	// Sequence runs the shutdown of the process: it first calls the
+	// onSignal hooks (e.g. flipping readiness), waits drainPeriod so load
+	// balancers notice, then runs the registered steps in order. All steps
+	// share a single timeout; a failing step is logged and the next one
+	// still runs so resources are released as far as possible.
+	Sequence struct {
+		drainPeriod time.Duration
+		timeout     time.Duration
+		onSignal    []func()
+		steps       []step
+	}
+)
+
+func WithDrainPeriod(d time.Duration) Opt {
+	return func(s *Sequence) {
+		if d >= 0 {
+			s.drainPeriod = d
+		}
+	}
+}
+
+func WithTimeout(d time.Duration) Opt {
+	return func(s *Sequence) {
+		if d > 0 {
+			s.timeout = d
+		}
+	}
+}
+
+func NewSequence(opts ...Opt) *Sequence {
+	s := &Sequence{
+		drainPeriod: defaultDrainPeriod,
+		timeout:     defaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// OnSignal registers a hook that runs as soon as shutdown starts, before the
+// drain period.
+func (s *Sequence) OnSignal(hook func()) {
+	s.onSignal = append(s.onSignal, hook)
+}
+
+// Then appends a step; steps run in registration order.
+func (s *Sequence) Then(name string, stop func(ctx context.Context) error) {
+	s.steps = append(s.steps, step{name: name, stop: stop})
+}
+
+// Run executes the shutdown and reports whether every step succeeded. ctx
+// must not be the one cancelled by the signal, or every step would see an
+// expired deadline.
+func (s *Sequence) Run(ctx context.Context) bool {
+	for _, hook := range s.onSignal {
+		hook()
+	}
+
+	logger.Of(ctx).Infof("Shutting down, draining for %s", s.drainPeriod)
+	time.Sleep(s.drainPeriod)
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	ok := true
+	for _, step := range s.steps {
+		start := time.Now()
+		if err := step.stop(ctx); err != nil {
+			ok = false
+			logger.Of(ctx).Errorf("[shutdown.Sequence] %s returned error: %+v\n", step.name, err)
+			continue
+		}
+		logger.Of(ctx).Infof("Shutdown step %s done in %s", step.name, time.Since(start))
+	}
+
+	return ok
+}
@@ -0,0 +1,46 @@
+package shutdown
+
+import (
+	"context"
+	"sync"
+)
+
+// Workers runs background loops under a shared context so they can be
+// cancelled together and awaited.
+type Workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWorkers(ctx context.Context) *Workers {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Workers{ctx: ctx, cancel: cancel}
+}
+
+func (w *Workers) Go(run func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		run(w.ctx)
+	}()
+}
+
+// Stop cancels every worker and waits for them to return or for ctx to be
+// done, whichever comes first.
+func (w *Workers) Stop(ctx context.Context) error {
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
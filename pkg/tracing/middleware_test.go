@@ -0,0 +1,90 @@
+package tracing_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rasteiro11/MCABankCustomer/pkg/problem"
+	"github.com/rasteiro11/MCABankCustomer/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+// newApp answers GET /customers/:id with a problem, after checking the
+// handler runs inside the request span.
+func newApp(t *testing.T) (*fiber.App, *tracetest.SpanRecorder) {
+	t.Helper()
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	app := fiber.New()
+	app.Use(tracing.Middleware(provider))
+	app.Get("/customers/:id", func(c *fiber.Ctx) error {
+		if !trace.SpanContextFromContext(c.UserContext()).IsValid() {
+			return c.SendStatus(http.StatusInternalServerError)
+		}
+		return problem.New(c, http.StatusNotFound, problem.WithCode("customer_not_found"))
+	})
+	return app, recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		wantTraceID string
+	}{
+		{name: "starts a trace"},
+		{name: "continues the caller's trace", traceparent: "00-" + parentTraceID + "-00f067aa0ba902b7-01", wantTraceID: parentTraceID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, recorder := newApp(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/customers/7", nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+
+			res, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("Test() returned error: %v", err)
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != http.StatusNotFound {
+				t.Fatalf("got %d, want 404", res.StatusCode)
+			}
+			var p problem.Problem
+			if err := json.NewDecoder(res.Body).Decode(&p); err != nil {
+				t.Fatalf("Decode() returned error: %v", err)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("recorded %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+			if span.Name() != "GET /customers/:id" || span.SpanKind() != trace.SpanKindServer {
+				t.Errorf("span is %q of kind %v", span.Name(), span.SpanKind())
+			}
+			if got := span.SpanContext().TraceID().String(); p.TraceID != got {
+				t.Errorf("problem trace_id is %s, want the request span's %s", p.TraceID, got)
+			}
+			if tt.wantTraceID != "" && p.TraceID != tt.wantTraceID {
+				t.Errorf("problem trace_id is %s, want the caller's %s", p.TraceID, tt.wantTraceID)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package validator
+
+import "strings"
+
+const (
+	cpfLength  = 11
+	cnpjLength = 14
+)
+
+type documentValidator struct{}
+
+var _ DocumentValidator = (*documentValidator)(nil)
+
+func NewDocumentValidator() DocumentValidator {
+	return &documentValidator{}
+}
+
+func (v *documentValidator) IsValid(document string) bool {
+	_, err := v.Normalize(document)
+	return err == nil
+}
+
+// Normalize strips the usual CPF/CNPJ punctuation (".", "-", "/" and
+// spaces) and returns the bare digits once the check digits match.
+func (v *documentValidator) Normalize(document string) (string, error) {
+	var b strings.Builder
+	for _, r := range document {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '.' || r == '-' || r == '/' || r == ' ':
+		default:
+			return "", ErrInvalidDocument
+		}
+	}
+
+	digits := b.String()
+	switch len(digits) {
+	case cpfLength:
+		if !validCPF(digits) {
+			return "", ErrInvalidDocument
+		}
+	case cnpjLength:
+		if !validCNPJ(digits) {
+			return "", ErrInvalidDocument
+		}
+	default:
+		return "", ErrInvalidDocument
+	}
+
+	return digits, nil
+}
+
+func validCPF(digits string) bool {
+	if repeated(digits) {
+		return false
+	}
+
+	return checkDigit(digits[:9], []int{10, 9, 8, 7, 6, 5, 4, 3, 2}) == digits[9] &&
+		checkDigit(digits[:10], []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}) == digits[10]
+}
+
+func validCNPJ(digits string) bool {
+	if repeated(digits) {
+		return false
+	}
+
+	return checkDigit(digits[:12], []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}) == digits[12] &&
+		checkDigit(digits[:13], []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}) == digits[13]
+}
+
+// checkDigit computes the modulo 11 check digit shared by CPF and CNPJ.
+func checkDigit(digits string, weights []int) byte {
+	sum := 0
+	for i := range digits {
+		sum += int(digits[i]-'0') * weights[i]
+	}
+
+	rest := sum % 11
+	if rest < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - rest)
+}
+
+// repeated reports documents made of a single digit, which pass the
+// checksum but are never issued.
+func repeated(digits string) bool {
+	return strings.Count(digits, digits[:1]) == len(digits)
+}
@@ -0,0 +1,90 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     string
+		wantErr  error
+	}{
+		{name: "raw cpf", document: "52998224725", want: "52998224725"},
+		{name: "punctuated cpf", document: "529.982.247-25", want: "52998224725"},
+		{name: "cpf with spaces", document: " 390 533 447 05 ", want: "39053344705"},
+		{name: "raw cnpj", document: "11222333000181", want: "11222333000181"},
+		{name: "punctuated cnpj", document: "11.222.333/0001-81", want: "11222333000181"},
+		{name: "cpf with a wrong check digit", document: "529.982.247-24", wantErr: ErrInvalidDocument},
+		{name: "cnpj with a wrong check digit", document: "11.222.333/0001-80", wantErr: ErrInvalidDocument},
+		{name: "repeated cpf", document: "111.111.111-11", wantErr: ErrInvalidDocument},
+		{name: "repeated cnpj", document: "00000000000000", wantErr: ErrInvalidDocument},
+		{name: "letters", document: "529.982.247-2A", wantErr: ErrInvalidDocument},
+		{name: "empty", document: "", wantErr: ErrInvalidDocument},
+		{name: "too short", document: "5299822472", wantErr: ErrInvalidDocument},
+		{name: "between cpf and cnpj", document: "529982247250", wantErr: ErrInvalidDocument},
+		{name: "too long", document: "112223330001810", wantErr: ErrInvalidDocument},
+	}
+
+	v := NewDocumentValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Normalize(tt.document)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Normalize(%q) returned error %v, want %v", tt.document, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.document, got, tt.want)
+			}
+			if v.IsValid(tt.document) != (tt.wantErr == nil) {
+				t.Errorf("IsValid(%q) disagrees with Normalize", tt.document)
+			}
+		})
+	}
+}
+
+func TestValidCPF(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   bool
+	}{
+		{digits: "52998224725", want: true},
+		{digits: "39053344705", want: true},
+		{digits: "52998224715"},
+		{digits: "52998224724"},
+		{digits: "00000000000"},
+		{digits: "99999999999"},
+		// a valid CNPJ prefix is not a CPF
+		{digits: "11222333000"},
+	}
+
+	for _, tt := range tests {
+		if got := validCPF(tt.digits); got != tt.want {
+			t.Errorf("validCPF(%q) = %v, want %v", tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestValidCNPJ(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   bool
+	}{
+		{digits: "11222333000181", want: true},
+		{digits: "11444777000161", want: true},
+		{digits: "11222333000171"},
+		{digits: "11222333000182"},
+		{digits: "00000000000000"},
+		{digits: "77777777777777"},
+		// a valid CPF padded to fourteen digits is not a CNPJ
+		{digits: "00052998224725"},
+	}
+
+	for _, tt := range tests {
+		if got := validCNPJ(tt.digits); got != tt.want {
+			t.Errorf("validCNPJ(%q) = %v, want %v", tt.digits, got, tt.want)
+		}
+	}
+}
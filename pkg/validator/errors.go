@@ -3,5 +3,6 @@ package validator
 import "errors"
 
 var (
-	ErrInvalidEmail = errors.New("invalid email format")
+	ErrInvalidEmail    = errors.New("invalid email format")
+	ErrInvalidDocument = errors.New("invalid CPF or CNPJ")
 )
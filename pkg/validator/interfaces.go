@@ -4,4 +4,10 @@ type (
 	EmailValidator interface {
 		IsValid(email string) bool
 	}
+
+	// DocumentValidator checks Brazilian CPF and CNPJ numbers.
+	DocumentValidator interface {
+		IsValid(document string) bool
+		Normalize(document string) (string, error)
+	}
 )
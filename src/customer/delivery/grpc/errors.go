@@ -0,0 +1,48 @@
+package grpc
+
+import (
+	"errors"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/validator"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrMissingNome = errors.New("nome is required")
+	// ErrMissingVersion rejects writes without the version they expect, so
+	// a gRPC caller can never overwrite or delete a customer it has not seen.
+	ErrMissingVersion = errors.New("version is required")
+)
+
+var codeByKind = map[domain.ErrorKind]codes.Code{
+	domain.KindInvalid:               codes.InvalidArgument,
+	domain.KindNotFound:              codes.NotFound,
+	domain.KindAlreadyExists:         codes.AlreadyExists,
+	domain.KindConflict:              codes.FailedPrecondition,
+	domain.KindDependencyUnavailable: codes.Unavailable,
+	domain.KindDependencyRejected:    codes.Internal,
+	domain.KindPreconditionFailed:    codes.Aborted,
+	domain.KindForbidden:             codes.PermissionDenied,
+}
+
+// toStatus translates errors coming from the customer service into gRPC
+// statuses so callers can branch on the code instead of the message.
+func toStatus(err error) error {
+	if errors.Is(err, ErrMissingNome) ||
+		errors.Is(err, ErrMissingVersion) ||
+		errors.Is(err, validator.ErrInvalidEmail) ||
+		errors.Is(err, validator.ErrInvalidDocument) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		if code, ok := codeByKind[domainErr.Kind]; ok {
+			return status.Error(code, domainErr.Error())
+		}
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,50 @@
+package grpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/validator"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "missing nome", err: ErrMissingNome, want: codes.InvalidArgument},
+		{name: "missing version", err: ErrMissingVersion, want: codes.InvalidArgument},
+		{name: "invalid email", err: validator.ErrInvalidEmail, want: codes.InvalidArgument},
+		{name: "invalid document", err: validator.ErrInvalidDocument, want: codes.InvalidArgument},
+		{name: "invalid", err: domain.ErrAuthUserMismatch, want: codes.InvalidArgument},
+		{name: "not found", err: domain.ErrNotFound, want: codes.NotFound},
+		{name: "already exists", err: domain.ErrEmailAlreadyExists, want: codes.AlreadyExists},
+		{name: "conflict", err: domain.ErrBalanceNotEmpty, want: codes.FailedPrecondition},
+		{name: "dependency unavailable", err: domain.ErrDependencyUnavailable, want: codes.Unavailable},
+		{name: "dependency rejected", err: domain.ErrPaymentRejected, want: codes.Internal},
+		{name: "precondition failed", err: domain.ErrVersionMismatch, want: codes.Aborted},
+		{name: "forbidden", err: domain.Forbidden("not your customer"), want: codes.PermissionDenied},
+		{name: "wrapped domain error", err: domain.Wrap(domain.ErrDependencyUnavailable, errors.New("timeout")), want: codes.Unavailable},
+		{name: "internal kind", err: &domain.Error{Kind: domain.KindInternal, Code: "boom", Message: "boom"}, want: codes.Internal},
+		{name: "unknown error", err: errors.New("boom"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, ok := status.FromError(toStatus(tt.err))
+			if !ok {
+				t.Fatalf("toStatus(%v) is not a status", tt.err)
+			}
+			if st.Code() != tt.want {
+				t.Errorf("toStatus(%v) = %v, want %v", tt.err, st.Code(), tt.want)
+			}
+			if st.Message() != tt.err.Error() {
+				t.Errorf("toStatus(%v) message is %q", tt.err, st.Message())
+			}
+		})
+	}
+}
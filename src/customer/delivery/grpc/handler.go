@@ -0,0 +1,152 @@
+package grpc
+
+import (
+	"context"
+
+	pbCustomer "github.com/rasteiro11/MCABankCustomer/gen/proto/go/customer"
+	"github.com/rasteiro11/MCABankCustomer/pkg/validator"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/service"
+	"github.com/rasteiro11/PogCore/pkg/transport/grpcserver"
+)
+
+type (
+	HandlerOpt func(*handler)
+	handler    struct {
+		customerService   service.CustomerService
+		emailValidator    validator.EmailValidator
+		documentValidator validator.DocumentValidator
+	}
+)
+
+func WithCustomerService(s service.CustomerService) HandlerOpt {
+	return func(h *handler) {
+		h.customerService = s
+	}
+}
+
+func NewHandler(server grpcserver.Server, opts ...HandlerOpt) {
+	h := &handler{
+		emailValidator:    validator.NewEmailValidator(),
+		documentValidator: validator.NewDocumentValidator(),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	server.Register(pbCustomer.CustomerService_ServiceDesc, h)
+}
+
+var _ pbCustomer.CustomerServiceServer = (*handler)(nil)
+
+func (h *handler) GetCustomer(ctx context.Context, req *pbCustomer.GetCustomerRequest) (*pbCustomer.GetCustomerResponse, error) {
+	customer, err := h.customerService.GetByID(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pbCustomer.GetCustomerResponse{Customer: MapCustomerToProto(customer)}, nil
+}
+
+func (h *handler) GetCustomerByDocument(ctx context.Context, req *pbCustomer.GetCustomerByDocumentRequest) (*pbCustomer.GetCustomerResponse, error) {
+	document, err := h.documentValidator.Normalize(req.GetDocument())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	customer, err := h.customerService.GetByDocument(ctx, document)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pbCustomer.GetCustomerResponse{Customer: MapCustomerToProto(customer)}, nil
+}
+
+func (h *handler) ListCustomers(ctx context.Context, req *pbCustomer.ListCustomersRequest) (*pbCustomer.ListCustomersResponse, error) {
+	page, err := h.customerService.GetAll(ctx, domain.CustomerQuery{
+		Limit:  int(req.GetLimit()),
+		Cursor: req.GetCursor(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pbCustomer.ListCustomersResponse{
+		Customers:  MapCustomersToProto(page.Items),
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}, nil
+}
+
+func (h *handler) CreateCustomer(ctx context.Context, req *pbCustomer.CreateCustomerRequest) (*pbCustomer.CreateCustomerResponse, error) {
+	document, err := h.validate(req.GetNome(), req.GetEmail(), req.GetDocument())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	customer, err := h.customerService.Create(ctx, &domain.Customer{
+		Nome:     req.GetNome(),
+		Email:    req.GetEmail(),
+		Document: document,
+		UserID:   req.GetUserId(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pbCustomer.CreateCustomerResponse{Customer: MapCustomerToProto(customer)}, nil
+}
+
+func (h *handler) UpdateCustomer(ctx context.Context, req *pbCustomer.UpdateCustomerRequest) (*pbCustomer.UpdateCustomerResponse, error) {
+	if req.GetVersion() == 0 {
+		return nil, toStatus(ErrMissingVersion)
+	}
+
+	document, err := h.validate(req.GetNome(), req.GetEmail(), req.GetDocument())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	customer, err := h.customerService.Update(ctx, &domain.Customer{
+		ID:       uint(req.GetId()),
+		Nome:     req.GetNome(),
+		Email:    req.GetEmail(),
+		Document: document,
+		Version:  uint(req.GetVersion()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pbCustomer.UpdateCustomerResponse{Customer: MapCustomerToProto(customer)}, nil
+}
+
+func (h *handler) DeleteCustomer(ctx context.Context, req *pbCustomer.DeleteCustomerRequest) (*pbCustomer.DeleteCustomerResponse, error) {
+	if req.GetVersion() == 0 {
+		return nil, toStatus(ErrMissingVersion)
+	}
+
+	if err := h.customerService.Delete(ctx, uint(req.GetId()), uint(req.GetVersion())); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pbCustomer.DeleteCustomerResponse{}, nil
+}
+
+// validate checks the writable fields and returns the normalized document,
+// or "" when none was given.
+func (h *handler) validate(nome, email, document string) (string, error) {
+	if nome == "" {
+		return "", ErrMissingNome
+	}
+
+	if !h.emailValidator.IsValid(email) {
+		return "", validator.ErrInvalidEmail
+	}
+
+	if document == "" {
+		return "", nil
+	}
+	return h.documentValidator.Normalize(document)
+}
@@ -0,0 +1,235 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pbCustomer "github.com/rasteiro11/MCABankCustomer/gen/proto/go/customer"
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth"
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth/authtest"
+	"github.com/rasteiro11/MCABankCustomer/pkg/payment/paymenttest"
+	"github.com/rasteiro11/MCABankCustomer/pkg/rbac"
+	customerGrpc "github.com/rasteiro11/MCABankCustomer/src/customer/delivery/grpc"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/repository"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testPolicy = `
+default_role: customer
+users:
+  admin: [1]
+  operator: [2]
+roles:
+  admin:
+    allow: ["*"]
+  operator:
+    allow: [customer.list, customer.read, customer.create, customer.update, customer.patch, customer.change_status]
+  customer:
+    own: [customer.read, customer.create, customer.patch]
+`
+
+// Bearer tokens of the users the test auth service knows.
+const (
+	adminToken    = "admin"
+	operatorToken = "operator"
+	ownerToken    = "owner"
+	strangerToken = "stranger"
+)
+
+// grpcServer adapts a bare grpc.Server to grpcserver.Server.
+type grpcServer struct {
+	srv *grpc.Server
+}
+
+func (s *grpcServer) Run() error { return nil }
+
+func (s *grpcServer) Register(desc grpc.ServiceDesc, service any) {
+	s.srv.RegisterService(&desc, service)
+}
+
+func (s *grpcServer) Close() {}
+
+// newClient serves the handler the way main does, behind the auth
+// interceptor and the policy, on memory repositories and fake auth and
+// payment services. Customer 1 belongs to the owner and has no funds;
+// customer 2 has a funded balance.
+func newClient(t *testing.T) pbCustomer.CustomerServiceClient {
+	t.Helper()
+	ctx := context.Background()
+
+	authSrv := authtest.NewServer()
+	authSrv.AddUser(10, "ana@example.com", "52998224725")
+	expires := time.Now().Add(time.Hour)
+	for token, userID := range map[string]uint64{adminToken: 1, operatorToken: 2, ownerToken: 10, strangerToken: 11} {
+		authSrv.AddSession(token, userID, expires)
+	}
+	authClient, stopAuth, err := authSrv.Start()
+	if err != nil {
+		t.Fatalf("authtest Start() returned error: %v", err)
+	}
+	t.Cleanup(stopAuth)
+
+	paymentSrv := paymenttest.NewServer()
+	paymentClient, stopPayment, err := paymentSrv.Start()
+	if err != nil {
+		t.Fatalf("paymenttest Start() returned error: %v", err)
+	}
+	t.Cleanup(stopPayment)
+
+	db := repository.NewMemoryDB()
+	repo := repository.NewMemoryCustomerRepository(db)
+	for _, c := range []*domain.Customer{
+		{Nome: "Ana", Email: "ana@example.com", Document: "52998224725", UserID: 10, Status: domain.StatusActive},
+		{Nome: "Bia", Email: "bia@example.com", Document: "11144477735", Status: domain.StatusActive},
+	} {
+		if _, err := repo.Create(ctx, c); err != nil {
+			t.Fatalf("Create() returned error: %v", err)
+		}
+	}
+	paymentSrv.SetBalance(1, 0, 0)
+	paymentSrv.SetBalance(2, 10, 0)
+
+	policy, err := rbac.Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	svc := service.NewCustomerService(repo, repository.NewMemorySagaRepository(db), paymentClient, authClient)
+
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(auth.NewUnaryServerInterceptor(authClient)))
+	customerGrpc.NewHandler(&grpcServer{srv: srv},
+		customerGrpc.WithCustomerService(service.NewPolicyService(svc, policy)))
+
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial() returned error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pbCustomer.NewCustomerServiceClient(conn)
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		call     func(context.Context, pbCustomer.CustomerServiceClient) error
+		wantCode codes.Code
+	}{
+		{name: "without a token", call: getCustomer(1), wantCode: codes.Unauthenticated},
+		{name: "with an unknown token", token: "forged", call: getCustomer(1), wantCode: codes.Unauthenticated},
+		{name: "get", token: adminToken, call: getCustomer(1), wantCode: codes.OK},
+		{name: "owner gets", token: ownerToken, call: getCustomer(1), wantCode: codes.OK},
+		{name: "get a missing customer", token: adminToken, call: getCustomer(99), wantCode: codes.NotFound},
+		{name: "stranger cannot get", token: strangerToken, call: getCustomer(1), wantCode: codes.PermissionDenied},
+		{name: "get by document", token: operatorToken, call: func(ctx context.Context, c pbCustomer.CustomerServiceClient) error {
+			_, err := c.GetCustomerByDocument(ctx, &pbCustomer.GetCustomerByDocumentRequest{Document: "529.982.247-25"})
+			return err
+		}, wantCode: codes.OK},
+		{name: "get by an invalid document", token: operatorToken, call: func(ctx context.Context, c pbCustomer.CustomerServiceClient) error {
+			_, err := c.GetCustomerByDocument(ctx, &pbCustomer.GetCustomerByDocumentRequest{Document: "123"})
+			return err
+		}, wantCode: codes.InvalidArgument},
+		{name: "list", token: operatorToken, call: listCustomers, wantCode: codes.OK},
+		{name: "customers cannot list", token: ownerToken, call: listCustomers, wantCode: codes.PermissionDenied},
+		{name: "create", token: adminToken, call: createCustomer("caio@example.com"), wantCode: codes.OK},
+		{name: "create with an invalid email", token: adminToken, call: createCustomer("caio"), wantCode: codes.InvalidArgument},
+		{name: "create with a taken email", token: adminToken, call: createCustomer("ana@example.com"), wantCode: codes.AlreadyExists},
+		{name: "update without a version", token: adminToken, call: updateCustomer(0), wantCode: codes.InvalidArgument},
+		{name: "update a stale version", token: adminToken, call: updateCustomer(5), wantCode: codes.Aborted},
+		{name: "update", token: adminToken, call: updateCustomer(1), wantCode: codes.OK},
+		{name: "customers cannot update", token: ownerToken, call: updateCustomer(1), wantCode: codes.PermissionDenied},
+		{name: "delete without a version", token: adminToken, call: deleteCustomer(1, 0), wantCode: codes.InvalidArgument},
+		{name: "delete a stale version", token: adminToken, call: deleteCustomer(1, 5), wantCode: codes.Aborted},
+		{name: "delete with funds", token: adminToken, call: deleteCustomer(2, 1), wantCode: codes.FailedPrecondition},
+		{name: "delete", token: adminToken, call: deleteCustomer(1, 1), wantCode: codes.OK},
+		{name: "operators cannot delete", token: operatorToken, call: deleteCustomer(1, 1), wantCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient(t)
+
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tt.token)
+			}
+
+			if code := status.Code(tt.call(ctx, client)); code != tt.wantCode {
+				t.Errorf("got %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestHandlerReturnsVersion(t *testing.T) {
+	client := newClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+adminToken)
+
+	res, err := client.UpdateCustomer(ctx, &pbCustomer.UpdateCustomerRequest{Id: 1, Nome: "Ana Maria", Email: "ana@example.com", Version: 1})
+	if err != nil {
+		t.Fatalf("UpdateCustomer() returned error: %v", err)
+	}
+	if got := res.GetCustomer().GetVersion(); got != 2 {
+		t.Fatalf("updated customer has version %d, want 2", got)
+	}
+
+	if _, err := client.DeleteCustomer(ctx, &pbCustomer.DeleteCustomerRequest{Id: 1, Version: 1}); status.Code(err) != codes.Aborted {
+		t.Errorf("delete with the old version returned %v, want Aborted", err)
+	}
+	if _, err := client.DeleteCustomer(ctx, &pbCustomer.DeleteCustomerRequest{Id: 1, Version: 2}); err != nil {
+		t.Errorf("delete with the current version returned error: %v", err)
+	}
+}
+
+func getCustomer(id uint32) func(context.Context, pbCustomer.CustomerServiceClient) error {
+	return func(ctx context.Context, c pbCustomer.CustomerServiceClient) error {
+		_, err := c.GetCustomer(ctx, &pbCustomer.GetCustomerRequest{Id: id})
+		return err
+	}
+}
+
+func listCustomers(ctx context.Context, c pbCustomer.CustomerServiceClient) error {
+	_, err := c.ListCustomers(ctx, &pbCustomer.ListCustomersRequest{Limit: 10})
+	return err
+}
+
+func createCustomer(email string) func(context.Context, pbCustomer.CustomerServiceClient) error {
+	return func(ctx context.Context, c pbCustomer.CustomerServiceClient) error {
+		_, err := c.CreateCustomer(ctx, &pbCustomer.CreateCustomerRequest{Nome: "Caio", Email: email})
+		return err
+	}
+}
+
+func updateCustomer(version uint32) func(context.Context, pbCustomer.CustomerServiceClient) error {
+	return func(ctx context.Context, c pbCustomer.CustomerServiceClient) error {
+		_, err := c.UpdateCustomer(ctx, &pbCustomer.UpdateCustomerRequest{Id: 1, Nome: "Ana Maria", Email: "ana@example.com", Version: version})
+		return err
+	}
+}
+
+func deleteCustomer(id, version uint32) func(context.Context, pbCustomer.CustomerServiceClient) error {
+	return func(ctx context.Context, c pbCustomer.CustomerServiceClient) error {
+		_, err := c.DeleteCustomer(ctx, &pbCustomer.DeleteCustomerRequest{Id: id, Version: version})
+		return err
+	}
+}
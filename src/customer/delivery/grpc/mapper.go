@@ -0,0 +1,29 @@
+package grpc
+
+import (
+	pbCustomer "github.com/rasteiro11/MCABankCustomer/gen/proto/go/customer"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+)
+
+func MapCustomerToProto(c *domain.Customer) *pbCustomer.Customer {
+	if c == nil {
+		return nil
+	}
+	return &pbCustomer.Customer{
+		Id:       uint32(c.ID),
+		Nome:     c.Nome,
+		Email:    c.Email,
+		Status:   string(c.Status),
+		Document: c.Document,
+		UserId:   c.UserID,
+		Version:  uint32(c.Version),
+	}
+}
+
+func MapCustomersToProto(customers []domain.Customer) []*pbCustomer.Customer {
+	out := make([]*pbCustomer.Customer, 0, len(customers))
+	for _, c := range customers {
+		out = append(out, MapCustomerToProto(&c))
+	}
+	return out
+}
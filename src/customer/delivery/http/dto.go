@@ -1,17 +1,99 @@
 package http
 
+import "time"
+
+// Document takes an optional CPF or CNPJ, with or without punctuation. A
+// non-zero UserID links the customer to that auth user, whose email and
+// document, when given, must match.
 type createCustomerRequest struct {
-	Nome  string `json:"nome" validate:"required"`
-	Email string `json:"email" validate:"required,email"`
+	Nome     string `json:"nome" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Document string `json:"document,omitempty" validate:"omitempty" example:"529.982.247-25"`
+	UserID   uint64 `json:"user_id,omitempty"`
 }
 
+// updateCustomerRequest replaces nome and email; an omitted document keeps
+// the stored one.
 type updateCustomerRequest struct {
-	Nome  string `json:"nome" validate:"required"`
-	Email string `json:"email" validate:"required,email"`
+	Nome     string `json:"nome" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Document string `json:"document,omitempty" validate:"omitempty" example:"529.982.247-25"`
+}
+
+const (
+	includeBalance = "balance"
+
+	balanceStatusOK          = "ok"
+	balanceStatusMissing     = "missing"
+	balanceStatusUnavailable = "unavailable"
+)
+
+// patchCustomerRequest documents the merge patch body. Omitted fields are
+// left unchanged; null clears the document and is rejected for nome and
+// email, which are required.
+type patchCustomerRequest struct {
+	Nome     *string `json:"nome,omitempty"`
+	Email    *string `json:"email,omitempty"`
+	Document *string `json:"document,omitempty"`
 }
 
 type customerResponse struct {
-	ID    uint   `json:"id"`
-	Nome  string `json:"nome"`
-	Email string `json:"email"`
+	ID             uint       `json:"id"`
+	Nome           string     `json:"nome"`
+	Email          string     `json:"email"`
+	Document       string     `json:"document,omitempty"`
+	UserID         uint64     `json:"user_id,omitempty"`
+	Status         string     `json:"status" enums:"pending,active,blocked,closed"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	Balance        *float64   `json:"balance,omitempty"`
+	BlockedBalance *float64   `json:"blocked_balance,omitempty"`
+	BalanceStatus  string     `json:"balance_status,omitempty" enums:"ok,missing,unavailable"`
+}
+
+type listCustomersQuery struct {
+	Limit       int    `query:"limit"`
+	Cursor      string `query:"cursor"`
+	Nome        string `query:"nome"`
+	NomeMatch   string `query:"nome_match"`
+	Email       string `query:"email"`
+	EmailMatch  string `query:"email_match"`
+	CreatedFrom string `query:"created_from"`
+	CreatedTo   string `query:"created_to"`
+	Sort        string `query:"sort"`
+	Include     string `query:"include"`
+	Deleted     bool   `query:"deleted"`
+	Status      string `query:"status"`
+}
+
+// statusChangeRequest is the optional body of a status transition. block
+// requires a reason.
+type statusChangeRequest struct {
+	Reason string `json:"reason"`
+}
+
+type statusChangeResponse struct {
+	ID        uint      `json:"id"`
+	Action    string    `json:"action" enums:"activate,block,unblock,close"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Reason    string    `json:"reason,omitempty"`
+	ChangedBy uint64    `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type purgeCustomersQuery struct {
+	OlderThanDays int `query:"older_than_days"`
+}
+
+type purgeCustomersResponse struct {
+	Purged        int64     `json:"purged"`
+	DeletedBefore time.Time `json:"deleted_before"`
+}
+
+type customerPageResponse struct {
+	Data       []*customerResponse `json:"data"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+	Total      int64               `json:"total"`
+	Limit      int                 `json:"limit"`
 }
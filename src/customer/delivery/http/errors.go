@@ -0,0 +1,138 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rasteiro11/MCABankCustomer/pkg/problem"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/PogCore/pkg/logger"
+	"github.com/rasteiro11/PogCore/pkg/validator"
+)
+
+const (
+	codeInternal   = "internal_error"
+	codeBadRequest = "bad_request"
+	codeValidation = "validation_failed"
+	codeMediaType  = "unsupported_media_type"
+	codePrecondReq = "precondition_required"
+	codeWeakETag   = "weak_etag"
+)
+
+var statusByKind = map[domain.ErrorKind]int{
+	domain.KindInvalid:               http.StatusBadRequest,
+	domain.KindNotFound:              http.StatusNotFound,
+	domain.KindAlreadyExists:         http.StatusConflict,
+	domain.KindConflict:              http.StatusConflict,
+	domain.KindDependencyUnavailable: http.StatusServiceUnavailable,
+	domain.KindDependencyRejected:    http.StatusBadGateway,
+	domain.KindPreconditionFailed:    http.StatusPreconditionFailed,
+	domain.KindForbidden:             http.StatusForbidden,
+}
+
+var ruleMessages = map[string]string{
+	"required": "is required",
+	"email":    "must be a valid email address",
+	"document": "must be a valid CPF or CNPJ",
+	"string":   "must be a string",
+	"unknown":  "is not a known field",
+}
+
+// NewStatusError answers with the problem matching a domain error kind and
+// its stable code. Unknown errors are logged and reported as a 500 without
+// leaking their message.
+func NewStatusError(c *fiber.Ctx, err error) error {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		if status, ok := statusByKind[domainErr.Kind]; ok {
+			return problem.New(c, status,
+				problem.WithCode(domainErr.Code),
+				problem.WithDetail(domainErr.Message))
+		}
+	}
+
+	logger.Of(c.Context()).Errorf("[handler] unexpected error on %s %s: %+v\n", c.Method(), c.Path(), err)
+	return problem.New(c, http.StatusInternalServerError, problem.WithCode(codeInternal))
+}
+
+func NewStatusBadRequest(c *fiber.Ctx, err error) error {
+	return problem.New(c, http.StatusBadRequest,
+		problem.WithCode(codeBadRequest),
+		problem.WithDetail(err.Error()))
+}
+
+// NewValidationError reports struct validation failures, naming fields by
+// their JSON name so clients can map them back to the request body.
+func NewValidationError(c *fiber.Ctx, req any, list *validator.ErrorListValidation) error {
+	errs := make([]problem.FieldError, 0, len(list.Validation))
+	for _, item := range list.Validation {
+		errs = append(errs, fieldError(jsonFieldName(req, item.Field), item.Key))
+	}
+
+	return NewFieldErrors(c, errs)
+}
+
+func NewFieldErrors(c *fiber.Ctx, errs []problem.FieldError) error {
+	return problem.New(c, http.StatusBadRequest,
+		problem.WithCode(codeValidation),
+		problem.WithDetail("request body failed validation"),
+		problem.WithErrors(errs...))
+}
+
+// NewStatusPreconditionError answers a missing If-Match with 428, a weak
+// one with 412 and a malformed one with 400.
+func NewStatusPreconditionError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrPreconditionRequired):
+		return problem.New(c, http.StatusPreconditionRequired,
+			problem.WithCode(codePrecondReq),
+			problem.WithDetail(err.Error()))
+	case errors.Is(err, ErrWeakIfMatch):
+		return problem.New(c, http.StatusPreconditionFailed,
+			problem.WithCode(codeWeakETag),
+			problem.WithDetail(err.Error()))
+	}
+	return NewStatusBadRequest(c, err)
+}
+
+func NewStatusUnsupportedMediaType(c *fiber.Ctx, expected string) error {
+	return problem.New(c, http.StatusUnsupportedMediaType,
+		problem.WithCode(codeMediaType),
+		problem.WithDetail("expected Content-Type "+expected))
+}
+
+func fieldError(field, rule string) problem.FieldError {
+	return problem.FieldError{
+		Field:   field,
+		Rule:    rule,
+		Message: field + " " + ruleMessage(rule),
+	}
+}
+
+func ruleMessage(rule string) string {
+	if msg, ok := ruleMessages[rule]; ok {
+		return msg
+	}
+	return "failed " + rule + " validation"
+}
+
+func jsonFieldName(req any, field string) string {
+	t := reflect.TypeOf(req)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	f, ok := t.FieldByName(field)
+	if !ok {
+		return field
+	}
+
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field
+	}
+	return name
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	ErrPreconditionRequired = errors.New("If-Match header is required")
+	ErrInvalidIfMatch       = errors.New("If-Match must be a single entity tag or *")
+	ErrWeakIfMatch          = errors.New("If-Match requires a strong entity tag")
+)
+
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+func setETag(c *fiber.Ctx, version uint) {
+	c.Set(fiber.HeaderETag, etag(version))
+}
+
+// expectedVersion reads the version a write is conditioned on. "*" only
+// requires the customer to exist and maps to zero, the unconditional write.
+// If-Match uses the strong comparison, so a weak tag never matches.
+func expectedVersion(c *fiber.Ctx) (uint, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" {
+		return 0, ErrPreconditionRequired
+	}
+	if header == "*" {
+		return 0, nil
+	}
+	if strings.HasPrefix(header, "W/") {
+		return 0, ErrWeakIfMatch
+	}
+
+	version, err := strconv.ParseUint(strings.Trim(header, `"`), 10, 64)
+	if err != nil || version == 0 || strings.Contains(header, ",") {
+		return 0, ErrInvalidIfMatch
+	}
+	return uint(version), nil
+}
+
+// notModified applies If-None-Match with the weak comparison RFC 9110
+// mandates for that header.
+func notModified(c *fiber.Ctx, version uint) bool {
+	header := c.Get(fiber.HeaderIfNoneMatch)
+	if header == "" {
+		return false
+	}
+
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
@@ -1,13 +1,22 @@
 package http
 
 import (
+	"encoding/json"
 	"errors"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth"
+	"github.com/rasteiro11/MCABankCustomer/pkg/idempotency"
+	"github.com/rasteiro11/MCABankCustomer/pkg/problem"
+	pkgValidator "github.com/rasteiro11/MCABankCustomer/pkg/validator"
 	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
 	"github.com/rasteiro11/MCABankCustomer/src/customer/service"
+	"github.com/rasteiro11/PogCore/pkg/logger"
 	"github.com/rasteiro11/PogCore/pkg/server"
 	"github.com/rasteiro11/PogCore/pkg/transport/rest"
 	"github.com/rasteiro11/PogCore/pkg/validator"
@@ -15,10 +24,15 @@ import (
 
 var CustomerGroupPath = "/customers"
 
+const mergePatchContentType = "application/merge-patch+json"
+
 type (
 	HandlerOpt func(*handler)
 	handler    struct {
-		customerService service.CustomerService
+		customerService   service.CustomerService
+		emailValidator    pkgValidator.EmailValidator
+		documentValidator pkgValidator.DocumentValidator
+		idempotent        idempotency.Decorator
 	}
 )
 
@@ -28,41 +42,99 @@ func WithCustomerService(s service.CustomerService) HandlerOpt {
 	}
 }
 
+// WithIdempotency makes POST /customers honour the Idempotency-Key header.
+func WithIdempotency(d idempotency.Decorator) HandlerOpt {
+	return func(h *handler) {
+		h.idempotent = d
+	}
+}
+
 func NewHandler(server server.Server, opts ...HandlerOpt) {
-	h := &handler{}
+	h := &handler{
+		emailValidator:    pkgValidator.NewEmailValidator(),
+		documentValidator: pkgValidator.NewDocumentValidator(),
+		idempotent:        func(next fiber.Handler) fiber.Handler { return next },
+	}
 
 	for _, opt := range opts {
 		opt(h)
 	}
 
 	server.AddHandler("", CustomerGroupPath, http.MethodGet, h.FindAll)
+	// /me must be registered before /:id, which would match it too
+	server.AddHandler("/me", CustomerGroupPath, http.MethodGet, h.Me)
 	server.AddHandler("/:id", CustomerGroupPath, http.MethodGet, h.FindByID)
-	server.AddHandler("", CustomerGroupPath, http.MethodPost, h.Create)
+	server.AddHandler("/by-document/:doc", CustomerGroupPath, http.MethodGet, h.FindByDocument)
+	server.AddHandler("", CustomerGroupPath, http.MethodPost, h.idempotent(h.Create))
+	server.AddHandler("/purge", CustomerGroupPath, http.MethodPost, h.Purge)
+	server.AddHandler("/:id/restore", CustomerGroupPath, http.MethodPost, h.Restore)
+	server.AddHandler("/:id/activate", CustomerGroupPath, http.MethodPost, h.Activate)
+	server.AddHandler("/:id/block", CustomerGroupPath, http.MethodPost, h.Block)
+	server.AddHandler("/:id/unblock", CustomerGroupPath, http.MethodPost, h.Unblock)
+	server.AddHandler("/:id/close", CustomerGroupPath, http.MethodPost, h.Close)
+	server.AddHandler("/:id/status-history", CustomerGroupPath, http.MethodGet, h.StatusHistory)
 	server.AddHandler("/:id", CustomerGroupPath, http.MethodPut, h.Update)
+	server.AddHandler("/:id", CustomerGroupPath, http.MethodPatch, h.Patch)
 	server.AddHandler("/:id", CustomerGroupPath, http.MethodDelete, h.Delete)
 }
 
 var _ Handler = (*handler)(nil)
 
 var (
-	ErrPathParam     = errors.New("path param is missing")
-	ErrTypeAssertion = errors.New("type assertion error")
+	ErrPathParam         = errors.New("path param is missing")
+	ErrTypeAssertion     = errors.New("type assertion error")
+	ErrInvalidTime       = errors.New("time must be in RFC 3339 format")
+	ErrPatchNotObject    = errors.New("merge patch body must be a JSON object")
+	ErrNegativeOlderThan = errors.New("older_than_days must not be negative")
 )
 
 // FindAll godoc
-// @Summary Get all customers
-// @Description Retrieve a list of all customers
+// @Summary List customers
+// @Description Retrieve a page of customers, optionally filtered and sorted
 // @Tags customers
 // @Produce json
-// @Success 200 {array} customerResponse
-// @Failure 500 {object} any
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Param nome query string false "Filter by name"
+// @Param nome_match query string false "Name match mode" Enums(exact, prefix, contains)
+// @Param email query string false "Filter by email"
+// @Param email_match query string false "Email match mode" Enums(exact, prefix, contains)
+// @Param created_from query string false "Created at or after (RFC 3339)"
+// @Param created_to query string false "Created before (RFC 3339)"
+// @Param sort query string false "Sort field, prefix with - for descending" Enums(id, -id, nome, -nome, email, -email, created_at, -created_at)
+// @Param include query string false "Comma separated related data to embed" Enums(balance)
+// @Param deleted query bool false "List soft-deleted customers instead of live ones"
+// @Param status query string false "Filter by status" Enums(pending, active, blocked, closed)
+// @Success 200 {object} customerPageResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Failure 503 {object} problem.Problem
+// @Security BearerAuth
 // @Router /customers [get]
 func (h *handler) FindAll(c *fiber.Ctx) error {
-	customers, err := h.customerService.GetAll(c.Context())
+	req := &listCustomersQuery{}
+	if err := c.QueryParser(req); err != nil {
+		return NewStatusBadRequest(c, err)
+	}
+
+	query, err := MapListQueryToDomain(req)
+	if err != nil {
+		return NewStatusError(c, err)
+	}
+
+	page, err := h.customerService.GetAll(c.UserContext(), query)
 	if err != nil {
-		return rest.NewStatusInternalServerError(c, err)
+		return NewStatusError(c, err)
 	}
-	return rest.NewStatusOk(c, rest.WithBody(MapCustomersToHTTP(customers)))
+
+	res := MapCustomerPageToHTTP(page)
+	if hasInclude(req.Include, includeBalance) {
+		h.attachBalances(c, res.Data)
+	}
+
+	return rest.NewStatusOk(c, rest.WithBody(res))
 }
 
 // FindByID godoc
@@ -71,21 +143,113 @@ func (h *handler) FindAll(c *fiber.Ctx) error {
 // @Tags customers
 // @Produce json
 // @Param id path int true "Customer ID"
+// @Param include query string false "Comma separated related data to embed" Enums(balance)
+// @Param If-None-Match header string false "ETag from a previous response"
 // @Success 200 {object} customerResponse
-// @Failure 400 {object} any
-// @Failure 404 {object} any
+// @Header 200 {string} ETag "Current customer version, omitted with include=balance"
+// @Success 304 "Not modified"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 503 {object} problem.Problem
+// @Security BearerAuth
 // @Router /customers/{id} [get]
 func (h *handler) FindByID(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return rest.NewStatusBadRequest(c, err)
+		return NewStatusBadRequest(c, err)
 	}
 
-	customer, err := h.customerService.GetByID(c.Context(), uint(id))
+	customer, err := h.customerService.GetByID(c.UserContext(), uint(id))
 	if err != nil {
-		return rest.NewStatusNotFound(c, err)
+		return NewStatusError(c, err)
 	}
 
+	res := MapCustomerToHTTP(customer)
+	if hasInclude(c.Query("include"), includeBalance) {
+		// the balance changes without bumping the customer version, so a
+		// response embedding it gets no validator
+		h.attachBalances(c, []*customerResponse{res})
+		return rest.NewStatusOk(c, rest.WithBody(res))
+	}
+
+	setETag(c, customer.Version)
+	if notModified(c, customer.Version) {
+		return c.SendStatus(http.StatusNotModified)
+	}
+
+	return rest.NewStatusOk(c, rest.WithBody(res))
+}
+
+// Me godoc
+// @Summary Get the caller's customer
+// @Description Retrieve the customer linked to the authenticated user, linking it by document on first access
+// @Tags customers
+// @Produce json
+// @Param include query string false "Comma separated related data to embed" Enums(balance)
+// @Success 200 {object} customerResponse
+// @Header 200 {string} ETag "Current customer version, omitted with include=balance"
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 503 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/me [get]
+func (h *handler) Me(c *fiber.Ctx) error {
+	session, err := auth.SessionFromFiber(c)
+	if err != nil {
+		return NewStatusError(c, err)
+	}
+
+	customer, err := h.customerService.GetByUser(c.UserContext(), session.UserID)
+	if err != nil {
+		return NewStatusError(c, err)
+	}
+
+	res := MapCustomerToHTTP(customer)
+	if hasInclude(c.Query("include"), includeBalance) {
+		h.attachBalances(c, []*customerResponse{res})
+	} else {
+		setETag(c, customer.Version)
+	}
+
+	return rest.NewStatusOk(c, rest.WithBody(res))
+}
+
+// FindByDocument godoc
+// @Summary Get a customer by document
+// @Description Retrieve a live customer by CPF or CNPJ, with or without punctuation; a CNPJ slash must be percent-encoded
+// @Tags customers
+// @Produce json
+// @Param doc path string true "CPF or CNPJ"
+// @Success 200 {object} customerResponse
+// @Header 200 {string} ETag "Current customer version"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 503 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/by-document/{doc} [get]
+func (h *handler) FindByDocument(c *fiber.Ctx) error {
+	// a CNPJ slash only reaches us percent-encoded
+	raw, err := url.PathUnescape(c.Params("doc"))
+	if err != nil {
+		return NewStatusError(c, domain.ErrInvalidDocument)
+	}
+
+	document, err := h.documentValidator.Normalize(raw)
+	if err != nil {
+		return NewStatusError(c, domain.ErrInvalidDocument)
+	}
+
+	customer, err := h.customerService.GetByDocument(c.UserContext(), document)
+	if err != nil {
+		return NewStatusError(c, err)
+	}
+
+	setETag(c, customer.Version)
 	return rest.NewStatusOk(c, rest.WithBody(MapCustomerToHTTP(customer)))
 }
 
@@ -96,65 +260,157 @@ func (h *handler) FindByID(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param request body createCustomerRequest true "Customer info"
+// @Param Idempotency-Key header string false "Client generated key; retries with the same key replay the first response"
 // @Success 201 {object} customerResponse
-// @Failure 400 {object} any
-// @Failure 422 {object} any
+// @Header 201 {string} ETag "Current customer version"
+// @Header 201 {string} Idempotent-Replayed "true when the response is a replay"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 502 {object} problem.Problem
+// @Failure 503 {object} problem.Problem
+// @Security BearerAuth
 // @Router /customers [post]
 func (h *handler) Create(c *fiber.Ctx) error {
 	req := &createCustomerRequest{}
 
 	if err := c.BodyParser(req); err != nil {
-		return rest.NewStatusBadRequest(c, err)
+		return NewStatusBadRequest(c, err)
 	}
 
 	if _, err := validator.IsRequestValid(req); err != nil {
-		return rest.NewResponse(c, http.StatusBadRequest, rest.WithBody(err)).JSON(c)
+		return NewValidationError(c, req, err)
+	}
+
+	if err := h.normalizeDocument(&req.Document); err != nil {
+		return NewFieldErrors(c, []problem.FieldError{fieldError("document", "document")})
 	}
 
-	customer, err := h.customerService.Create(c.Context(), MapCreateRequestToDomain(req))
+	customer, err := h.customerService.Create(c.UserContext(), MapCreateRequestToDomain(req))
 	if err != nil {
-		return rest.NewStatusUnprocessableEntity(c, err)
+		return NewStatusError(c, err)
 	}
 
+	setETag(c, customer.Version)
 	return rest.NewStatusCreated(c, rest.WithBody(MapCustomerToHTTP(customer)))
 }
 
 // Update godoc
 // @Summary Update an existing customer
-// @Description Update a customer's name and email by ID
+// @Description Replace a customer's name and email by ID
 // @Tags customers
 // @Accept json
 // @Produce json
 // @Param id path int true "Customer ID"
 // @Param request body updateCustomerRequest true "Updated customer info"
+// @Param If-Match header string true "ETag of the version being modified, or *"
 // @Success 200 {object} customerResponse
-// @Failure 400 {object} any
-// @Failure 422 {object} any
+// @Header 200 {string} ETag "Current customer version"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 503 {object} problem.Problem
+// @Security BearerAuth
 // @Router /customers/{id} [put]
 func (h *handler) Update(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return rest.NewStatusBadRequest(c, err)
+		return NewStatusBadRequest(c, err)
+	}
+
+	version, err := expectedVersion(c)
+	if err != nil {
+		return NewStatusPreconditionError(c, err)
 	}
 
 	req := &updateCustomerRequest{}
 	if err := c.BodyParser(req); err != nil {
-		return rest.NewStatusBadRequest(c, err)
+		return NewStatusBadRequest(c, err)
 	}
 
 	if _, err := validator.IsRequestValid(req); err != nil {
-		return rest.NewResponse(c, http.StatusBadRequest, rest.WithBody(err)).JSON(c)
+		return NewValidationError(c, req, err)
+	}
+
+	if err := h.normalizeDocument(&req.Document); err != nil {
+		return NewFieldErrors(c, []problem.FieldError{fieldError("document", "document")})
 	}
 
-	customer, err := h.customerService.Update(c.Context(), &domain.Customer{
-		ID:    uint(id),
-		Nome:  req.Nome,
-		Email: req.Email,
+	customer, err := h.customerService.Update(c.UserContext(), &domain.Customer{
+		ID:       uint(id),
+		Nome:     req.Nome,
+		Email:    req.Email,
+		Document: req.Document,
+		Version:  version,
 	})
 	if err != nil {
-		return rest.NewStatusUnprocessableEntity(c, err)
+		return NewStatusError(c, err)
+	}
+
+	setETag(c, customer.Version)
+	return rest.NewStatusOk(c, rest.WithBody(MapCustomerToHTTP(customer)))
+}
+
+// Patch godoc
+// @Summary Partially update a customer
+// @Description Apply a JSON Merge Patch (RFC 7396); only the supplied fields are validated and updated
+// @Tags customers
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param request body patchCustomerRequest true "Fields to change"
+// @Param If-Match header string true "ETag of the version being modified, or *"
+// @Success 200 {object} customerResponse
+// @Header 200 {string} ETag "Current customer version"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 503 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/{id} [patch]
+func (h *handler) Patch(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return NewStatusBadRequest(c, err)
+	}
+
+	version, err := expectedVersion(c)
+	if err != nil {
+		return NewStatusPreconditionError(c, err)
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType)); err != nil || mediaType != mergePatchContentType {
+		return NewStatusUnsupportedMediaType(c, mergePatchContentType)
+	}
+
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal(c.Body(), &doc); err != nil || doc == nil {
+		return NewStatusBadRequest(c, ErrPatchNotObject)
+	}
+
+	patch, fieldErrs := MapMergePatchToDomain(doc, h.emailValidator, h.documentValidator)
+	if len(fieldErrs) > 0 {
+		return NewFieldErrors(c, fieldErrs)
+	}
+	patch.ExpectedVersion = version
+
+	customer, err := h.customerService.Patch(c.UserContext(), uint(id), patch)
+	if err != nil {
+		return NewStatusError(c, err)
 	}
 
+	setETag(c, customer.Version)
 	return rest.NewStatusOk(c, rest.WithBody(MapCustomerToHTTP(customer)))
 }
 
@@ -163,19 +419,268 @@ func (h *handler) Update(c *fiber.Ctx) error {
 // @Description Delete a customer by ID
 // @Tags customers
 // @Param id path int true "Customer ID"
+// @Param If-Match header string true "ETag of the version being modified, or *"
 // @Success 200 {string} string "Customer deleted successfully"
-// @Failure 400 {object} any
-// @Failure 422 {object} any
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 412 {object} problem.Problem
+// @Failure 428 {object} problem.Problem
+// @Failure 502 {object} problem.Problem
+// @Failure 503 {object} problem.Problem
+// @Security BearerAuth
 // @Router /customers/{id} [delete]
 func (h *handler) Delete(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return rest.NewStatusBadRequest(c, err)
+		return NewStatusBadRequest(c, err)
 	}
 
-	if err := h.customerService.Delete(c.Context(), uint(id)); err != nil {
-		return rest.NewStatusUnprocessableEntity(c, err)
+	version, err := expectedVersion(c)
+	if err != nil {
+		return NewStatusPreconditionError(c, err)
+	}
+
+	if err := h.customerService.Delete(c.UserContext(), uint(id), version); err != nil {
+		return NewStatusError(c, err)
 	}
 
 	return rest.NewStatusOk(c, rest.WithBody("Customer deleted successfully"))
 }
+
+// Restore godoc
+// @Summary Restore a deleted customer
+// @Description Undo the soft delete of a customer that has not been purged yet
+// @Tags customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Success 200 {object} customerResponse
+// @Header 200 {string} ETag "Current customer version"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 503 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/{id}/restore [post]
+func (h *handler) Restore(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return NewStatusBadRequest(c, err)
+	}
+
+	customer, err := h.customerService.Restore(c.UserContext(), uint(id))
+	if err != nil {
+		return NewStatusError(c, err)
+	}
+
+	setETag(c, customer.Version)
+	return rest.NewStatusOk(c, rest.WithBody(MapCustomerToHTTP(customer)))
+}
+
+// Purge godoc
+// @Summary Purge deleted customers
+// @Description Permanently remove customers soft-deleted before the retention period (admin only)
+// @Tags customers
+// @Produce json
+// @Param older_than_days query int false "Only purge customers deleted at least this many days ago; never less than the retention period"
+// @Success 200 {object} purgeCustomersResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 503 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/purge [post]
+func (h *handler) Purge(c *fiber.Ctx) error {
+	req := &purgeCustomersQuery{}
+	if err := c.QueryParser(req); err != nil {
+		return NewStatusBadRequest(c, err)
+	}
+	if req.OlderThanDays < 0 {
+		return NewStatusBadRequest(c, ErrNegativeOlderThan)
+	}
+
+	result, err := h.customerService.Purge(c.UserContext(), time.Duration(req.OlderThanDays)*24*time.Hour)
+	if err != nil {
+		return NewStatusError(c, err)
+	}
+
+	return rest.NewStatusOk(c, rest.WithBody(MapPurgeResultToHTTP(result)))
+}
+
+// Activate godoc
+// @Summary Activate a customer
+// @Description Move a pending customer to active
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param request body statusChangeRequest false "Reason for the change"
+// @Success 200 {object} customerResponse
+// @Header 200 {string} ETag "Current customer version"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/{id}/activate [post]
+func (h *handler) Activate(c *fiber.Ctx) error {
+	return h.changeStatus(c, domain.StatusActionActivate)
+}
+
+// Block godoc
+// @Summary Block a customer
+// @Description Block a pending or active customer; a reason is required
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param request body statusChangeRequest true "Reason for blocking"
+// @Success 200 {object} customerResponse
+// @Header 200 {string} ETag "Current customer version"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/{id}/block [post]
+func (h *handler) Block(c *fiber.Ctx) error {
+	return h.changeStatus(c, domain.StatusActionBlock)
+}
+
+// Unblock godoc
+// @Summary Unblock a customer
+// @Description Move a blocked customer back to active
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param request body statusChangeRequest false "Reason for the change"
+// @Success 200 {object} customerResponse
+// @Header 200 {string} ETag "Current customer version"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/{id}/unblock [post]
+func (h *handler) Unblock(c *fiber.Ctx) error {
+	return h.changeStatus(c, domain.StatusActionUnblock)
+}
+
+// Close godoc
+// @Summary Close a customer
+// @Description Close a customer for good; the payment balance must be zero
+// @Tags customers
+// @Accept json
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Param request body statusChangeRequest false "Reason for the change"
+// @Success 200 {object} customerResponse
+// @Header 200 {string} ETag "Current customer version"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 502 {object} problem.Problem
+// @Failure 503 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/{id}/close [post]
+func (h *handler) Close(c *fiber.Ctx) error {
+	return h.changeStatus(c, domain.StatusActionClose)
+}
+
+// StatusHistory godoc
+// @Summary List status changes of a customer
+// @Description Audit trail of status transitions, oldest first
+// @Tags customers
+// @Produce json
+// @Param id path int true "Customer ID"
+// @Success 200 {array} statusChangeResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Security BearerAuth
+// @Router /customers/{id}/status-history [get]
+func (h *handler) StatusHistory(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return NewStatusBadRequest(c, err)
+	}
+
+	changes, err := h.customerService.StatusHistory(c.UserContext(), uint(id))
+	if err != nil {
+		return NewStatusError(c, err)
+	}
+
+	return rest.NewStatusOk(c, rest.WithBody(MapStatusChangesToHTTP(changes)))
+}
+
+// changeStatus applies a status transition on behalf of the session user.
+func (h *handler) changeStatus(c *fiber.Ctx, action domain.StatusAction) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return NewStatusBadRequest(c, err)
+	}
+
+	session, err := auth.SessionFromFiber(c)
+	if err != nil {
+		return NewStatusError(c, err)
+	}
+
+	req := &statusChangeRequest{}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(req); err != nil {
+			return NewStatusBadRequest(c, err)
+		}
+	}
+
+	customer, err := h.customerService.ChangeStatus(c.UserContext(), uint(id), action, req.Reason, session.UserID)
+	if err != nil {
+		return NewStatusError(c, err)
+	}
+
+	setETag(c, customer.Version)
+	return rest.NewStatusOk(c, rest.WithBody(MapCustomerToHTTP(customer)))
+}
+
+// normalizeDocument normalizes an optional document in place; an empty one
+// stays empty.
+func (h *handler) normalizeDocument(document *string) error {
+	if *document == "" {
+		return nil
+	}
+
+	normalized, err := h.documentValidator.Normalize(*document)
+	if err != nil {
+		return err
+	}
+	*document = normalized
+	return nil
+}
+
+// attachBalances embeds balances with one batched call. Payment service
+// failures degrade to balance_status "unavailable" instead of failing the
+// request.
+func (h *handler) attachBalances(c *fiber.Ctx, customers []*customerResponse) {
+	ids := make([]uint, 0, len(customers))
+	for _, customer := range customers {
+		ids = append(ids, customer.ID)
+	}
+
+	balances, err := h.customerService.GetBalances(c.UserContext(), ids)
+	if err != nil {
+		logger.Of(c.UserContext()).Warnf("[handler.attachBalances] customerService.GetBalances() returned error: %+v\n", err)
+		balances = nil
+	}
+
+	MapBalancesToHTTP(customers, balances)
+}
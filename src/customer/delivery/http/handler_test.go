@@ -0,0 +1,264 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth"
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth/authtest"
+	"github.com/rasteiro11/MCABankCustomer/pkg/idempotency"
+	"github.com/rasteiro11/MCABankCustomer/pkg/payment/paymenttest"
+	"github.com/rasteiro11/MCABankCustomer/pkg/rbac"
+	customerHttp "github.com/rasteiro11/MCABankCustomer/src/customer/delivery/http"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/repository"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/service"
+)
+
+const testPolicy = `
+default_role: customer
+users:
+  admin: [1]
+  operator: [2]
+roles:
+  admin:
+    allow: ["*"]
+  operator:
+    allow: [customer.list, customer.read, customer.create, customer.update, customer.patch, customer.change_status]
+  customer:
+    own: [customer.read, customer.create, customer.patch]
+`
+
+// Bearer tokens of the users the test auth service knows.
+const (
+	adminToken    = "admin"
+	operatorToken = "operator"
+	ownerToken    = "owner"
+	strangerToken = "stranger"
+)
+
+// fiberServer adapts a bare fiber app to server.Server, so tests can call
+// app.Test on the routes the handler registers.
+type fiberServer struct {
+	app *fiber.App
+}
+
+func (s *fiberServer) AddHandler(path, group, method string, handler fiber.Handler, middlewares ...fiber.Handler) {
+	for _, middleware := range middlewares {
+		s.app.Use(group+path, middleware)
+	}
+	s.app.Add(method, group+path, handler)
+}
+
+func (s *fiberServer) Use(group string, middlewares ...fiber.Handler) {
+	for _, middleware := range middlewares {
+		s.app.Use(group, middleware)
+	}
+}
+
+func (s *fiberServer) Start(port string) error {
+	return s.app.Listen(port)
+}
+
+func (s *fiberServer) PrintRouter() {}
+
+// newApp wires the handler the way main does, on memory repositories and
+// fake auth and payment services. Customer 1 belongs to the owner and has
+// no funds; customer 2 has a funded balance.
+func newApp(t *testing.T) *fiber.App {
+	t.Helper()
+	ctx := context.Background()
+
+	authSrv := authtest.NewServer()
+	authSrv.AddUser(10, "ana@example.com", "52998224725")
+	expires := time.Now().Add(time.Hour)
+	for token, userID := range map[string]uint64{adminToken: 1, operatorToken: 2, ownerToken: 10, strangerToken: 11} {
+		authSrv.AddSession(token, userID, expires)
+	}
+	authClient, stopAuth, err := authSrv.Start()
+	if err != nil {
+		t.Fatalf("authtest Start() returned error: %v", err)
+	}
+	t.Cleanup(stopAuth)
+
+	paymentSrv := paymenttest.NewServer()
+	paymentClient, stopPayment, err := paymentSrv.Start()
+	if err != nil {
+		t.Fatalf("paymenttest Start() returned error: %v", err)
+	}
+	t.Cleanup(stopPayment)
+
+	db := repository.NewMemoryDB()
+	repo := repository.NewMemoryCustomerRepository(db)
+	for _, c := range []*domain.Customer{
+		{Nome: "Ana", Email: "ana@example.com", Document: "52998224725", UserID: 10, Status: domain.StatusActive},
+		{Nome: "Bia", Email: "bia@example.com", Document: "11144477735", Status: domain.StatusActive},
+	} {
+		if _, err := repo.Create(ctx, c); err != nil {
+			t.Fatalf("Create() returned error: %v", err)
+		}
+	}
+	paymentSrv.SetBalance(1, 0, 0)
+	paymentSrv.SetBalance(2, 10, 0)
+
+	policy, err := rbac.Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	svc := service.NewCustomerService(repo, repository.NewMemorySagaRepository(db), paymentClient, authClient)
+
+	srv := &fiberServer{app: fiber.New()}
+	srv.Use(customerHttp.CustomerGroupPath, auth.NewMiddleware(authClient))
+	customerHttp.NewHandler(srv,
+		customerHttp.WithCustomerService(service.NewPolicyService(svc, policy)),
+		customerHttp.WithIdempotency(idempotency.NewDecorator(idempotency.NewMemoryStore())),
+	)
+	return srv.app
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		token       string
+		headers     map[string]string
+		body        string
+		wantStatus  int
+		wantInBody  string
+		wantHeaders map[string]string
+	}{
+		{name: "no token", method: http.MethodGet, path: "/customers/1", wantStatus: http.StatusUnauthorized},
+		{name: "unknown token", method: http.MethodGet, path: "/customers/1", token: "forged", wantStatus: http.StatusUnauthorized},
+		{name: "find by id", method: http.MethodGet, path: "/customers/1", token: adminToken, wantStatus: http.StatusOK, wantInBody: `"nome":"Ana"`, wantHeaders: map[string]string{fiber.HeaderETag: `"1"`}},
+		{name: "find by id not modified", method: http.MethodGet, path: "/customers/1", token: adminToken, headers: map[string]string{fiber.HeaderIfNoneMatch: `"1"`}, wantStatus: http.StatusNotModified},
+		{name: "find by id not modified by a weak tag", method: http.MethodGet, path: "/customers/1", token: adminToken, headers: map[string]string{fiber.HeaderIfNoneMatch: `W/"1"`}, wantStatus: http.StatusNotModified},
+		{name: "find by id with balance", method: http.MethodGet, path: "/customers/2?include=balance", token: operatorToken, wantStatus: http.StatusOK, wantInBody: `"balance":10`},
+		{name: "find by id with balance has no validator", method: http.MethodGet, path: "/customers/2?include=balance", token: operatorToken, headers: map[string]string{fiber.HeaderIfNoneMatch: `"1"`}, wantStatus: http.StatusOK, wantInBody: `"balance":10`, wantHeaders: map[string]string{fiber.HeaderETag: ""}},
+		{name: "find a missing id", method: http.MethodGet, path: "/customers/99", token: adminToken, wantStatus: http.StatusNotFound},
+		{name: "find a malformed id", method: http.MethodGet, path: "/customers/abc", token: adminToken, wantStatus: http.StatusBadRequest},
+		{name: "owner finds by document", method: http.MethodGet, path: "/customers/by-document/529.982.247-25", token: ownerToken, wantStatus: http.StatusOK},
+		{name: "stranger cannot probe documents", method: http.MethodGet, path: "/customers/by-document/529.982.247-25", token: strangerToken, wantStatus: http.StatusForbidden},
+		{name: "invalid document", method: http.MethodGet, path: "/customers/by-document/123", token: adminToken, wantStatus: http.StatusBadRequest},
+		{name: "me", method: http.MethodGet, path: "/customers/me", token: ownerToken, wantStatus: http.StatusOK, wantInBody: `"email":"ana@example.com"`},
+		{name: "list", method: http.MethodGet, path: "/customers?sort=-id", token: operatorToken, wantStatus: http.StatusOK, wantInBody: `"nome":"Bia"`},
+		{name: "customers cannot list", method: http.MethodGet, path: "/customers", token: ownerToken, wantStatus: http.StatusForbidden},
+		{name: "create", method: http.MethodPost, path: "/customers", token: adminToken, body: `{"nome":"Caio","email":"caio@example.com"}`, wantStatus: http.StatusCreated, wantInBody: `"status":"pending"`},
+		{name: "create with an invalid email", method: http.MethodPost, path: "/customers", token: adminToken, body: `{"nome":"Caio","email":"caio"}`, wantStatus: http.StatusBadRequest, wantInBody: "validation_failed"},
+		{name: "create with a taken email", method: http.MethodPost, path: "/customers", token: adminToken, body: `{"nome":"Ana","email":"ana@example.com"}`, wantStatus: http.StatusConflict},
+		{name: "update without If-Match", method: http.MethodPut, path: "/customers/1", token: adminToken, body: `{"nome":"Ana","email":"ana@example.com"}`, wantStatus: http.StatusPreconditionRequired},
+		{name: "update with a weak tag", method: http.MethodPut, path: "/customers/1", token: adminToken, headers: map[string]string{fiber.HeaderIfMatch: `W/"1"`}, body: `{"nome":"Ana","email":"ana@example.com"}`, wantStatus: http.StatusPreconditionFailed, wantInBody: "weak_etag"},
+		{name: "update a stale version", method: http.MethodPut, path: "/customers/1", token: adminToken, headers: map[string]string{fiber.HeaderIfMatch: `"5"`}, body: `{"nome":"Ana","email":"ana@example.com"}`, wantStatus: http.StatusPreconditionFailed},
+		{name: "update keeps the document", method: http.MethodPut, path: "/customers/1", token: adminToken, headers: map[string]string{fiber.HeaderIfMatch: `"1"`}, body: `{"nome":"Ana Maria","email":"ana@example.com"}`, wantStatus: http.StatusOK, wantInBody: `"document":"52998224725"`, wantHeaders: map[string]string{fiber.HeaderETag: `"2"`}},
+		{name: "owner patches", method: http.MethodPatch, path: "/customers/1", token: ownerToken, headers: map[string]string{fiber.HeaderContentType: "application/merge-patch+json", fiber.HeaderIfMatch: "*"}, body: `{"nome":"Ana Maria"}`, wantStatus: http.StatusOK, wantInBody: `"nome":"Ana Maria"`},
+		{name: "stranger cannot patch", method: http.MethodPatch, path: "/customers/1", token: strangerToken, headers: map[string]string{fiber.HeaderContentType: "application/merge-patch+json", fiber.HeaderIfMatch: "*"}, body: `{"nome":"Eve"}`, wantStatus: http.StatusForbidden},
+		{name: "delete", method: http.MethodDelete, path: "/customers/1", token: adminToken, headers: map[string]string{fiber.HeaderIfMatch: "*"}, wantStatus: http.StatusOK},
+		{name: "delete with funds", method: http.MethodDelete, path: "/customers/2", token: adminToken, headers: map[string]string{fiber.HeaderIfMatch: "*"}, wantStatus: http.StatusConflict, wantInBody: "balance_not_empty"},
+		{name: "block without a reason", method: http.MethodPost, path: "/customers/1/block", token: operatorToken, wantStatus: http.StatusBadRequest, wantInBody: "reason_required"},
+		{name: "block", method: http.MethodPost, path: "/customers/1/block", token: operatorToken, headers: map[string]string{fiber.HeaderContentType: fiber.MIMEApplicationJSON}, body: `{"reason":"fraud"}`, wantStatus: http.StatusOK, wantInBody: `"status":"blocked"`},
+		{name: "unblock an active customer", method: http.MethodPost, path: "/customers/1/unblock", token: operatorToken, wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newApp(t)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			}
+			if tt.token != "" {
+				req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tt.token)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			res, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("Test() returned error: %v", err)
+			}
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("got %d, want %d: %s", res.StatusCode, tt.wantStatus, body)
+			}
+			if !strings.Contains(string(body), tt.wantInBody) {
+				t.Errorf("body %s does not contain %s", body, tt.wantInBody)
+			}
+			for k, v := range tt.wantHeaders {
+				if got := res.Header.Get(k); got != v {
+					t.Errorf("header %s is %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerReplaysIdempotentCreate(t *testing.T) {
+	app := newApp(t)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/customers", strings.NewReader(`{"nome":"Caio","email":"caio@example.com"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+adminToken)
+		req.Header.Set(idempotency.HeaderKey, "create-caio")
+
+		res, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("Test() returned error: %v", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusCreated {
+			t.Fatalf("request %d got %d, want 201", i, res.StatusCode)
+		}
+		if replayed := res.Header.Get(idempotency.HeaderReplayed) == "true"; replayed != (i == 1) {
+			t.Errorf("request %d replayed %v", i, replayed)
+		}
+	}
+}
+
+func TestHandlerClearsDocument(t *testing.T) {
+	app := newApp(t)
+
+	req := httptest.NewRequest(http.MethodPatch, "/customers/1", strings.NewReader(`{"document":null}`))
+	req.Header.Set(fiber.HeaderContentType, "application/merge-patch+json")
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+ownerToken)
+	req.Header.Set(fiber.HeaderIfMatch, "*")
+
+	res, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("Test() returned error: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("patch got %d, want 200: %s", res.StatusCode, body)
+	}
+	if strings.Contains(string(body), `"document"`) {
+		t.Errorf("patched customer still has a document: %s", body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/customers/by-document/529.982.247-25", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+adminToken)
+
+	res, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("Test() returned error: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("find by the cleared document got %d, want 404", res.StatusCode)
+	}
+}
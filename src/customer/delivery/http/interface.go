@@ -6,8 +6,18 @@ type (
 	Handler interface {
 		FindAll(c *fiber.Ctx) error
 		FindByID(c *fiber.Ctx) error
+		FindByDocument(c *fiber.Ctx) error
+		Me(c *fiber.Ctx) error
 		Create(c *fiber.Ctx) error
 		Update(c *fiber.Ctx) error
+		Patch(c *fiber.Ctx) error
 		Delete(c *fiber.Ctx) error
+		Restore(c *fiber.Ctx) error
+		Purge(c *fiber.Ctx) error
+		Activate(c *fiber.Ctx) error
+		Block(c *fiber.Ctx) error
+		Unblock(c *fiber.Ctx) error
+		Close(c *fiber.Ctx) error
+		StatusHistory(c *fiber.Ctx) error
 	}
 )
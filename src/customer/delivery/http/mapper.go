@@ -1,13 +1,23 @@
 package http
 
 import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/problem"
+	"github.com/rasteiro11/MCABankCustomer/pkg/validator"
 	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
 )
 
 func MapCreateRequestToDomain(req *createCustomerRequest) *domain.Customer {
 	return &domain.Customer{
-		Nome:  req.Nome,
-		Email: req.Email,
+		Nome:     req.Nome,
+		Email:    req.Email,
+		Document: req.Document,
+		UserID:   req.UserID,
 	}
 }
 
@@ -16,9 +26,14 @@ func MapCustomerToHTTP(c *domain.Customer) *customerResponse {
 		return nil
 	}
 	return &customerResponse{
-		ID:    c.ID,
-		Nome:  c.Nome,
-		Email: c.Email,
+		ID:        c.ID,
+		Nome:      c.Nome,
+		Email:     c.Email,
+		Document:  c.Document,
+		UserID:    c.UserID,
+		Status:    string(c.Status),
+		CreatedAt: c.CreatedAt,
+		DeletedAt: c.DeletedAt,
 	}
 }
 
@@ -29,3 +44,205 @@ func MapCustomersToHTTP(customers []domain.Customer) []*customerResponse {
 	}
 	return out
 }
+
+func MapCustomerPageToHTTP(p *domain.CustomerPage) *customerPageResponse {
+	return &customerPageResponse{
+		Data:       MapCustomersToHTTP(p.Items),
+		NextCursor: p.NextCursor,
+		Total:      p.Total,
+		Limit:      p.Limit,
+	}
+}
+
+func MapPurgeResultToHTTP(r *domain.PurgeResult) *purgeCustomersResponse {
+	return &purgeCustomersResponse{
+		Purged:        r.Purged,
+		DeletedBefore: r.DeletedBefore,
+	}
+}
+
+func MapStatusChangesToHTTP(changes []domain.StatusChange) []*statusChangeResponse {
+	out := make([]*statusChangeResponse, 0, len(changes))
+	for _, c := range changes {
+		out = append(out, &statusChangeResponse{
+			ID:        c.ID,
+			Action:    string(c.Action),
+			From:      string(c.From),
+			To:        string(c.To),
+			Reason:    c.Reason,
+			ChangedBy: c.ChangedBy,
+			ChangedAt: c.ChangedAt,
+		})
+	}
+	return out
+}
+
+// MapListQueryToDomain parses the GET /customers query string. sort takes a
+// field name, prefixed with "-" for descending order.
+func MapListQueryToDomain(q *listCustomersQuery) (domain.CustomerQuery, error) {
+	out := domain.CustomerQuery{
+		Limit:   q.Limit,
+		Cursor:  q.Cursor,
+		Deleted: q.Deleted,
+	}
+
+	var err error
+	if out.Nome, err = mapStringFilter(q.Nome, q.NomeMatch); err != nil {
+		return out, err
+	}
+	if out.Email, err = mapStringFilter(q.Email, q.EmailMatch); err != nil {
+		return out, err
+	}
+	if out.CreatedFrom, err = mapTime(q.CreatedFrom); err != nil {
+		return out, err
+	}
+	if out.CreatedTo, err = mapTime(q.CreatedTo); err != nil {
+		return out, err
+	}
+
+	if q.Status != "" {
+		if out.Status, err = domain.ParseStatus(q.Status); err != nil {
+			return out, err
+		}
+	}
+
+	if q.Sort != "" {
+		field := strings.TrimPrefix(q.Sort, "-")
+		out.SortDesc = field != q.Sort
+		if out.SortBy, err = domain.ParseSortField(field); err != nil {
+			return out, err
+		}
+	}
+
+	return out, nil
+}
+
+func mapStringFilter(value, mode string) (*domain.StringFilter, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if mode == "" {
+		return &domain.StringFilter{Value: value, Mode: domain.MatchExact}, nil
+	}
+
+	m, err := domain.ParseMatchMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.StringFilter{Value: value, Mode: m}, nil
+}
+
+func mapTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, ErrInvalidTime
+	}
+	return &t, nil
+}
+
+// MapBalancesToHTTP attaches balances to already mapped customers. A nil
+// map means the payment service could not be reached.
+func MapBalancesToHTTP(customers []*customerResponse, balances map[uint]domain.Balance) {
+	for _, c := range customers {
+		if balances == nil {
+			c.BalanceStatus = balanceStatusUnavailable
+			continue
+		}
+
+		b, ok := balances[c.ID]
+		if !ok {
+			c.BalanceStatus = balanceStatusMissing
+			continue
+		}
+
+		c.Balance = &b.Balance
+		c.BlockedBalance = &b.BlockedBalance
+		c.BalanceStatus = balanceStatusOK
+	}
+}
+
+func hasInclude(include, name string) bool {
+	for _, part := range strings.Split(include, ",") {
+		if strings.TrimSpace(part) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MapMergePatchToDomain applies RFC 7396 semantics to a customer: members
+// present in the document are replaced, absent ones are kept and a null
+// document is removed. The body must already be known to be a JSON object.
+// Documents come out normalized.
+func MapMergePatchToDomain(
+	doc map[string]json.RawMessage,
+	emailValidator validator.EmailValidator,
+	documentValidator validator.DocumentValidator,
+) (domain.CustomerPatch, []problem.FieldError) {
+	var (
+		patch domain.CustomerPatch
+		errs  []problem.FieldError
+	)
+
+	fields := make([]string, 0, len(doc))
+	for field := range doc {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		raw := doc[field]
+
+		switch field {
+		case "nome", "email", "document":
+		default:
+			errs = append(errs, fieldError(field, "unknown"))
+			continue
+		}
+
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			if field == "document" {
+				cleared := ""
+				patch.Document = &cleared
+				continue
+			}
+			errs = append(errs, fieldError(field, "required"))
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			errs = append(errs, fieldError(field, "string"))
+			continue
+		}
+
+		switch field {
+		case "nome":
+			if strings.TrimSpace(value) == "" {
+				errs = append(errs, fieldError(field, "required"))
+				continue
+			}
+			patch.Nome = &value
+		case "email":
+			if !emailValidator.IsValid(value) {
+				errs = append(errs, fieldError(field, "email"))
+				continue
+			}
+			patch.Email = &value
+		case "document":
+			document, err := documentValidator.Normalize(value)
+			if err != nil {
+				errs = append(errs, fieldError(field, "document"))
+				continue
+			}
+			patch.Document = &document
+		}
+	}
+
+	return patch, errs
+}
@@ -0,0 +1,7 @@
+package domain
+
+type Balance struct {
+	CustomerID     uint
+	Balance        float64
+	BlockedBalance float64
+}
@@ -1,7 +1,15 @@
 package domain
 
+import "time"
+
 type Customer struct {
-	ID    uint   `gorm:"primaryKey;autoIncrement" json:"id"`
-	Nome  string `gorm:"not null" json:"nome"`
-	Email string `gorm:"not null;unique" json:"email"`
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Nome      string     `gorm:"not null" json:"nome"`
+	Email     string     `gorm:"not null;unique" json:"email"`
+	Document  string     `json:"document,omitempty"`
+	UserID    uint64     `json:"user_id,omitempty"`
+	Status    Status     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Version   uint       `json:"version"`
 }
@@ -0,0 +1,69 @@
+package domain
+
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindInvalid
+	KindNotFound
+	KindAlreadyExists
+	KindConflict
+	KindDependencyUnavailable
+	KindPreconditionFailed
+	KindForbidden
+	KindDependencyRejected
+)
+
+// Error is a customer domain error. Code is stable and machine readable;
+// Kind tells the delivery layers which status to answer with. Two errors
+// match under errors.Is when their codes are equal.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Err     error
+}
+
+var (
+	ErrNotFound              = &Error{Kind: KindNotFound, Code: "customer_not_found", Message: "customer not found"}
+	ErrEmailAlreadyExists    = &Error{Kind: KindAlreadyExists, Code: "email_already_exists", Message: "email already exists"}
+	ErrDependencyUnavailable = &Error{Kind: KindDependencyUnavailable, Code: "dependency_unavailable", Message: "dependency unavailable"}
+	ErrPaymentRejected       = &Error{Kind: KindDependencyRejected, Code: "payment_rejected", Message: "payment service rejected the request"}
+	ErrConflict              = &Error{Kind: KindConflict, Code: "conflict", Message: "conflict"}
+	ErrBalanceNotEmpty       = &Error{Kind: KindConflict, Code: "balance_not_empty", Message: "customer balance is not empty"}
+	ErrVersionMismatch       = &Error{Kind: KindPreconditionFailed, Code: "version_mismatch", Message: "customer was modified by another request"}
+	ErrDocumentAlreadyExists = &Error{Kind: KindAlreadyExists, Code: "document_already_exists", Message: "document already exists"}
+	ErrInvalidDocument       = &Error{Kind: KindInvalid, Code: "invalid_document", Message: "invalid CPF or CNPJ"}
+	ErrAuthUserNotFound      = &Error{Kind: KindInvalid, Code: "auth_user_not_found", Message: "auth user not found"}
+	ErrAuthUserMismatch      = &Error{Kind: KindInvalid, Code: "auth_user_mismatch", Message: "email or document does not match the auth user"}
+	ErrUserAlreadyLinked     = &Error{Kind: KindAlreadyExists, Code: "user_already_linked", Message: "auth user is already linked to a customer"}
+	ErrForbidden             = &Error{Kind: KindForbidden, Code: "forbidden", Message: "forbidden"}
+	ErrNotDeleted            = &Error{Kind: KindConflict, Code: "customer_not_deleted", Message: "customer is not deleted"}
+)
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Forbidden returns ErrForbidden with the reason of the denial as message.
+func Forbidden(reason string) error {
+	return &Error{Kind: KindForbidden, Code: ErrForbidden.Code, Message: reason}
+}
+
+// Wrap returns a copy of kind carrying cause, so callers keep the original
+// error for logs while matching kind with errors.Is.
+func Wrap(kind *Error, cause error) error {
+	return &Error{Kind: kind.Kind, Code: kind.Code, Message: kind.Message, Err: cause}
+}
@@ -0,0 +1,13 @@
+package domain
+
+const (
+	CustomerAggregate = "customer"
+
+	EventCustomerCreated  = "customer.created"
+	EventCustomerUpdated  = "customer.updated"
+	EventCustomerDeleted  = "customer.deleted"
+	EventCustomerRestored = "customer.restored"
+	EventCustomerPurged   = "customer.purged"
+
+	EventCustomerStatusChanged = "customer.status_changed"
+)
@@ -0,0 +1,15 @@
+package domain
+
+// CustomerPatch is a partial update. Nil fields are left unchanged and an
+// empty Document clears it. ExpectedVersion guards against lost updates;
+// zero skips the check.
+type CustomerPatch struct {
+	Nome            *string
+	Email           *string
+	Document        *string
+	ExpectedVersion uint
+}
+
+func (p CustomerPatch) IsEmpty() bool {
+	return p.Nome == nil && p.Email == nil && p.Document == nil
+}
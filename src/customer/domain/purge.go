@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// DefaultPurgeRetention is how long a soft-deleted customer can still be
+// restored before a purge may remove it for good.
+const DefaultPurgeRetention = 30 * 24 * time.Hour
+
+type PurgeResult struct {
+	Purged        int64
+	DeletedBefore time.Time
+}
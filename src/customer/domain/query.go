@@ -0,0 +1,87 @@
+package domain
+
+import "time"
+
+type (
+	MatchMode string
+	SortField string
+)
+
+const (
+	MatchExact    MatchMode = "exact"
+	MatchPrefix   MatchMode = "prefix"
+	MatchContains MatchMode = "contains"
+
+	SortByID        SortField = "id"
+	SortByNome      SortField = "nome"
+	SortByEmail     SortField = "email"
+	SortByCreatedAt SortField = "created_at"
+
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var (
+	ErrInvalidCursor    = &Error{Kind: KindInvalid, Code: "invalid_cursor", Message: "invalid cursor"}
+	ErrInvalidSort      = &Error{Kind: KindInvalid, Code: "invalid_sort", Message: "invalid sort field"}
+	ErrInvalidMatchMode = &Error{Kind: KindInvalid, Code: "invalid_match_mode", Message: "invalid match mode"}
+)
+
+type (
+	StringFilter struct {
+		Value string
+		Mode  MatchMode
+	}
+
+	// CustomerQuery describes one page of a customer listing. Cursor is the
+	// opaque NextCursor of the previous page and is only valid for the same
+	// sort order. Deleted lists soft-deleted customers instead of live ones.
+	// An empty Status matches every status.
+	CustomerQuery struct {
+		Limit       int
+		Cursor      string
+		Nome        *StringFilter
+		Email       *StringFilter
+		CreatedFrom *time.Time
+		CreatedTo   *time.Time
+		SortBy      SortField
+		SortDesc    bool
+		Deleted     bool
+		Status      Status
+	}
+
+	CustomerPage struct {
+		Items      []Customer
+		NextCursor string
+		Total      int64
+		Limit      int
+	}
+)
+
+func (q *CustomerQuery) Normalize() {
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageLimit
+	}
+	if q.Limit > MaxPageLimit {
+		q.Limit = MaxPageLimit
+	}
+	if q.SortBy == "" {
+		q.SortBy = SortByID
+	}
+}
+
+func ParseSortField(s string) (SortField, error) {
+	switch f := SortField(s); f {
+	case SortByID, SortByNome, SortByEmail, SortByCreatedAt:
+		return f, nil
+	}
+	return "", ErrInvalidSort
+}
+
+func ParseMatchMode(s string) (MatchMode, error) {
+	switch m := MatchMode(s); m {
+	case MatchExact, MatchPrefix, MatchContains:
+		return m, nil
+	}
+	return "", ErrInvalidMatchMode
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+type (
+	SagaAction string
+	SagaStatus string
+)
+
+const (
+	SagaActionCreate SagaAction = "create"
+
+	SagaStepCreateBalance = "create_balance"
+
+	SagaStatusPending     SagaStatus = "pending"
+	SagaStatusCompleted   SagaStatus = "completed"
+	SagaStatusCompensated SagaStatus = "compensated"
+	SagaStatusFailed      SagaStatus = "failed"
+)
+
+// SagaStep records a remote step the customer service intends to perform
+// so it can be compensated, or picked up again, if a later step fails.
+type SagaStep struct {
+	ID         uint
+	CustomerID uint
+	Action     SagaAction
+	Step       string
+	Status     SagaStatus
+	Error      string
+	CreatedAt  time.Time
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+type (
+	Status       string
+	StatusAction string
+)
+
+const (
+	StatusPending Status = "pending"
+	StatusActive  Status = "active"
+	StatusBlocked Status = "blocked"
+	StatusClosed  Status = "closed"
+
+	StatusActionActivate StatusAction = "activate"
+	StatusActionBlock    StatusAction = "block"
+	StatusActionUnblock  StatusAction = "unblock"
+	StatusActionClose    StatusAction = "close"
+)
+
+var (
+	ErrIllegalTransition = &Error{Kind: KindConflict, Code: "illegal_status_transition", Message: "status transition not allowed"}
+	ErrReasonRequired    = &Error{Kind: KindInvalid, Code: "reason_required", Message: "a reason is required for this transition"}
+	ErrInvalidStatus     = &Error{Kind: KindInvalid, Code: "invalid_status", Message: "invalid status"}
+)
+
+// transitions is the customer state machine: for each action, the states it
+// may be applied in and the state it leads to. closed is terminal.
+var transitions = map[StatusAction]struct {
+	from []Status
+	to   Status
+}{
+	StatusActionActivate: {from: []Status{StatusPending}, to: StatusActive},
+	StatusActionBlock:    {from: []Status{StatusPending, StatusActive}, to: StatusBlocked},
+	StatusActionUnblock:  {from: []Status{StatusBlocked}, to: StatusActive},
+	StatusActionClose:    {from: []Status{StatusPending, StatusActive, StatusBlocked}, to: StatusClosed},
+}
+
+// StatusChange is an audit record of one applied transition.
+type StatusChange struct {
+	ID         uint         `json:"id"`
+	CustomerID uint         `json:"customer_id"`
+	Action     StatusAction `json:"action"`
+	From       Status       `json:"from"`
+	To         Status       `json:"to"`
+	Reason     string       `json:"reason,omitempty"`
+	ChangedBy  uint64       `json:"changed_by"`
+	ChangedAt  time.Time    `json:"changed_at"`
+}
+
+// Transition returns the state action leads to from s, or
+// ErrIllegalTransition when the state machine does not allow it.
+func (s Status) Transition(action StatusAction) (Status, error) {
+	t, ok := transitions[action]
+	if !ok {
+		return "", ErrIllegalTransition
+	}
+
+	for _, from := range t.from {
+		if from == s {
+			return t.to, nil
+		}
+	}
+	return "", ErrIllegalTransition
+}
+
+// RequiresReason tells whether action must be justified by the caller.
+func (a StatusAction) RequiresReason() bool {
+	return a == StatusActionBlock
+}
+
+// NewStatusChange validates action against the current status and returns
+// the change to record.
+func NewStatusChange(c *Customer, action StatusAction, reason string, changedBy uint64) (*StatusChange, error) {
+	reason = strings.TrimSpace(reason)
+	if action.RequiresReason() && reason == "" {
+		return nil, ErrReasonRequired
+	}
+
+	to, err := c.Status.Transition(action)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatusChange{
+		CustomerID: c.ID,
+		Action:     action,
+		From:       c.Status,
+		To:         to,
+		Reason:     reason,
+		ChangedBy:  changedBy,
+	}, nil
+}
+
+func ParseStatus(s string) (Status, error) {
+	switch st := Status(s); st {
+	case StatusPending, StatusActive, StatusBlocked, StatusClosed:
+		return st, nil
+	}
+	return "", ErrInvalidStatus
+}
@@ -0,0 +1,87 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rasteiro11/PogCore/pkg/logger"
+)
+
+const defaultInterval = time.Hour
+
+type (
+	JobOpt func(*Job)
+
+	// Leadership tells whether this replica is the one that should run
+	// singleton work; leader.Elector implements it.
+	Leadership interface {
+		IsLeader() bool
+	}
+
+	// Job runs the reconciler on a schedule, only on the replica holding
+	// leadership. A run that outlives its leadership is not interrupted:
+	// repairs check for the balance before creating it, so an overlapping
+	// run on the new leader is harmless.
+	Job struct {
+		reconciler *Reconciler
+		leadership Leadership
+		interval   time.Duration
+	}
+)
+
+func WithInterval(d time.Duration) JobOpt {
+	return func(j *Job) {
+		if d > 0 {
+			j.interval = d
+		}
+	}
+}
+
+func NewJob(reconciler *Reconciler, leadership Leadership, opts ...JobOpt) *Job {
+	j := &Job{
+		reconciler: reconciler,
+		leadership: leadership,
+		interval:   defaultInterval,
+	}
+
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	return j
+}
+
+// Run reconciles every interval until ctx is cancelled. The first run
+// waits a full interval, so rolling deploys do not trigger one each.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !j.leadership.IsLeader() {
+			continue
+		}
+
+		report, err := j.reconciler.Reconcile(ctx)
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+
+		body, _ := json.Marshal(report)
+		switch {
+		case err != nil:
+			logger.Of(ctx).Errorf("[reconciler.Job] Reconcile() returned error: %+v, report: %s\n", err, body)
+		case !report.Clean():
+			logger.Of(ctx).Warnf("[reconciler.Job] drift found: %s", body)
+		default:
+			logger.Of(ctx).Infof("[reconciler.Job] no drift: %s", body)
+		}
+	}
+}
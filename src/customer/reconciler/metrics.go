@@ -0,0 +1,57 @@
+package reconciler
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Drift found by the last completed run, exported as gauges so alerts can
+// fire on the current state rather than on a rate.
+var (
+	lastMissing  atomic.Int64
+	lastOrphaned atomic.Int64
+)
+
+var (
+	meter = otel.Meter("customer-reconciler")
+
+	runs, _ = meter.Int64Counter("reconciler.runs",
+		metric.WithDescription("Reconciliation runs by outcome"))
+	runDuration, _ = meter.Float64Histogram("reconciler.run.duration",
+		metric.WithDescription("Duration of reconciliation runs"),
+		metric.WithUnit("s"))
+	repaired, _ = meter.Int64Counter("reconciler.balances.repaired",
+		metric.WithDescription("Missing balances created by the reconciler"))
+	_, _ = meter.Int64ObservableGauge("reconciler.balances.missing",
+		metric.WithDescription("Live customers without a balance at the last run"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(lastMissing.Load())
+			return nil
+		}))
+	_, _ = meter.Int64ObservableGauge("reconciler.balances.orphaned",
+		metric.WithDescription("Balances without a live customer at the last run"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(lastOrphaned.Load())
+			return nil
+		}))
+)
+
+func record(ctx context.Context, report *Report) {
+	outcome := "success"
+	if report.Error != "" {
+		outcome = "error"
+	}
+
+	runs.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome)))
+	runDuration.Record(ctx, report.FinishedAt.Sub(report.StartedAt).Seconds())
+	repaired.Add(ctx, int64(len(report.Repaired)))
+
+	if report.Error == "" {
+		lastMissing.Store(int64(len(report.Missing) - len(report.Repaired)))
+		lastOrphaned.Store(int64(len(report.Orphaned)))
+	}
+}
@@ -0,0 +1,268 @@
+// Package reconciler finds drift between customers and their payment
+// balances: live customers without a balance and balances without a live
+// customer. It can repair the former; the payment service offers no way to
+// remove a balance, so orphans are only reported.
+package reconciler
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/service"
+	"github.com/rasteiro11/PogCore/pkg/logger"
+)
+
+const defaultGracePeriod = 10 * time.Minute
+
+type (
+	Opt func(*Reconciler)
+
+	// Reconciler compares customers with the payment service in batches of
+	// one customer page per GetBalances call.
+	//
+	// The payment service cannot list balances, so orphans are searched
+	// among the IDs the customer table knows of: soft-deleted customers
+	// and the gaps below the highest ID. A balance above every customer ID
+	// goes unnoticed.
+	Reconciler struct {
+		customers   service.CustomerService
+		batchSize   int
+		repair      bool
+		gracePeriod time.Duration
+		now         func() time.Time
+	}
+)
+
+// WithBatchSize sets how many customers are checked per GetBalances call,
+// at most domain.MaxPageLimit.
+func WithBatchSize(n int) Opt {
+	return func(r *Reconciler) {
+		if n > 0 && n <= domain.MaxPageLimit {
+			r.batchSize = n
+		}
+	}
+}
+
+// WithRepair creates the missing balances found by a run.
+func WithRepair(repair bool) Opt {
+	return func(r *Reconciler) {
+		r.repair = repair
+	}
+}
+
+// WithGracePeriod skips customers created less than d ago, whose create
+// saga may still be waiting for the payment service. Repairing those would
+// race the saga into a duplicate balance.
+func WithGracePeriod(d time.Duration) Opt {
+	return func(r *Reconciler) {
+		if d > 0 {
+			r.gracePeriod = d
+		}
+	}
+}
+
+func NewReconciler(customers service.CustomerService, opts ...Opt) *Reconciler {
+	r := &Reconciler{
+		customers:   customers,
+		batchSize:   domain.MaxPageLimit,
+		gracePeriod: defaultGracePeriod,
+		now:         time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Reconcile runs one full pass. On error the report covers what was
+// checked before it and carries the error too.
+func (r *Reconciler) Reconcile(ctx context.Context) (*Report, error) {
+	report := &Report{
+		StartedAt: r.now(),
+		Repair:    r.repair,
+		Missing:   []uint{},
+		Orphaned:  []OrphanedBalance{},
+	}
+
+	err := r.reconcile(ctx, report)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	report.FinishedAt = r.now()
+
+	record(ctx, report)
+	return report, err
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, report *Report) error {
+	known := make(map[uint]struct{})
+	var maxID uint
+
+	see := func(id uint) {
+		known[id] = struct{}{}
+		if id > maxID {
+			maxID = id
+		}
+	}
+
+	cutoff := report.StartedAt.Add(-r.gracePeriod)
+	if err := r.eachPage(ctx, false, func(customers []domain.Customer) error {
+		ids := make([]uint, 0, len(customers))
+		for _, c := range customers {
+			see(c.ID)
+			if c.CreatedAt.After(cutoff) {
+				report.SkippedRecent++
+				continue
+			}
+			ids = append(ids, c.ID)
+		}
+
+		balances, err := r.customers.GetBalances(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		report.CustomersChecked += len(ids)
+		for _, id := range ids {
+			if _, ok := balances[id]; !ok {
+				report.Missing = append(report.Missing, id)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := r.eachPage(ctx, true, func(customers []domain.Customer) error {
+		ids := make([]uint, 0, len(customers))
+		for _, c := range customers {
+			see(c.ID)
+			ids = append(ids, c.ID)
+		}
+
+		balances, err := r.customers.GetBalances(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		report.DeletedChecked += len(ids)
+		for _, id := range ids {
+			// an empty balance is what deleting a customer leaves behind
+			if b, ok := balances[id]; ok && holdsFunds(b) {
+				report.Orphaned = append(report.Orphaned, orphan(b, OrphanCustomerDeleted))
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := r.probeUnknown(ctx, known, maxID, report); err != nil {
+		return err
+	}
+
+	if r.repair {
+		r.repairMissing(ctx, report)
+	}
+	return nil
+}
+
+// probeUnknown asks for the balances of every ID up to maxID that has no
+// customer row. Like for deleted customers, only balances holding funds
+// are orphans; empty ones are left by compensated creates.
+func (r *Reconciler) probeUnknown(ctx context.Context, known map[uint]struct{}, maxID uint, report *Report) error {
+	batch := make([]uint, 0, r.batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		balances, err := r.customers.GetBalances(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		report.UnknownIDsProbed += len(batch)
+		for _, id := range batch {
+			if b, ok := balances[id]; ok && holdsFunds(b) {
+				report.Orphaned = append(report.Orphaned, orphan(b, OrphanCustomerUnknown))
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for id := uint(1); id <= maxID; id++ {
+		if _, ok := known[id]; ok {
+			continue
+		}
+		if batch = append(batch, id); len(batch) == r.batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// repairMissing creates the missing balances one at a time. A failure is
+// recorded and the rest are still attempted.
+func (r *Reconciler) repairMissing(ctx context.Context, report *Report) {
+	for _, id := range report.Missing {
+		if ctx.Err() != nil {
+			return
+		}
+
+		created, err := r.customers.ResyncBalance(ctx, id)
+		if err != nil {
+			logger.Of(ctx).Errorf("[reconciler.repairMissing] customers.ResyncBalance() returned error for customer %d: %+v\n", id, err)
+			report.RepairFailures = append(report.RepairFailures, RepairFailure{CustomerID: id, Error: err.Error()})
+			continue
+		}
+		if created {
+			report.Repaired = append(report.Repaired, id)
+		}
+	}
+}
+
+// eachPage walks live or deleted customers in ID order, one batch per page.
+func (r *Reconciler) eachPage(ctx context.Context, deleted bool, fn func(customers []domain.Customer) error) error {
+	q := domain.CustomerQuery{Limit: r.batchSize, SortBy: domain.SortByID, Deleted: deleted}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		page, err := r.customers.GetAll(ctx, q)
+		if err != nil {
+			return err
+		}
+		if len(page.Items) > 0 {
+			if err := fn(page.Items); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func holdsFunds(b domain.Balance) bool {
+	return b.Balance != 0 || b.BlockedBalance != 0
+}
+
+func orphan(b domain.Balance, reason OrphanReason) OrphanedBalance {
+	return OrphanedBalance{
+		CustomerID:     b.CustomerID,
+		Reason:         reason,
+		Balance:        b.Balance,
+		BlockedBalance: b.BlockedBalance,
+	}
+}
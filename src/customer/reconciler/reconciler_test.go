@@ -0,0 +1,74 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/service"
+)
+
+// fakeCustomers serves one page of live and one of deleted customers and
+// the balances held by the payment service.
+type fakeCustomers struct {
+	service.CustomerService
+
+	live     []domain.Customer
+	deleted  []domain.Customer
+	balances map[uint]domain.Balance
+}
+
+func (f *fakeCustomers) GetAll(_ context.Context, q domain.CustomerQuery) (*domain.CustomerPage, error) {
+	if q.Deleted {
+		return &domain.CustomerPage{Items: f.deleted}, nil
+	}
+	return &domain.CustomerPage{Items: f.live}, nil
+}
+
+func (f *fakeCustomers) GetBalances(_ context.Context, ids []uint) (map[uint]domain.Balance, error) {
+	found := make(map[uint]domain.Balance)
+	for _, id := range ids {
+		if b, ok := f.balances[id]; ok {
+			found[id] = b
+		}
+	}
+	return found, nil
+}
+
+func TestReconcileReportsOnlyFundedOrphans(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	customers := &fakeCustomers{
+		live:    []domain.Customer{{ID: 1, CreatedAt: old}, {ID: 6, CreatedAt: old}},
+		deleted: []domain.Customer{{ID: 2}, {ID: 3}},
+		balances: map[uint]domain.Balance{
+			1: {CustomerID: 1},
+			2: {CustomerID: 2},
+			3: {CustomerID: 3, Balance: 10},
+			4: {CustomerID: 4},
+			5: {CustomerID: 5, BlockedBalance: 2.5},
+		},
+	}
+
+	report, err := NewReconciler(customers).Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile() returned error: %v", err)
+	}
+
+	if len(report.Missing) != 1 || report.Missing[0] != 6 {
+		t.Errorf("Missing = %v, want [6]", report.Missing)
+	}
+
+	want := map[uint]OrphanReason{3: OrphanCustomerDeleted, 5: OrphanCustomerUnknown}
+	if len(report.Orphaned) != len(want) {
+		t.Fatalf("Orphaned = %+v, want customers 3 and 5", report.Orphaned)
+	}
+	for _, o := range report.Orphaned {
+		if reason, ok := want[o.CustomerID]; !ok || reason != o.Reason {
+			t.Errorf("unexpected orphan %+v", o)
+		}
+	}
+	if report.UnknownIDsProbed != 2 {
+		t.Errorf("UnknownIDsProbed = %d, want 2", report.UnknownIDsProbed)
+	}
+}
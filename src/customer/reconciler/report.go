@@ -0,0 +1,55 @@
+package reconciler
+
+import "time"
+
+type OrphanReason string
+
+const (
+	// OrphanCustomerDeleted is a soft-deleted customer whose balance still
+	// holds funds, which deletion is supposed to rule out.
+	OrphanCustomerDeleted OrphanReason = "customer_deleted"
+	// OrphanCustomerUnknown is a balance for an ID with no customer row at
+	// all: a purged customer or a create that was compensated after the
+	// payment service had already created the balance.
+	OrphanCustomerUnknown OrphanReason = "customer_unknown"
+)
+
+type (
+	OrphanedBalance struct {
+		CustomerID     uint         `json:"customer_id"`
+		Reason         OrphanReason `json:"reason"`
+		Balance        float64      `json:"balance"`
+		BlockedBalance float64      `json:"blocked_balance"`
+	}
+
+	RepairFailure struct {
+		CustomerID uint   `json:"customer_id"`
+		Error      string `json:"error"`
+	}
+
+	// Report is the outcome of one reconciliation. Missing lists live
+	// customers without a balance; with repair on, each of them ends up in
+	// Repaired or RepairFailures, or in neither when the balance appeared
+	// in the meantime.
+	Report struct {
+		StartedAt        time.Time         `json:"started_at"`
+		FinishedAt       time.Time         `json:"finished_at"`
+		Repair           bool              `json:"repair"`
+		CustomersChecked int               `json:"customers_checked"`
+		SkippedRecent    int               `json:"skipped_recent"`
+		DeletedChecked   int               `json:"deleted_checked"`
+		UnknownIDsProbed int               `json:"unknown_ids_probed"`
+		Missing          []uint            `json:"missing"`
+		Orphaned         []OrphanedBalance `json:"orphaned"`
+		Repaired         []uint            `json:"repaired,omitempty"`
+		RepairFailures   []RepairFailure   `json:"repair_failures,omitempty"`
+		Error            string            `json:"error,omitempty"`
+	}
+)
+
+// Clean reports whether the run completed and found no drift left
+// unrepaired.
+func (r *Report) Clean() bool {
+	return r.Error == "" && len(r.Orphaned) == 0 && len(r.RepairFailures) == 0 &&
+		(len(r.Missing) == 0 || r.Repair)
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/rasteiro11/PogCore/pkg/database"
+	"gorm.io/gorm"
+)
+
+// conn returns the transaction carried by ctx, if any, so repositories
+// called from inside Transaction share the same unit of work.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, err := database.FromContext(ctx); err == nil {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	sqlite "github.com/glebarez/go-sqlite"
+	"github.com/go-sql-driver/mysql"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"gorm.io/gorm"
+)
+
+const (
+	mysqlDuplicateEntry = 1062
+
+	// sqliteConstraintUnique is SQLITE_CONSTRAINT_UNIQUE.
+	sqliteConstraintUnique = 2067
+
+	// documentIndex and userIndex tell duplicates apart from email ones in
+	// MySQL duplicate entry messages.
+	documentIndex = "idx_customers_document_live"
+	userIndex     = "idx_customers_user_live"
+
+	// SQLite names the columns of the violated index instead.
+	documentColumn = "customers.document"
+	userColumn     = "customers.user_id"
+)
+
+// translateError maps gorm and driver errors to domain errors. Errors it
+// does not recognise are returned unchanged.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var (
+		domainErr *domain.Error
+		mysqlErr  *mysql.MySQLError
+		sqliteErr *sqlite.Error
+		netErr    net.Error
+	)
+
+	switch {
+	case errors.As(err, &domainErr):
+		return err
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return domain.Wrap(domain.ErrNotFound, err)
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry:
+		if strings.Contains(mysqlErr.Message, documentIndex) {
+			return domain.Wrap(domain.ErrDocumentAlreadyExists, err)
+		}
+		if strings.Contains(mysqlErr.Message, userIndex) {
+			return domain.Wrap(domain.ErrUserAlreadyLinked, err)
+		}
+		return domain.Wrap(domain.ErrEmailAlreadyExists, err)
+	case errors.As(err, &sqliteErr) && sqliteErr.Code() == sqliteConstraintUnique:
+		if strings.Contains(sqliteErr.Error(), documentColumn) {
+			return domain.Wrap(domain.ErrDocumentAlreadyExists, err)
+		}
+		if strings.Contains(sqliteErr.Error(), userColumn) {
+			return domain.Wrap(domain.ErrUserAlreadyLinked, err)
+		}
+		return domain.Wrap(domain.ErrEmailAlreadyExists, err)
+	case errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, mysql.ErrInvalidConn),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr):
+		return domain.Wrap(domain.ErrDependencyUnavailable, err)
+	}
+
+	return err
+}
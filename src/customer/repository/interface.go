@@ -2,17 +2,33 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
 )
 
 type (
 	CustomerRepository interface {
-		FindAll(ctx context.Context) ([]domain.Customer, error)
+		FindAll(ctx context.Context, q domain.CustomerQuery) (*domain.CustomerPage, error)
 		FindByID(ctx context.Context, id uint) (*domain.Customer, error)
+		FindByDocument(ctx context.Context, document string) (*domain.Customer, error)
+		FindByUserID(ctx context.Context, userID uint64) (*domain.Customer, error)
+		LinkUser(ctx context.Context, id uint, userID uint64) (*domain.Customer, error)
 		Create(ctx context.Context, customer *domain.Customer) (*domain.Customer, error)
-		CreateWithCallback(ctx context.Context, customer *domain.Customer, fn func(*domain.Customer) error) (*domain.Customer, error)
 		Update(ctx context.Context, customer *domain.Customer) (*domain.Customer, error)
-		Delete(ctx context.Context, id uint) error
+		Patch(ctx context.Context, id uint, patch domain.CustomerPatch) (*domain.Customer, error)
+		Delete(ctx context.Context, id uint, expectedVersion uint) error
+		HardDelete(ctx context.Context, id uint) error
+		Restore(ctx context.Context, id uint) (*domain.Customer, error)
+		Purge(ctx context.Context, before time.Time, limit int) (int64, error)
+		ChangeStatus(ctx context.Context, change *domain.StatusChange) (*domain.Customer, error)
+		StatusHistory(ctx context.Context, id uint) ([]domain.StatusChange, error)
+		Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+	}
+
+	SagaRepository interface {
+		Begin(ctx context.Context, step *domain.SagaStep) (*domain.SagaStep, error)
+		SetStatus(ctx context.Context, id uint, status domain.SagaStatus, cause error) error
+		ClaimPending(ctx context.Context, createdBefore time.Time, limit int, lease time.Duration) ([]domain.SagaStep, error)
 	}
 )
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/outbox"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+)
+
+type memoryTxKey struct{}
+
+// memoryState is everything a MemoryDB transaction can roll back.
+type memoryState struct {
+	customers    map[uint]*domain.Customer
+	nextID       uint
+	changes      []domain.StatusChange
+	nextChangeID uint
+	sagaSteps    map[uint]*domain.SagaStep
+	sagaClaims   map[uint]time.Time
+	nextSagaID   uint
+	events       []outbox.Event
+	nextEventID  uint
+}
+
+// MemoryDB is the in-process storage shared by the memory repositories.
+// Transactions are serialized and roll back by restoring a snapshot, nested
+// ones behave like gorm savepoints. It suits tests and local runs, not
+// production.
+type MemoryDB struct {
+	mu    sync.Mutex
+	state *memoryState
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{state: &memoryState{
+		customers:  make(map[uint]*domain.Customer),
+		sagaSteps:  make(map[uint]*domain.SagaStep),
+		sagaClaims: make(map[uint]time.Time),
+	}}
+}
+
+// Events returns the outbox events committed so far, oldest first.
+func (db *MemoryDB) Events() []outbox.Event {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]outbox.Event(nil), db.state.events...)
+}
+
+// Transaction runs fn with exclusive access to the state. Every change fn
+// makes is undone if it returns an error.
+func (db *MemoryDB) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryTxKey{}) != db {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		ctx = context.WithValue(ctx, memoryTxKey{}, db)
+	}
+
+	snapshot := db.state.clone()
+	if err := fn(ctx); err != nil {
+		db.state = snapshot
+		return err
+	}
+	return nil
+}
+
+// view runs fn against the current state without a snapshot, for reads.
+func (db *MemoryDB) view(ctx context.Context, fn func(s *memoryState) error) error {
+	if ctx.Value(memoryTxKey{}) != db {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+	}
+	return fn(db.state)
+}
+
+func (s *memoryState) clone() *memoryState {
+	c := *s
+
+	c.customers = make(map[uint]*domain.Customer, len(s.customers))
+	for id, customer := range s.customers {
+		c.customers[id] = copyCustomer(customer)
+	}
+
+	c.sagaSteps = make(map[uint]*domain.SagaStep, len(s.sagaSteps))
+	for id, step := range s.sagaSteps {
+		copied := *step
+		c.sagaSteps[id] = &copied
+	}
+
+	c.sagaClaims = make(map[uint]time.Time, len(s.sagaClaims))
+	for id, until := range s.sagaClaims {
+		c.sagaClaims[id] = until
+	}
+
+	c.changes = append([]domain.StatusChange(nil), s.changes...)
+	c.events = append([]outbox.Event(nil), s.events...)
+	return &c
+}
+
+func copyCustomer(c *domain.Customer) *domain.Customer {
+	copied := *c
+	if c.DeletedAt != nil {
+		deletedAt := *c.DeletedAt
+		copied.DeletedAt = &deletedAt
+	}
+	return &copied
+}
@@ -0,0 +1,514 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/outbox"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+)
+
+// memoryCustomerRepository mirrors customerRepository over a MemoryDB,
+// including live-only unique email, document and user, soft delete, and
+// outbox events committed with the mutation. String filters and sorting are
+// case-insensitive like the MySQL default collation.
+type memoryCustomerRepository struct {
+	db *MemoryDB
+}
+
+var _ CustomerRepository = (*memoryCustomerRepository)(nil)
+
+func NewMemoryCustomerRepository(db *MemoryDB) CustomerRepository {
+	return &memoryCustomerRepository{db: db}
+}
+
+func (r *memoryCustomerRepository) FindAll(ctx context.Context, q domain.CustomerQuery) (*domain.CustomerPage, error) {
+	q.Normalize()
+
+	var after *domain.Customer
+	if q.Cursor != "" {
+		c, err := decodeCursor(q)
+		if err != nil {
+			return nil, err
+		}
+		if after, err = cursorCustomer(q.SortBy, c); err != nil {
+			return nil, err
+		}
+	}
+
+	page := &domain.CustomerPage{Limit: q.Limit}
+
+	_ = r.db.view(ctx, func(s *memoryState) error {
+		var matched []*domain.Customer
+		for _, c := range s.customers {
+			if matchesQuery(c, q) {
+				matched = append(matched, c)
+			}
+		}
+		page.Total = int64(len(matched))
+
+		sort.Slice(matched, func(i, j int) bool {
+			return compareCustomers(matched[i], matched[j], q) < 0
+		})
+
+		page.Items = make([]domain.Customer, 0, q.Limit)
+		for _, c := range matched {
+			if after != nil && compareCustomers(c, after, q) <= 0 {
+				continue
+			}
+			if len(page.Items) == q.Limit {
+				last := page.Items[len(page.Items)-1]
+				page.NextCursor = encodeCursor(q, &last)
+				break
+			}
+			page.Items = append(page.Items, *copyCustomer(c))
+		}
+		return nil
+	})
+
+	return page, nil
+}
+
+func (r *memoryCustomerRepository) FindByID(ctx context.Context, id uint) (*domain.Customer, error) {
+	return r.findLive(ctx, func(c *domain.Customer) bool { return c.ID == id })
+}
+
+func (r *memoryCustomerRepository) FindByDocument(ctx context.Context, document string) (*domain.Customer, error) {
+	return r.findLive(ctx, func(c *domain.Customer) bool { return c.Document != "" && c.Document == document })
+}
+
+func (r *memoryCustomerRepository) FindByUserID(ctx context.Context, userID uint64) (*domain.Customer, error) {
+	return r.findLive(ctx, func(c *domain.Customer) bool { return c.UserID != 0 && c.UserID == userID })
+}
+
+func (r *memoryCustomerRepository) LinkUser(ctx context.Context, id uint, userID uint64) (*domain.Customer, error) {
+	var linked *domain.Customer
+
+	if err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		c, ok := r.live(id)
+		if !ok {
+			return domain.ErrNotFound
+		}
+		if c.UserID != 0 {
+			return domain.ErrUserAlreadyLinked
+		}
+
+		c.UserID = userID
+		if err := r.checkUnique(c); err != nil {
+			return err
+		}
+		c.Version++
+
+		linked = copyCustomer(c)
+		return r.emit(domain.EventCustomerUpdated, linked.ID, linked)
+	}); err != nil {
+		return nil, err
+	}
+
+	return linked, nil
+}
+
+func (r *memoryCustomerRepository) Create(ctx context.Context, customer *domain.Customer) (*domain.Customer, error) {
+	var created *domain.Customer
+
+	if err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		s := r.db.state
+
+		c := copyCustomer(customer)
+		c.ID = s.nextID + 1
+		c.Version = 1
+		c.CreatedAt = time.Now()
+		c.DeletedAt = nil
+		if c.Status == "" {
+			c.Status = domain.StatusActive
+		}
+
+		if err := r.checkUnique(c); err != nil {
+			return err
+		}
+
+		s.nextID = c.ID
+		s.customers[c.ID] = c
+
+		created = copyCustomer(c)
+		return r.emit(domain.EventCustomerCreated, created.ID, created)
+	}); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *memoryCustomerRepository) Update(ctx context.Context, customer *domain.Customer) (*domain.Customer, error) {
+	var updated *domain.Customer
+
+	if err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		c, err := r.updateVersioned(customer.ID, customer.Version, func(c *domain.Customer) {
+			c.Nome = customer.Nome
+			c.Email = customer.Email
+			if customer.Document != "" {
+				c.Document = customer.Document
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		updated = copyCustomer(c)
+		return r.emit(domain.EventCustomerUpdated, updated.ID, updated)
+	}); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (r *memoryCustomerRepository) Patch(ctx context.Context, id uint, patch domain.CustomerPatch) (*domain.Customer, error) {
+	var patched *domain.Customer
+
+	if err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		if patch.IsEmpty() {
+			c, ok := r.live(id)
+			if !ok {
+				return domain.ErrNotFound
+			}
+			if patch.ExpectedVersion != 0 && patch.ExpectedVersion != c.Version {
+				return domain.ErrVersionMismatch
+			}
+			patched = copyCustomer(c)
+			return nil
+		}
+
+		c, err := r.updateVersioned(id, patch.ExpectedVersion, func(c *domain.Customer) {
+			if patch.Nome != nil {
+				c.Nome = *patch.Nome
+			}
+			if patch.Email != nil {
+				c.Email = *patch.Email
+			}
+			if patch.Document != nil {
+				c.Document = *patch.Document
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		patched = copyCustomer(c)
+		return r.emit(domain.EventCustomerUpdated, patched.ID, patched)
+	}); err != nil {
+		return nil, err
+	}
+
+	return patched, nil
+}
+
+func (r *memoryCustomerRepository) Delete(ctx context.Context, id uint, expectedVersion uint) error {
+	return r.db.Transaction(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		if _, err := r.updateVersioned(id, expectedVersion, func(c *domain.Customer) {
+			c.DeletedAt = &now
+		}); err != nil {
+			return err
+		}
+		return r.emit(domain.EventCustomerDeleted, id, map[string]any{"id": id})
+	})
+}
+
+func (r *memoryCustomerRepository) HardDelete(ctx context.Context, id uint) error {
+	return r.db.Transaction(ctx, func(ctx context.Context) error {
+		s := r.db.state
+		if _, ok := s.customers[id]; !ok {
+			return domain.ErrNotFound
+		}
+		delete(s.customers, id)
+		return r.emit(domain.EventCustomerDeleted, id, map[string]any{"id": id, "hard": true})
+	})
+}
+
+func (r *memoryCustomerRepository) Restore(ctx context.Context, id uint) (*domain.Customer, error) {
+	var restored *domain.Customer
+
+	if err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		c, ok := r.db.state.customers[id]
+		if !ok {
+			return domain.ErrNotFound
+		}
+		if c.DeletedAt == nil {
+			return domain.ErrNotDeleted
+		}
+
+		if err := r.checkUnique(c); err != nil {
+			return err
+		}
+		c.DeletedAt = nil
+		c.Version++
+
+		restored = copyCustomer(c)
+		return r.emit(domain.EventCustomerRestored, restored.ID, restored)
+	}); err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+func (r *memoryCustomerRepository) Purge(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var purged int64
+
+	if err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		s := r.db.state
+
+		var ids []uint
+		for id, c := range s.customers {
+			if c.DeletedAt != nil && c.DeletedAt.Before(before) {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		if len(ids) > limit {
+			ids = ids[:limit]
+		}
+
+		for _, id := range ids {
+			delete(s.customers, id)
+			if err := r.emit(domain.EventCustomerPurged, id, map[string]any{"id": id}); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(ids))
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+func (r *memoryCustomerRepository) ChangeStatus(ctx context.Context, change *domain.StatusChange) (*domain.Customer, error) {
+	var changed *domain.Customer
+
+	if err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		s := r.db.state
+
+		c, ok := r.live(change.CustomerID)
+		if !ok {
+			return domain.ErrNotFound
+		}
+		if c.Status != change.From {
+			return domain.ErrConflict
+		}
+
+		c.Status = change.To
+		c.Version++
+
+		s.nextChangeID++
+		change.ID = s.nextChangeID
+		change.ChangedAt = time.Now()
+		s.changes = append(s.changes, *change)
+
+		changed = copyCustomer(c)
+		return r.emit(domain.EventCustomerStatusChanged, changed.ID, change)
+	}); err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+func (r *memoryCustomerRepository) StatusHistory(ctx context.Context, id uint) ([]domain.StatusChange, error) {
+	if _, err := r.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	changes := []domain.StatusChange{}
+	_ = r.db.view(ctx, func(s *memoryState) error {
+		for _, change := range s.changes {
+			if change.CustomerID == id {
+				changes = append(changes, change)
+			}
+		}
+		return nil
+	})
+	return changes, nil
+}
+
+func (r *memoryCustomerRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.db.Transaction(ctx, fn)
+}
+
+func (r *memoryCustomerRepository) findLive(ctx context.Context, match func(*domain.Customer) bool) (*domain.Customer, error) {
+	var found *domain.Customer
+	_ = r.db.view(ctx, func(s *memoryState) error {
+		for _, c := range s.customers {
+			if c.DeletedAt == nil && match(c) {
+				found = copyCustomer(c)
+				return nil
+			}
+		}
+		return nil
+	})
+
+	if found == nil {
+		return nil, domain.ErrNotFound
+	}
+	return found, nil
+}
+
+// live returns the stored customer itself; callers must hold the
+// transaction.
+func (r *memoryCustomerRepository) live(id uint) (*domain.Customer, bool) {
+	c, ok := r.db.state.customers[id]
+	if !ok || c.DeletedAt != nil {
+		return nil, false
+	}
+	return c, true
+}
+
+// updateVersioned applies update to a live customer and bumps the version.
+// A non-zero expectedVersion turns it into a compare-and-swap.
+func (r *memoryCustomerRepository) updateVersioned(
+	id, expectedVersion uint,
+	update func(c *domain.Customer),
+) (*domain.Customer, error) {
+	c, ok := r.live(id)
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	if expectedVersion != 0 && expectedVersion != c.Version {
+		return nil, domain.ErrVersionMismatch
+	}
+
+	update(c)
+	if c.DeletedAt == nil {
+		if err := r.checkUnique(c); err != nil {
+			return nil, err
+		}
+	}
+	c.Version++
+	return c, nil
+}
+
+// checkUnique enforces the live-only unique indexes on email, document and
+// user against every other live customer.
+func (r *memoryCustomerRepository) checkUnique(c *domain.Customer) error {
+	for id, other := range r.db.state.customers {
+		if id == c.ID || other.DeletedAt != nil {
+			continue
+		}
+		switch {
+		case strings.EqualFold(other.Email, c.Email):
+			return domain.ErrEmailAlreadyExists
+		case c.Document != "" && other.Document == c.Document:
+			return domain.ErrDocumentAlreadyExists
+		case c.UserID != 0 && other.UserID == c.UserID:
+			return domain.ErrUserAlreadyLinked
+		}
+	}
+	return nil
+}
+
+func (r *memoryCustomerRepository) emit(eventType string, id uint, payload any) error {
+	event, err := outbox.NewEvent(domain.CustomerAggregate, id, eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	s := r.db.state
+	s.nextEventID++
+	event.ID = s.nextEventID
+	event.Status = outbox.StatusPending
+	s.events = append(s.events, *event)
+	return nil
+}
+
+func matchesQuery(c *domain.Customer, q domain.CustomerQuery) bool {
+	if (c.DeletedAt != nil) != q.Deleted {
+		return false
+	}
+	if q.Status != "" && c.Status != q.Status {
+		return false
+	}
+	if !matchesString(c.Nome, q.Nome) || !matchesString(c.Email, q.Email) {
+		return false
+	}
+	if q.CreatedFrom != nil && c.CreatedAt.Before(*q.CreatedFrom) {
+		return false
+	}
+	if q.CreatedTo != nil && !c.CreatedAt.Before(*q.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+func matchesString(value string, f *domain.StringFilter) bool {
+	if f == nil || f.Value == "" {
+		return true
+	}
+
+	value, want := strings.ToLower(value), strings.ToLower(f.Value)
+	switch f.Mode {
+	case domain.MatchPrefix:
+		return strings.HasPrefix(value, want)
+	case domain.MatchContains:
+		return strings.Contains(value, want)
+	default:
+		return value == want
+	}
+}
+
+// compareCustomers orders a before b the way applyOrder does: by the sort
+// field, then by id, both in the query direction.
+func compareCustomers(a, b *domain.Customer, q domain.CustomerQuery) int {
+	cmp := 0
+	switch q.SortBy {
+	case domain.SortByNome:
+		cmp = strings.Compare(strings.ToLower(a.Nome), strings.ToLower(b.Nome))
+	case domain.SortByEmail:
+		cmp = strings.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email))
+	case domain.SortByCreatedAt:
+		switch {
+		case a.CreatedAt.Before(b.CreatedAt):
+			cmp = -1
+		case a.CreatedAt.After(b.CreatedAt):
+			cmp = 1
+		}
+	}
+
+	if cmp == 0 {
+		switch {
+		case a.ID < b.ID:
+			cmp = -1
+		case a.ID > b.ID:
+			cmp = 1
+		}
+	}
+
+	if q.SortDesc {
+		return -cmp
+	}
+	return cmp
+}
+
+// cursorCustomer turns a decoded cursor into the customer it points after,
+// so it can be compared with compareCustomers.
+func cursorCustomer(sortBy domain.SortField, c *cursor) (*domain.Customer, error) {
+	customer := &domain.Customer{ID: c.ID}
+
+	switch sortBy {
+	case domain.SortByNome:
+		customer.Nome = c.Value
+	case domain.SortByEmail:
+		customer.Email = c.Value
+	case domain.SortByCreatedAt:
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, domain.ErrInvalidCursor
+		}
+		customer.CreatedAt = t
+	}
+
+	return customer, nil
+}
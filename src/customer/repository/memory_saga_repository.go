@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+)
+
+type memorySagaRepository struct {
+	db *MemoryDB
+}
+
+var _ SagaRepository = (*memorySagaRepository)(nil)
+
+// NewMemorySagaRepository shares db with the memory customer repository so
+// steps begun inside a customer transaction roll back with it.
+func NewMemorySagaRepository(db *MemoryDB) SagaRepository {
+	return &memorySagaRepository{db: db}
+}
+
+func (r *memorySagaRepository) Begin(ctx context.Context, step *domain.SagaStep) (*domain.SagaStep, error) {
+	var begun domain.SagaStep
+
+	err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		s := r.db.state
+
+		begun = *step
+		s.nextSagaID++
+		begun.ID = s.nextSagaID
+		begun.Status = domain.SagaStatusPending
+		begun.CreatedAt = time.Now()
+
+		stored := begun
+		s.sagaSteps[begun.ID] = &stored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &begun, nil
+}
+
+func (r *memorySagaRepository) SetStatus(ctx context.Context, id uint, status domain.SagaStatus, cause error) error {
+	return r.db.Transaction(ctx, func(ctx context.Context) error {
+		step, ok := r.db.state.sagaSteps[id]
+		if !ok {
+			return nil
+		}
+		step.Status = status
+		if cause != nil {
+			step.Error = cause.Error()
+		}
+		return nil
+	})
+}
+
+func (r *memorySagaRepository) ClaimPending(ctx context.Context, createdBefore time.Time, limit int, lease time.Duration) ([]domain.SagaStep, error) {
+	steps := []domain.SagaStep{}
+
+	err := r.db.Transaction(ctx, func(ctx context.Context) error {
+		s := r.db.state
+		now := time.Now()
+
+		for _, step := range s.sagaSteps {
+			if step.Status != domain.SagaStatusPending || !step.CreatedAt.Before(createdBefore) {
+				continue
+			}
+			if until, ok := s.sagaClaims[step.ID]; ok && until.After(now) {
+				continue
+			}
+			steps = append(steps, *step)
+		}
+
+		sort.Slice(steps, func(i, j int) bool { return steps[i].ID < steps[j].ID })
+		if len(steps) > limit {
+			steps = steps[:limit]
+		}
+
+		for _, step := range steps {
+			s.sagaClaims[step.ID] = now.Add(lease)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return steps, nil
+}
@@ -2,8 +2,18 @@ package models
 
 import "gorm.io/gorm"
 
+// Customer keeps email, document and user unique among live rows only:
+// DeletedID is zero while the customer is live and takes the row id on soft
+// delete, so deleted rows never collide with each other or with a new
+// registration. Document and UserID are nullable because customers created
+// before they existed have neither.
 type Customer struct {
 	gorm.Model
-	Nome  string `gorm:"not null" json:"nome"`
-	Email string `gorm:"not null;unique" json:"email"`
+	Nome      string  `gorm:"not null" json:"nome"`
+	Email     string  `gorm:"size:191;not null;uniqueIndex:idx_customers_email_live,priority:1" json:"email"`
+	Document  *string `gorm:"size:14;uniqueIndex:idx_customers_document_live,priority:1" json:"document"`
+	UserID    *uint64 `gorm:"uniqueIndex:idx_customers_user_live,priority:1" json:"user_id"`
+	DeletedID uint    `gorm:"not null;default:0;uniqueIndex:idx_customers_email_live,priority:2;uniqueIndex:idx_customers_document_live,priority:2;uniqueIndex:idx_customers_user_live,priority:2" json:"-"`
+	Status    string  `gorm:"not null;size:16;default:active;index" json:"status"`
+	Version   uint    `gorm:"not null;default:1" json:"version"`
 }
@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+type CustomerStatusChange struct {
+	gorm.Model
+	CustomerID uint   `gorm:"not null;index" json:"customer_id"`
+	Action     string `gorm:"not null;size:16" json:"action"`
+	FromStatus string `gorm:"not null;size:16" json:"from_status"`
+	ToStatus   string `gorm:"not null;size:16" json:"to_status"`
+	Reason     string `gorm:"type:text" json:"reason"`
+	ChangedBy  uint64 `gorm:"not null" json:"changed_by"`
+}
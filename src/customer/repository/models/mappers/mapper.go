@@ -11,9 +11,13 @@ func FromDomain(c *domain.Customer) *models.Customer {
 		return nil
 	}
 	return &models.Customer{
-		Model: gorm.Model{ID: c.ID},
-		Nome:  c.Nome,
-		Email: c.Email,
+		Model:    gorm.Model{ID: c.ID},
+		Nome:     c.Nome,
+		Email:    c.Email,
+		Document: nullableString(c.Document),
+		UserID:   nullableUint64(c.UserID),
+		Status:   string(c.Status),
+		Version:  c.Version,
 	}
 }
 
@@ -21,9 +25,107 @@ func ToDomain(m *models.Customer) *domain.Customer {
 	if m == nil {
 		return nil
 	}
-	return &domain.Customer{
-		ID:    m.ID,
-		Nome:  m.Nome,
-		Email: m.Email,
+	c := &domain.Customer{
+		ID:        m.ID,
+		Nome:      m.Nome,
+		Email:     m.Email,
+		Document:  stringValue(m.Document),
+		UserID:    uint64Value(m.UserID),
+		Status:    domain.Status(m.Status),
+		CreatedAt: m.CreatedAt,
+		Version:   m.Version,
 	}
+	if m.DeletedAt.Valid {
+		deletedAt := m.DeletedAt.Time
+		c.DeletedAt = &deletedAt
+	}
+	return c
+}
+
+func SagaStepFromDomain(s *domain.SagaStep) *models.SagaStep {
+	if s == nil {
+		return nil
+	}
+	return &models.SagaStep{
+		Model:      gorm.Model{ID: s.ID},
+		CustomerID: s.CustomerID,
+		Action:     string(s.Action),
+		Step:       s.Step,
+		Status:     string(s.Status),
+		Error:      s.Error,
+	}
+}
+
+func SagaStepToDomain(m *models.SagaStep) *domain.SagaStep {
+	if m == nil {
+		return nil
+	}
+	return &domain.SagaStep{
+		ID:         m.ID,
+		CustomerID: m.CustomerID,
+		Action:     domain.SagaAction(m.Action),
+		Step:       m.Step,
+		Status:     domain.SagaStatus(m.Status),
+		Error:      m.Error,
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+func StatusChangeFromDomain(c *domain.StatusChange) *models.CustomerStatusChange {
+	if c == nil {
+		return nil
+	}
+	return &models.CustomerStatusChange{
+		Model:      gorm.Model{ID: c.ID},
+		CustomerID: c.CustomerID,
+		Action:     string(c.Action),
+		FromStatus: string(c.From),
+		ToStatus:   string(c.To),
+		Reason:     c.Reason,
+		ChangedBy:  c.ChangedBy,
+	}
+}
+
+func StatusChangeToDomain(m *models.CustomerStatusChange) *domain.StatusChange {
+	if m == nil {
+		return nil
+	}
+	return &domain.StatusChange{
+		ID:         m.ID,
+		CustomerID: m.CustomerID,
+		Action:     domain.StatusAction(m.Action),
+		From:       domain.Status(m.FromStatus),
+		To:         domain.Status(m.ToStatus),
+		Reason:     m.Reason,
+		ChangedBy:  m.ChangedBy,
+		ChangedAt:  m.CreatedAt,
+	}
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func nullableUint64(n uint64) *uint64 {
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
+
+func uint64Value(n *uint64) uint64 {
+	if n == nil {
+		return 0
+	}
+	return *n
 }
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SagaStep struct {
+	gorm.Model
+	CustomerID   uint       `gorm:"not null;index" json:"customer_id"`
+	Action       string     `gorm:"not null;size:32" json:"action"`
+	Step         string     `gorm:"not null;size:64" json:"step"`
+	Status       string     `gorm:"not null;size:32;index" json:"status"`
+	Error        string     `gorm:"type:text" json:"error"`
+	ClaimedUntil *time.Time `json:"claimed_until"`
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"gorm.io/gorm"
+)
+
+// likeEscape is portable across MySQL and SQLite, unlike backslash.
+const likeEscape = "!"
+
+var sortColumns = map[domain.SortField]string{
+	domain.SortByID:        "id",
+	domain.SortByNome:      "nome",
+	domain.SortByEmail:     "email",
+	domain.SortByCreatedAt: "created_at",
+}
+
+// cursor is the keyset position after the last row of a page: the value of
+// the sort column plus the id as a tie breaker.
+type cursor struct {
+	Sort  domain.SortField `json:"s"`
+	Desc  bool             `json:"d,omitempty"`
+	Value string           `json:"v,omitempty"`
+	ID    uint             `json:"id"`
+}
+
+func encodeCursor(q domain.CustomerQuery, last *domain.Customer) string {
+	c := cursor{Sort: q.SortBy, Desc: q.SortDesc, ID: last.ID}
+
+	switch q.SortBy {
+	case domain.SortByNome:
+		c.Value = last.Nome
+	case domain.SortByEmail:
+		c.Value = last.Email
+	case domain.SortByCreatedAt:
+		c.Value = last.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(q domain.CustomerQuery) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	c := &cursor{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	if c.Sort != q.SortBy || c.Desc != q.SortDesc {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+func applyFilters(db *gorm.DB, q domain.CustomerQuery) *gorm.DB {
+	if q.Deleted {
+		db = db.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+
+	if q.Status != "" {
+		db = db.Where("status = ?", string(q.Status))
+	}
+
+	db = applyStringFilter(db, "nome", q.Nome)
+	db = applyStringFilter(db, "email", q.Email)
+
+	if q.CreatedFrom != nil {
+		db = db.Where("created_at >= ?", *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		db = db.Where("created_at < ?", *q.CreatedTo)
+	}
+
+	return db
+}
+
+func applyStringFilter(db *gorm.DB, column string, f *domain.StringFilter) *gorm.DB {
+	if f == nil || f.Value == "" {
+		return db
+	}
+
+	switch f.Mode {
+	case domain.MatchPrefix:
+		return db.Where(column+" LIKE ? ESCAPE '"+likeEscape+"'", escapeLike(f.Value)+"%")
+	case domain.MatchContains:
+		return db.Where(column+" LIKE ? ESCAPE '"+likeEscape+"'", "%"+escapeLike(f.Value)+"%")
+	default:
+		return db.Where(column+" = ?", f.Value)
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(
+		likeEscape, likeEscape+likeEscape,
+		"%", likeEscape+"%",
+		"_", likeEscape+"_",
+	).Replace(s)
+}
+
+func applyCursor(db *gorm.DB, q domain.CustomerQuery) (*gorm.DB, error) {
+	if q.Cursor == "" {
+		return db, nil
+	}
+
+	c, err := decodeCursor(q)
+	if err != nil {
+		return nil, err
+	}
+
+	op := ">"
+	if q.SortDesc {
+		op = "<"
+	}
+
+	if q.SortBy == domain.SortByID {
+		return db.Where("id "+op+" ?", c.ID), nil
+	}
+
+	var value any = c.Value
+	if q.SortBy == domain.SortByCreatedAt {
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, domain.ErrInvalidCursor
+		}
+		value = t
+	}
+
+	column := sortColumns[q.SortBy]
+	return db.Where(
+		"("+column+" "+op+" ?) OR ("+column+" = ? AND id "+op+" ?)",
+		value, value, c.ID,
+	), nil
+}
+
+func applyOrder(db *gorm.DB, q domain.CustomerQuery) *gorm.DB {
+	dir := " ASC"
+	if q.SortDesc {
+		dir = " DESC"
+	}
+
+	if q.SortBy != domain.SortByID {
+		db = db.Order(sortColumns[q.SortBy] + dir)
+	}
+	return db.Order("id" + dir)
+}
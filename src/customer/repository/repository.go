@@ -2,95 +2,423 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"github.com/rasteiro11/MCABankCustomer/pkg/outbox"
 	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
 	"github.com/rasteiro11/MCABankCustomer/src/customer/repository/models"
 	"github.com/rasteiro11/MCABankCustomer/src/customer/repository/models/mappers"
+	"github.com/rasteiro11/PogCore/pkg/database"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type customerRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	outbox outbox.Store
 }
 
 var _ CustomerRepository = (*customerRepository)(nil)
 
 func NewCustomerRepository(db *gorm.DB) CustomerRepository {
-	return &customerRepository{db: db}
+	return &customerRepository{db: db, outbox: outbox.NewStore(db)}
 }
 
-func (r *customerRepository) FindAll(ctx context.Context) ([]domain.Customer, error) {
-	var ms []models.Customer
-	if err := r.db.WithContext(ctx).Find(&ms).Error; err != nil {
+func (r *customerRepository) FindAll(ctx context.Context, q domain.CustomerQuery) (*domain.CustomerPage, error) {
+	q.Normalize()
+
+	var total int64
+	if err := applyFilters(conn(ctx, r.db).Model(&models.Customer{}), q).
+		Count(&total).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	db, err := applyCursor(applyFilters(conn(ctx, r.db), q), q)
+	if err != nil {
 		return nil, err
 	}
-	customers := make([]domain.Customer, 0, len(ms))
+
+	// one extra row tells whether there is a next page
+	var ms []models.Customer
+	if err := applyOrder(db, q).Limit(q.Limit + 1).Find(&ms).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	page := &domain.CustomerPage{Total: total, Limit: q.Limit}
+	if len(ms) > q.Limit {
+		ms = ms[:q.Limit]
+		page.NextCursor = encodeCursor(q, mappers.ToDomain(&ms[len(ms)-1]))
+	}
+
+	page.Items = make([]domain.Customer, 0, len(ms))
 	for _, m := range ms {
-		customers = append(customers, *mappers.ToDomain(&m))
+		page.Items = append(page.Items, *mappers.ToDomain(&m))
 	}
-	return customers, nil
+	return page, nil
 }
 
 func (r *customerRepository) FindByID(ctx context.Context, id uint) (*domain.Customer, error) {
 	var m models.Customer
-	if err := r.db.WithContext(ctx).First(&m, id).Error; err != nil {
-		return nil, err
+	if err := conn(ctx, r.db).First(&m, id).Error; err != nil {
+		return nil, translateError(err)
 	}
 	return mappers.ToDomain(&m), nil
 }
 
-func (r *customerRepository) Create(ctx context.Context, customer *domain.Customer) (*domain.Customer, error) {
-	m := mappers.FromDomain(customer)
-	if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
-		return nil, err
+func (r *customerRepository) FindByDocument(ctx context.Context, document string) (*domain.Customer, error) {
+	var m models.Customer
+	if err := conn(ctx, r.db).Where("document = ?", document).First(&m).Error; err != nil {
+		return nil, translateError(err)
 	}
-	return mappers.ToDomain(m), nil
+	return mappers.ToDomain(&m), nil
 }
 
-func (r *customerRepository) CreateWithCallback(
-	ctx context.Context,
-	customer *domain.Customer,
-	fn func(*domain.Customer) error,
-) (*domain.Customer, error) {
+func (r *customerRepository) FindByUserID(ctx context.Context, userID uint64) (*domain.Customer, error) {
+	var m models.Customer
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).First(&m).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return mappers.ToDomain(&m), nil
+}
+
+// LinkUser sets the auth user of a customer that has none yet.
+func (r *customerRepository) LinkUser(ctx context.Context, id uint, userID uint64) (*domain.Customer, error) {
+	var linked *domain.Customer
+
+	if err := r.Transaction(ctx, func(ctx context.Context) error {
+		res := conn(ctx, r.db).
+			Model(&models.Customer{}).
+			Where("id = ? AND user_id IS NULL", id).
+			Updates(map[string]any{
+				"user_id": userID,
+				"version": gorm.Expr("version + 1"),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			if _, err := r.FindByID(ctx, id); err != nil {
+				return err
+			}
+			return domain.ErrUserAlreadyLinked
+		}
+
+		var err error
+		if linked, err = r.FindByID(ctx, id); err != nil {
+			return err
+		}
+
+		return r.emit(ctx, domain.EventCustomerUpdated, linked.ID, linked)
+	}); err != nil {
+		return nil, translateError(err)
+	}
+
+	return linked, nil
+}
+
+func (r *customerRepository) Create(ctx context.Context, customer *domain.Customer) (*domain.Customer, error) {
 	var created *domain.Customer
 
-	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	if err := r.Transaction(ctx, func(ctx context.Context) error {
 		m := mappers.FromDomain(customer)
-
-		if err := tx.Create(m).Error; err != nil {
+		m.Version = 1
+		if err := conn(ctx, r.db).Create(m).Error; err != nil {
 			return err
 		}
 
 		created = mappers.ToDomain(m)
+		return r.emit(ctx, domain.EventCustomerCreated, created.ID, created)
+	}); err != nil {
+		return nil, translateError(err)
+	}
+
+	return created, nil
+}
+
+func (r *customerRepository) Update(ctx context.Context, customer *domain.Customer) (*domain.Customer, error) {
+	var updated *domain.Customer
+
+	if err := r.Transaction(ctx, func(ctx context.Context) error {
+		// a map writes zero values too, which makes this a full replace
+		// of everything but an omitted document
+		updates := map[string]any{
+			"nome":  customer.Nome,
+			"email": customer.Email,
+		}
+		if customer.Document != "" {
+			updates["document"] = customer.Document
+		}
+		if err := r.updateVersioned(ctx, customer.ID, customer.Version, updates); err != nil {
+			return err
+		}
+
+		var err error
+		if updated, err = r.FindByID(ctx, customer.ID); err != nil {
+			return err
+		}
+
+		return r.emit(ctx, domain.EventCustomerUpdated, updated.ID, updated)
+	}); err != nil {
+		return nil, translateError(err)
+	}
+
+	return updated, nil
+}
+
+// Patch updates exactly the columns set in patch. An empty patch is a read.
+func (r *customerRepository) Patch(ctx context.Context, id uint, patch domain.CustomerPatch) (*domain.Customer, error) {
+	updates := map[string]any{}
+	if patch.Nome != nil {
+		updates["nome"] = *patch.Nome
+	}
+	if patch.Email != nil {
+		updates["email"] = *patch.Email
+	}
+	if patch.Document != nil {
+		// a cleared document is NULL, so it never collides on the index
+		var document *string
+		if *patch.Document != "" {
+			document = patch.Document
+		}
+		updates["document"] = document
+	}
 
-		if err := fn(created); err != nil {
+	var patched *domain.Customer
+
+	if err := r.Transaction(ctx, func(ctx context.Context) error {
+		if len(updates) > 0 {
+			if err := r.updateVersioned(ctx, id, patch.ExpectedVersion, updates); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if patched, err = r.FindByID(ctx, id); err != nil {
 			return err
 		}
 
+		if len(updates) == 0 {
+			if patch.ExpectedVersion != 0 && patch.ExpectedVersion != patched.Version {
+				return domain.ErrVersionMismatch
+			}
+			return nil
+		}
+		return r.emit(ctx, domain.EventCustomerUpdated, patched.ID, patched)
+	}); err != nil {
+		return nil, translateError(err)
+	}
+
+	return patched, nil
+}
+
+// Delete soft deletes the customer and moves it out of the live email
+// index, freeing its email for a new registration.
+func (r *customerRepository) Delete(ctx context.Context, id uint, expectedVersion uint) error {
+	return translateError(r.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.updateVersioned(ctx, id, expectedVersion, map[string]any{
+			"deleted_at": time.Now(),
+			"deleted_id": gorm.Expr("id"),
+		}); err != nil {
+			return err
+		}
+		return r.emit(ctx, domain.EventCustomerDeleted, id, map[string]any{"id": id})
+	}))
+}
+
+func (r *customerRepository) HardDelete(ctx context.Context, id uint) error {
+	return translateError(r.Transaction(ctx, func(ctx context.Context) error {
+		res := conn(ctx, r.db).Unscoped().Delete(&models.Customer{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return r.emit(ctx, domain.EventCustomerDeleted, id, map[string]any{"id": id, "hard": true})
+	}))
+}
+
+// Restore brings a soft-deleted customer back. It fails with
+// ErrEmailAlreadyExists when a live customer took the email meanwhile.
+func (r *customerRepository) Restore(ctx context.Context, id uint) (*domain.Customer, error) {
+	var restored *domain.Customer
+
+	if err := r.Transaction(ctx, func(ctx context.Context) error {
+		res := conn(ctx, r.db).
+			Unscoped().
+			Model(&models.Customer{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]any{
+				"deleted_at": nil,
+				"deleted_id": 0,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			if _, err := r.FindByID(ctx, id); err != nil {
+				return err
+			}
+			return domain.ErrNotDeleted
+		}
+
+		var err error
+		if restored, err = r.FindByID(ctx, id); err != nil {
+			return err
+		}
+
+		return r.emit(ctx, domain.EventCustomerRestored, restored.ID, restored)
+	}); err != nil {
+		return nil, translateError(err)
+	}
+
+	return restored, nil
+}
+
+// Purge hard deletes up to limit customers soft-deleted before the given
+// time and returns how many it removed.
+func (r *customerRepository) Purge(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var purged int64
+
+	if err := r.Transaction(ctx, func(ctx context.Context) error {
+		var ids []uint
+		if err := conn(ctx, r.db).
+			Unscoped().
+			Model(&models.Customer{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Order("id").
+			Limit(limit).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		res := conn(ctx, r.db).Unscoped().Delete(&models.Customer{}, ids)
+		if res.Error != nil {
+			return res.Error
+		}
+		purged = res.RowsAffected
+
+		for _, id := range ids {
+			if err := r.emit(ctx, domain.EventCustomerPurged, id, map[string]any{"id": id}); err != nil {
+				return err
+			}
+		}
 		return nil
-	})
+	}); err != nil {
+		return 0, translateError(err)
+	}
 
-	if err != nil {
-		return nil, err
+	return purged, nil
+}
+
+// ChangeStatus applies change only if the customer is still in change.From,
+// and records it in the status audit trail in the same transaction.
+func (r *customerRepository) ChangeStatus(ctx context.Context, change *domain.StatusChange) (*domain.Customer, error) {
+	var changed *domain.Customer
+
+	if err := r.Transaction(ctx, func(ctx context.Context) error {
+		res := conn(ctx, r.db).
+			Model(&models.Customer{}).
+			Where("id = ? AND status = ?", change.CustomerID, string(change.From)).
+			Updates(map[string]any{
+				"status":  string(change.To),
+				"version": gorm.Expr("version + 1"),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			if _, err := r.FindByID(ctx, change.CustomerID); err != nil {
+				return err
+			}
+			return domain.ErrConflict
+		}
+
+		m := mappers.StatusChangeFromDomain(change)
+		if err := conn(ctx, r.db).Create(m).Error; err != nil {
+			return err
+		}
+		*change = *mappers.StatusChangeToDomain(m)
+
+		var err error
+		if changed, err = r.FindByID(ctx, change.CustomerID); err != nil {
+			return err
+		}
+
+		return r.emit(ctx, domain.EventCustomerStatusChanged, changed.ID, change)
+	}); err != nil {
+		return nil, translateError(err)
 	}
 
-	return created, nil
+	return changed, nil
 }
 
-func (r *customerRepository) Update(ctx context.Context, customer *domain.Customer) (*domain.Customer, error) {
-	if err := r.db.WithContext(ctx).
-		Model(&models.Customer{}).
-		Where("id = ?", customer.ID).
-		Updates(models.Customer{
-			Nome:  customer.Nome,
-			Email: customer.Email,
-		}).Error; err != nil {
+// StatusHistory returns the status changes of a customer, oldest first.
+func (r *customerRepository) StatusHistory(ctx context.Context, id uint) ([]domain.StatusChange, error) {
+	if _, err := r.FindByID(ctx, id); err != nil {
 		return nil, err
 	}
 
-	return r.FindByID(ctx, customer.ID)
+	var ms []models.CustomerStatusChange
+	if err := conn(ctx, r.db).
+		Where("customer_id = ?", id).
+		Order("id").
+		Find(&ms).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	changes := make([]domain.StatusChange, 0, len(ms))
+	for _, m := range ms {
+		changes = append(changes, *mappers.StatusChangeToDomain(&m))
+	}
+	return changes, nil
+}
+
+// updateVersioned applies updates and bumps the version. A non-zero
+// expectedVersion turns the update into a compare-and-swap.
+func (r *customerRepository) updateVersioned(ctx context.Context, id, expectedVersion uint, updates map[string]any) error {
+	updates["version"] = gorm.Expr("version + 1")
+
+	db := conn(ctx, r.db).Model(&models.Customer{}).Where("id = ?", id)
+	if expectedVersion != 0 {
+		db = db.Where("version = ?", expectedVersion)
+	}
+
+	res := db.Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return r.missingOrStale(ctx, id)
+	}
+	return nil
+}
+
+// missingOrStale explains why a guarded write touched no rows.
+func (r *customerRepository) missingOrStale(ctx context.Context, id uint) error {
+	if _, err := r.FindByID(ctx, id); err != nil {
+		return err
+	}
+	return domain.ErrVersionMismatch
+}
+
+func (r *customerRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		return fn(database.WithTx(ctx, tx))
+	})
 }
 
-func (r *customerRepository) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&models.Customer{}, id).Error
+// emit writes a lifecycle event to the outbox. It must be called with a
+// ctx from Transaction so the event commits together with the mutation.
+func (r *customerRepository) emit(ctx context.Context, eventType string, id uint, payload any) error {
+	event, err := outbox.NewEvent(domain.CustomerAggregate, id, eventType, payload)
+	if err != nil {
+		return err
+	}
+	return r.outbox.Add(ctx, event)
 }
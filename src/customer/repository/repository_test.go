@@ -0,0 +1,167 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/migrations"
+	"github.com/rasteiro11/MCABankCustomer/pkg/dbdriver"
+	"github.com/rasteiro11/MCABankCustomer/pkg/migrate"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/repository"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/repository/repositorytest"
+	"gorm.io/gorm"
+)
+
+// migratedSQLite returns an in-memory SQLite database with every
+// migration applied.
+func migratedSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := dbdriver.OpenSQLite(dbdriver.InMemory)
+	if err != nil {
+		t.Fatalf("OpenSQLite() returned error: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.Conn().DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	all, err := migrations.Load(dbdriver.SQLite)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if _, err := migrate.NewMigrator(db.Conn(), all).Up(context.Background()); err != nil {
+		t.Fatalf("Up() returned error: %v", err)
+	}
+	return db.Conn()
+}
+
+func TestCustomerRepositoryConformance(t *testing.T) {
+	implementations := []struct {
+		name    string
+		newRepo func(t *testing.T) repository.CustomerRepository
+	}{
+		{"memory", func(*testing.T) repository.CustomerRepository {
+			return repository.NewMemoryCustomerRepository(repository.NewMemoryDB())
+		}},
+		{"sqlite", func(t *testing.T) repository.CustomerRepository {
+			return repository.NewCustomerRepository(migratedSQLite(t))
+		}},
+	}
+
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			for _, c := range repositorytest.Cases {
+				t.Run(c.Name, func(t *testing.T) {
+					if err := c.Run(context.Background(), impl.newRepo(t)); err != nil {
+						t.Fatal(err)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestSagaRepositoryClaimPending(t *testing.T) {
+	implementations := []struct {
+		name    string
+		newRepo func(t *testing.T) repository.SagaRepository
+	}{
+		{"memory", func(*testing.T) repository.SagaRepository {
+			return repository.NewMemorySagaRepository(repository.NewMemoryDB())
+		}},
+		{"sqlite", func(t *testing.T) repository.SagaRepository {
+			return repository.NewSagaRepository(migratedSQLite(t))
+		}},
+	}
+
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := impl.newRepo(t)
+
+			for id := uint(1); id <= 3; id++ {
+				if _, err := repo.Begin(ctx, &domain.SagaStep{
+					CustomerID: id,
+					Action:     domain.SagaActionCreate,
+					Step:       domain.SagaStepCreateBalance,
+				}); err != nil {
+					t.Fatalf("Begin() returned error: %v", err)
+				}
+			}
+			if err := repo.SetStatus(ctx, 3, domain.SagaStatusCompleted, nil); err != nil {
+				t.Fatalf("SetStatus() returned error: %v", err)
+			}
+
+			// steps still within the grace period are left alone
+			steps, err := repo.ClaimPending(ctx, time.Now().Add(-time.Hour), 10, time.Minute)
+			if err != nil || len(steps) != 0 {
+				t.Fatalf("ClaimPending() of old steps returned %d steps, error %v", len(steps), err)
+			}
+
+			cutoff := time.Now().Add(time.Second)
+			first, err := repo.ClaimPending(ctx, cutoff, 1, time.Minute)
+			if err != nil || len(first) != 1 {
+				t.Fatalf("ClaimPending(limit 1) returned %d steps, error %v", len(first), err)
+			}
+			rest, err := repo.ClaimPending(ctx, cutoff, 10, time.Minute)
+			if err != nil || len(rest) != 1 || rest[0].ID == first[0].ID {
+				t.Fatalf("second ClaimPending() returned %+v, error %v, want the other pending step", rest, err)
+			}
+
+			// both pending steps are leased, the completed one never shows up
+			again, err := repo.ClaimPending(ctx, cutoff, 10, time.Minute)
+			if err != nil || len(again) != 0 {
+				t.Fatalf("ClaimPending() of leased steps returned %d steps, error %v", len(again), err)
+			}
+		})
+	}
+}
+
+func TestMemorySagaRepositoryRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDB()
+	repo := repository.NewMemorySagaRepository(db)
+
+	for id := uint(1); id <= 3; id++ {
+		if _, err := repo.Begin(ctx, &domain.SagaStep{
+			CustomerID: id,
+			Action:     domain.SagaActionCreate,
+			Step:       domain.SagaStepCreateBalance,
+		}); err != nil {
+			t.Fatalf("Begin() returned error: %v", err)
+		}
+	}
+	if err := repo.SetStatus(ctx, 2, domain.SagaStatusCompleted, nil); err != nil {
+		t.Fatalf("SetStatus() returned error: %v", err)
+	}
+
+	// a step begun in a transaction that rolls back is never seen
+	rollback := errors.New("rollback")
+	err := db.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.Begin(ctx, &domain.SagaStep{CustomerID: 4, Action: domain.SagaActionCreate}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Transaction() returned %v, want the callback error", err)
+	}
+
+	pending, err := repo.ClaimPending(ctx, time.Now().Add(time.Second), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimPending() returned error: %v", err)
+	}
+	if len(pending) != 2 || pending[0].ID != 1 || pending[1].ID != 3 {
+		t.Errorf("ClaimPending() returned %+v, want steps 1 and 3", pending)
+	}
+
+	// both are leased now
+	if again, err := repo.ClaimPending(ctx, time.Now().Add(time.Second), 10, time.Minute); err != nil || len(again) != 0 {
+		t.Errorf("ClaimPending() of leased steps returned %d steps, error %v", len(again), err)
+	}
+}
@@ -0,0 +1,521 @@
+// Package repositorytest holds the behaviour every CustomerRepository must
+// share, so the gorm and memory implementations can be held to the same
+// contract. Each case gets a fresh, empty repository.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/repository"
+)
+
+var errRollback = errors.New("rollback")
+
+type (
+	Case struct {
+		Name string
+		Run  func(ctx context.Context, repo repository.CustomerRepository) error
+	}
+
+	Failure struct {
+		Case string
+		Err  error
+	}
+
+	// Failures is returned by Run when at least one case failed.
+	Failures []Failure
+)
+
+func (f Failures) Error() string {
+	lines := make([]string, 0, len(f))
+	for _, failure := range f {
+		lines = append(lines, failure.Case+": "+failure.Err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Run executes every case against a repository from newRepo and returns
+// Failures, or nil when the implementation conforms. From a Go test, range
+// over Cases instead to get one subtest per case.
+func Run(ctx context.Context, newRepo func() repository.CustomerRepository) error {
+	var failures Failures
+	for _, c := range Cases {
+		if err := c.Run(ctx, newRepo()); err != nil {
+			failures = append(failures, Failure{Case: c.Name, Err: err})
+		}
+	}
+
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+var Cases = []Case{
+	{Name: "create assigns id and first version", Run: createAssignsIDAndVersion},
+	{Name: "email is unique among live customers", Run: uniqueEmail},
+	{Name: "document is unique among live customers", Run: uniqueDocument},
+	{Name: "soft delete hides and frees the email", Run: softDelete},
+	{Name: "restore brings a customer back", Run: restore},
+	{Name: "restore fails when the email was taken", Run: restoreConflict},
+	{Name: "transaction rolls back every write", Run: transactionRollback},
+	{Name: "update with a stale version fails", Run: staleVersion},
+	{Name: "update keeps an omitted document", Run: updateKeepsDocument},
+	{Name: "patch changes only the given fields", Run: patch},
+	{Name: "patch clears the document", Run: patchClearsDocument},
+	{Name: "change status is a compare-and-swap with history", Run: changeStatus},
+	{Name: "link user only once", Run: linkUser},
+	{Name: "purge honours the cut-off and limit", Run: purge},
+	{Name: "pages cover every customer once", Run: pagination},
+}
+
+func createAssignsIDAndVersion(ctx context.Context, repo repository.CustomerRepository) error {
+	c, err := repo.Create(ctx, newCustomer(1))
+	if err != nil {
+		return err
+	}
+	if c.ID == 0 {
+		return errors.New("created customer has no id")
+	}
+	if c.Version != 1 {
+		return fmt.Errorf("version = %d, want 1", c.Version)
+	}
+
+	found, err := repo.FindByID(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	if found.Email != c.Email || found.Nome != c.Nome {
+		return fmt.Errorf("found %+v, want %+v", found, c)
+	}
+	return nil
+}
+
+func uniqueEmail(ctx context.Context, repo repository.CustomerRepository) error {
+	if _, err := repo.Create(ctx, newCustomer(1)); err != nil {
+		return err
+	}
+
+	dup := newCustomer(2)
+	dup.Email = newCustomer(1).Email
+	_, err := repo.Create(ctx, dup)
+	return expect(err, domain.ErrEmailAlreadyExists)
+}
+
+func uniqueDocument(ctx context.Context, repo repository.CustomerRepository) error {
+	if _, err := repo.Create(ctx, newCustomer(1)); err != nil {
+		return err
+	}
+
+	dup := newCustomer(2)
+	dup.Document = newCustomer(1).Document
+	_, err := repo.Create(ctx, dup)
+	return expect(err, domain.ErrDocumentAlreadyExists)
+}
+
+func softDelete(ctx context.Context, repo repository.CustomerRepository) error {
+	c, err := repo.Create(ctx, newCustomer(1))
+	if err != nil {
+		return err
+	}
+	if err := repo.Delete(ctx, c.ID, c.Version); err != nil {
+		return err
+	}
+
+	_, err = repo.FindByID(ctx, c.ID)
+	if err := expect(err, domain.ErrNotFound); err != nil {
+		return err
+	}
+
+	live, err := repo.FindAll(ctx, domain.CustomerQuery{})
+	if err != nil {
+		return err
+	}
+	if live.Total != 0 {
+		return fmt.Errorf("live total = %d, want 0", live.Total)
+	}
+
+	deleted, err := repo.FindAll(ctx, domain.CustomerQuery{Deleted: true})
+	if err != nil {
+		return err
+	}
+	if deleted.Total != 1 || deleted.Items[0].DeletedAt == nil {
+		return fmt.Errorf("deleted listing = %+v, want the deleted customer", deleted.Items)
+	}
+
+	_, err = repo.Create(ctx, newCustomer(1))
+	return err
+}
+
+func restore(ctx context.Context, repo repository.CustomerRepository) error {
+	c, err := repo.Create(ctx, newCustomer(1))
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.Restore(ctx, c.ID)
+	if err := expect(err, domain.ErrNotDeleted); err != nil {
+		return err
+	}
+
+	if err := repo.Delete(ctx, c.ID, 0); err != nil {
+		return err
+	}
+	restored, err := repo.Restore(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	if restored.DeletedAt != nil {
+		return errors.New("restored customer is still deleted")
+	}
+	if restored.Version <= c.Version {
+		return fmt.Errorf("version = %d, want more than %d", restored.Version, c.Version)
+	}
+
+	_, err = repo.Restore(ctx, c.ID+1000)
+	return expect(err, domain.ErrNotFound)
+}
+
+func restoreConflict(ctx context.Context, repo repository.CustomerRepository) error {
+	c, err := repo.Create(ctx, newCustomer(1))
+	if err != nil {
+		return err
+	}
+	if err := repo.Delete(ctx, c.ID, 0); err != nil {
+		return err
+	}
+
+	// only the email is taken, so the engine has a single index to report
+	taken := newCustomer(2)
+	taken.Email = c.Email
+	if _, err := repo.Create(ctx, taken); err != nil {
+		return err
+	}
+
+	_, err = repo.Restore(ctx, c.ID)
+	return expect(err, domain.ErrEmailAlreadyExists)
+}
+
+func transactionRollback(ctx context.Context, repo repository.CustomerRepository) error {
+	existing, err := repo.Create(ctx, newCustomer(1))
+	if err != nil {
+		return err
+	}
+
+	err = repo.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.Create(ctx, newCustomer(2)); err != nil {
+			return err
+		}
+		nome := "changed"
+		if _, err := repo.Patch(ctx, existing.ID, domain.CustomerPatch{Nome: &nome}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		return fmt.Errorf("error = %v, want the rollback error", err)
+	}
+
+	page, err := repo.FindAll(ctx, domain.CustomerQuery{})
+	if err != nil {
+		return err
+	}
+	if page.Total != 1 {
+		return fmt.Errorf("total = %d, want 1", page.Total)
+	}
+
+	found, err := repo.FindByID(ctx, existing.ID)
+	if err != nil {
+		return err
+	}
+	if found.Nome != existing.Nome || found.Version != existing.Version {
+		return fmt.Errorf("found %+v, want the customer untouched", found)
+	}
+	return nil
+}
+
+func staleVersion(ctx context.Context, repo repository.CustomerRepository) error {
+	c, err := repo.Create(ctx, newCustomer(1))
+	if err != nil {
+		return err
+	}
+
+	c.Nome = "first"
+	updated, err := repo.Update(ctx, c)
+	if err != nil {
+		return err
+	}
+	if updated.Version != c.Version+1 {
+		return fmt.Errorf("version = %d, want %d", updated.Version, c.Version+1)
+	}
+
+	c.Nome = "second"
+	_, err = repo.Update(ctx, c)
+	if err := expect(err, domain.ErrVersionMismatch); err != nil {
+		return err
+	}
+
+	return expect(repo.Delete(ctx, c.ID, c.Version), domain.ErrVersionMismatch)
+}
+
+func updateKeepsDocument(ctx context.Context, repo repository.CustomerRepository) error {
+	c, err := repo.Create(ctx, newCustomer(1))
+	if err != nil {
+		return err
+	}
+
+	// customers without a document never collide with each other
+	for n := 2; n <= 3; n++ {
+		undocumented := newCustomer(n)
+		undocumented.Document = ""
+		if _, err := repo.Create(ctx, undocumented); err != nil {
+			return err
+		}
+	}
+
+	c.Nome = "renamed"
+	c.Document = ""
+	updated, err := repo.Update(ctx, c)
+	if err != nil {
+		return err
+	}
+	if updated.Document != newCustomer(1).Document {
+		return fmt.Errorf("document = %q, want %q", updated.Document, newCustomer(1).Document)
+	}
+	return nil
+}
+
+func patch(ctx context.Context, repo repository.CustomerRepository) error {
+	c, err := repo.Create(ctx, newCustomer(1))
+	if err != nil {
+		return err
+	}
+
+	nome := "patched"
+	patched, err := repo.Patch(ctx, c.ID, domain.CustomerPatch{Nome: &nome, ExpectedVersion: c.Version})
+	if err != nil {
+		return err
+	}
+	if patched.Nome != nome || patched.Email != c.Email || patched.Document != c.Document {
+		return fmt.Errorf("patched %+v, want only nome changed", patched)
+	}
+
+	unchanged, err := repo.Patch(ctx, c.ID, domain.CustomerPatch{})
+	if err != nil {
+		return err
+	}
+	if unchanged.Version != patched.Version {
+		return fmt.Errorf("empty patch bumped the version to %d", unchanged.Version)
+	}
+
+	_, err = repo.Patch(ctx, c.ID+1000, domain.CustomerPatch{Nome: &nome})
+	return expect(err, domain.ErrNotFound)
+}
+
+func patchClearsDocument(ctx context.Context, repo repository.CustomerRepository) error {
+	cleared := ""
+	for n := 1; n <= 2; n++ {
+		c, err := repo.Create(ctx, newCustomer(n))
+		if err != nil {
+			return err
+		}
+
+		patched, err := repo.Patch(ctx, c.ID, domain.CustomerPatch{Document: &cleared})
+		if err != nil {
+			return err
+		}
+		if patched.Document != "" {
+			return fmt.Errorf("document = %q after clearing it", patched.Document)
+		}
+	}
+
+	_, err := repo.FindByDocument(ctx, newCustomer(1).Document)
+	if err := expect(err, domain.ErrNotFound); err != nil {
+		return err
+	}
+
+	// the document is free for another customer
+	_, err = repo.Create(ctx, newCustomer(len(cpfs)+1))
+	return err
+}
+
+func changeStatus(ctx context.Context, repo repository.CustomerRepository) error {
+	c := newCustomer(1)
+	c.Status = domain.StatusPending
+	c, err := repo.Create(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	change, err := domain.NewStatusChange(c, domain.StatusActionActivate, "", 7)
+	if err != nil {
+		return err
+	}
+	changed, err := repo.ChangeStatus(ctx, change)
+	if err != nil {
+		return err
+	}
+	if changed.Status != domain.StatusActive {
+		return fmt.Errorf("status = %s, want %s", changed.Status, domain.StatusActive)
+	}
+
+	// the same change again no longer matches the current status
+	stale := *change
+	stale.ID = 0
+	_, err = repo.ChangeStatus(ctx, &stale)
+	if err := expect(err, domain.ErrConflict); err != nil {
+		return err
+	}
+
+	history, err := repo.StatusHistory(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	if len(history) != 1 || history[0].To != domain.StatusActive || history[0].ChangedBy != 7 {
+		return fmt.Errorf("history = %+v, want the single activation", history)
+	}
+	return nil
+}
+
+func linkUser(ctx context.Context, repo repository.CustomerRepository) error {
+	c, err := repo.Create(ctx, newCustomer(1))
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.LinkUser(ctx, c.ID, 42); err != nil {
+		return err
+	}
+	found, err := repo.FindByUserID(ctx, 42)
+	if err != nil {
+		return err
+	}
+	if found.ID != c.ID {
+		return fmt.Errorf("found customer %d, want %d", found.ID, c.ID)
+	}
+
+	_, err = repo.LinkUser(ctx, c.ID, 43)
+	return expect(err, domain.ErrUserAlreadyLinked)
+}
+
+func purge(ctx context.Context, repo repository.CustomerRepository) error {
+	var ids []uint
+	for i := 1; i <= 3; i++ {
+		c, err := repo.Create(ctx, newCustomer(i))
+		if err != nil {
+			return err
+		}
+		if err := repo.Delete(ctx, c.ID, 0); err != nil {
+			return err
+		}
+		ids = append(ids, c.ID)
+	}
+	if _, err := repo.Create(ctx, newCustomer(4)); err != nil {
+		return err
+	}
+
+	n, err := repo.Purge(ctx, time.Now().Add(-time.Hour), 10)
+	if err != nil {
+		return err
+	}
+	if n != 0 {
+		return fmt.Errorf("purged %d customers deleted after the cut-off", n)
+	}
+
+	n, err = repo.Purge(ctx, time.Now().Add(time.Second), 2)
+	if err != nil {
+		return err
+	}
+	if n != 2 {
+		return fmt.Errorf("purged %d, want the limit of 2", n)
+	}
+
+	_, err = repo.Restore(ctx, ids[0])
+	if err := expect(err, domain.ErrNotFound); err != nil {
+		return err
+	}
+
+	live, err := repo.FindAll(ctx, domain.CustomerQuery{})
+	if err != nil {
+		return err
+	}
+	if live.Total != 1 {
+		return fmt.Errorf("live total = %d, want 1", live.Total)
+	}
+	return nil
+}
+
+func pagination(ctx context.Context, repo repository.CustomerRepository) error {
+	const total = 5
+	for i := 1; i <= total; i++ {
+		if _, err := repo.Create(ctx, newCustomer(i)); err != nil {
+			return err
+		}
+	}
+
+	for _, q := range []domain.CustomerQuery{
+		{Limit: 2},
+		{Limit: 2, SortBy: domain.SortByEmail, SortDesc: true},
+	} {
+		seen := map[uint]bool{}
+		var last *domain.Customer
+		for {
+			page, err := repo.FindAll(ctx, q)
+			if err != nil {
+				return err
+			}
+			if page.Total != total {
+				return fmt.Errorf("total = %d, want %d", page.Total, total)
+			}
+
+			for i := range page.Items {
+				c := &page.Items[i]
+				if seen[c.ID] {
+					return fmt.Errorf("customer %d listed twice sorting by %s", c.ID, q.SortBy)
+				}
+				if q.SortBy == domain.SortByEmail && last != nil && c.Email > last.Email {
+					return fmt.Errorf("%s listed after %s in descending order", c.Email, last.Email)
+				}
+				seen[c.ID] = true
+				last = c
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+			q.Cursor = page.NextCursor
+		}
+
+		if len(seen) != total {
+			return fmt.Errorf("listed %d customers, want %d", len(seen), total)
+		}
+	}
+
+	_, err := repo.FindAll(ctx, domain.CustomerQuery{Cursor: "not-a-cursor"})
+	return expect(err, domain.ErrInvalidCursor)
+}
+
+// newCustomer returns a distinct customer for each n. Documents are valid
+// CPFs so implementations that validate them accept the fixtures.
+func newCustomer(n int) *domain.Customer {
+	return &domain.Customer{
+		Nome:     fmt.Sprintf("Customer %d", n),
+		Email:    fmt.Sprintf("customer%d@example.com", n),
+		Document: cpfs[(n-1)%len(cpfs)],
+		Status:   domain.StatusActive,
+	}
+}
+
+var cpfs = []string{"52998224725", "11144477735", "39053344705", "71428793860", "86288366757"}
+
+func expect(err error, want *domain.Error) error {
+	if !errors.Is(err, want) {
+		return fmt.Errorf("error = %v, want %s", err, want.Code)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/repository/models"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/repository/models/mappers"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type sagaRepository struct {
+	db *gorm.DB
+}
+
+var _ SagaRepository = (*sagaRepository)(nil)
+
+func NewSagaRepository(db *gorm.DB) SagaRepository {
+	return &sagaRepository{db: db}
+}
+
+func (r *sagaRepository) Begin(ctx context.Context, step *domain.SagaStep) (*domain.SagaStep, error) {
+	m := mappers.SagaStepFromDomain(step)
+	m.Status = string(domain.SagaStatusPending)
+	if err := conn(ctx, r.db).Create(m).Error; err != nil {
+		return nil, err
+	}
+	return mappers.SagaStepToDomain(m), nil
+}
+
+func (r *sagaRepository) SetStatus(ctx context.Context, id uint, status domain.SagaStatus, cause error) error {
+	updates := map[string]any{"status": string(status)}
+	if cause != nil {
+		updates["error"] = cause.Error()
+	}
+
+	return conn(ctx, r.db).
+		Model(&models.SagaStep{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+// ClaimPending locks pending steps created before createdBefore and hides
+// them from other callers for the lease, like the outbox relay claims
+// events. A step whose worker dies is claimed again once the lease expires.
+func (r *sagaRepository) ClaimPending(ctx context.Context, createdBefore time.Time, limit int, lease time.Duration) ([]domain.SagaStep, error) {
+	var ms []models.SagaStep
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND created_at < ?", string(domain.SagaStatusPending), createdBefore).
+			Where("claimed_until IS NULL OR claimed_until <= ?", now).
+			Order("id").
+			Limit(limit).
+			Find(&ms).Error; err != nil {
+			return err
+		}
+
+		if len(ms) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(ms))
+		for _, m := range ms {
+			ids = append(ids, m.ID)
+		}
+
+		return tx.Model(&models.SagaStep{}).
+			Where("id IN ?", ids).
+			Update("claimed_until", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]domain.SagaStep, 0, len(ms))
+	for _, m := range ms {
+		steps = append(steps, *mappers.SagaStepToDomain(&m))
+	}
+	return steps, nil
+}
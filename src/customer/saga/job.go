@@ -0,0 +1,81 @@
+// Package saga schedules the resumption of customer saga steps left
+// pending by a crash or by a payment call with an unknown outcome.
+package saga
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasteiro11/PogCore/pkg/logger"
+)
+
+const defaultInterval = time.Minute
+
+type (
+	JobOpt func(*Job)
+
+	// Leadership tells whether this replica is the one that should run
+	// singleton work; leader.Elector implements it.
+	Leadership interface {
+		IsLeader() bool
+	}
+
+	// Resumer is the part of the customer service the job drives.
+	Resumer interface {
+		ResumePendingSagas(ctx context.Context) error
+	}
+
+	// Job resumes pending saga steps on a schedule, only on the replica
+	// holding leadership. Steps are claimed with a lease, so a run that
+	// outlives its leadership never works on the same step as the new
+	// leader.
+	Job struct {
+		resumer    Resumer
+		leadership Leadership
+		interval   time.Duration
+	}
+)
+
+func WithInterval(d time.Duration) JobOpt {
+	return func(j *Job) {
+		if d > 0 {
+			j.interval = d
+		}
+	}
+}
+
+func NewJob(resumer Resumer, leadership Leadership, opts ...JobOpt) *Job {
+	j := &Job{
+		resumer:    resumer,
+		leadership: leadership,
+		interval:   defaultInterval,
+	}
+
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	return j
+}
+
+// Run resumes pending steps every interval until ctx is cancelled.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !j.leadership.IsLeader() {
+			continue
+		}
+
+		if err := j.resumer.ResumePendingSagas(ctx); err != nil && ctx.Err() == nil {
+			logger.Of(ctx).Errorf("[saga.Job] ResumePendingSagas() returned error: %+v\n", err)
+		}
+	}
+}
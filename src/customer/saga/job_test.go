@@ -0,0 +1,38 @@
+package saga
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type leadership bool
+
+func (l leadership) IsLeader() bool {
+	return bool(l)
+}
+
+type countingResumer struct {
+	runs atomic.Int32
+}
+
+func (r *countingResumer) ResumePendingSagas(context.Context) error {
+	r.runs.Add(1)
+	return nil
+}
+
+func TestJobRunsOnlyOnTheLeader(t *testing.T) {
+	for _, leader := range []bool{true, false} {
+		resumer := &countingResumer{}
+		job := NewJob(resumer, leadership(leader), WithInterval(5*time.Millisecond))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		job.Run(ctx)
+		cancel()
+
+		if ran := resumer.runs.Load() > 0; ran != leader {
+			t.Errorf("leader %v: resumed %d times", leader, resumer.runs.Load())
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth/authtest"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+)
+
+// withAuth points f at an auth service knowing user 10, Ana. The returned
+// func stops it, to simulate an outage.
+func withAuth(t *testing.T, f *fixture) (stop func()) {
+	t.Helper()
+
+	srv := authtest.NewServer()
+	srv.AddUser(10, "Ana@Example.com", "529.982.247-25")
+
+	client, stop, err := srv.Start()
+	if err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	t.Cleanup(stop)
+
+	f.svc.authClient = client
+	return stop
+}
+
+func TestCreateVerifiesUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   uint64
+		email    string
+		document string
+		authDown bool
+		wantErr  error
+	}{
+		{name: "matching user", userID: 10, email: "ana@example.com", document: "52998224725"},
+		{name: "matching user without a document", userID: 10, email: "ana@example.com"},
+		{name: "other email", userID: 10, email: "bia@example.com", document: "52998224725", wantErr: domain.ErrAuthUserMismatch},
+		{name: "other document", userID: 10, email: "ana@example.com", document: "11144477735", wantErr: domain.ErrAuthUserMismatch},
+		{name: "unknown user", userID: 11, email: "ana@example.com", wantErr: domain.ErrAuthUserNotFound},
+		{name: "user id out of range", userID: math.MaxInt32 + 1, email: "ana@example.com", wantErr: domain.ErrAuthUserNotFound},
+		{name: "auth is down", userID: 10, email: "ana@example.com", authDown: true, wantErr: domain.ErrDependencyUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			stop := withAuth(t, f)
+			if tt.authDown {
+				stop()
+			}
+
+			c := &domain.Customer{Nome: "Ana", Email: tt.email, Document: tt.document, UserID: tt.userID}
+			created, err := f.svc.Create(context.Background(), c)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Create() returned %v, want %v", err, tt.wantErr)
+				}
+				if f.payments.creates != 0 {
+					t.Error("a customer refused by verification got a balance")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create() returned error: %v", err)
+			}
+			if created.UserID != tt.userID {
+				t.Errorf("created customer linked to %d, want %d", created.UserID, tt.userID)
+			}
+		})
+	}
+}
+
+func TestGetByUserLinksByDocument(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture()
+	withAuth(t, f)
+
+	stored, err := f.repo.Create(ctx, newCustomer())
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+
+	linked, err := f.svc.GetByUser(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetByUser() returned error: %v", err)
+	}
+	if linked.ID != stored.ID || linked.UserID != 10 {
+		t.Errorf("GetByUser() returned %+v, want customer %d linked to user 10", linked, stored.ID)
+	}
+
+	if _, err := f.svc.GetByUser(ctx, 11); !errors.Is(err, domain.ErrAuthUserNotFound) {
+		t.Errorf("GetByUser() of an unknown user returned %v, want ErrAuthUserNotFound", err)
+	}
+}
+
+func TestWritesVerifyLinkedUser(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name     string
+		userID   uint64
+		update   *domain.Customer
+		patch    domain.CustomerPatch
+		authDown bool
+		wantErr  error
+	}{
+		{name: "patch the name", userID: 10, patch: domain.CustomerPatch{Nome: ptr("Ana Maria")}, authDown: true},
+		{name: "patch the email case", userID: 10, patch: domain.CustomerPatch{Email: ptr("ANA@example.com")}, authDown: true},
+		{name: "patch another email", userID: 10, patch: domain.CustomerPatch{Email: ptr("bia@example.com")}, wantErr: domain.ErrAuthUserMismatch},
+		{name: "patch another document", userID: 10, patch: domain.CustomerPatch{Document: ptr("11144477735")}, wantErr: domain.ErrAuthUserMismatch},
+		{name: "patch clears the document", userID: 10, patch: domain.CustomerPatch{Document: ptr("")}},
+		{name: "patch while auth is down", userID: 10, patch: domain.CustomerPatch{Email: ptr("bia@example.com")}, authDown: true, wantErr: domain.ErrDependencyUnavailable},
+		{name: "patch an unlinked customer", patch: domain.CustomerPatch{Email: ptr("bia@example.com")}, authDown: true},
+		{name: "update keeping email and document", userID: 10, update: &domain.Customer{Nome: "Ana Maria", Email: "ana@example.com"}, authDown: true},
+		{name: "update to another email", userID: 10, update: &domain.Customer{Nome: "Ana", Email: "bia@example.com"}, wantErr: domain.ErrAuthUserMismatch},
+		{name: "update to another document", userID: 10, update: &domain.Customer{Nome: "Ana", Email: "ana@example.com", Document: "11144477735"}, wantErr: domain.ErrAuthUserMismatch},
+		{name: "update an unlinked customer", update: &domain.Customer{Nome: "Bia", Email: "bia@example.com"}, authDown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newFixture()
+			stop := withAuth(t, f)
+			if tt.authDown {
+				stop()
+			}
+
+			c := newCustomer()
+			c.UserID = tt.userID
+			stored, err := f.repo.Create(ctx, c)
+			if err != nil {
+				t.Fatalf("Create() returned error: %v", err)
+			}
+
+			if tt.update != nil {
+				tt.update.ID = stored.ID
+				_, err = f.svc.Update(ctx, tt.update)
+			} else {
+				_, err = f.svc.Patch(ctx, stored.ID, tt.patch)
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("returned error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("returned %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,8 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	pbPaymentClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/payment"
+	pbUserClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/user"
+	"github.com/rasteiro11/MCABankCustomer/pkg/validator"
 	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
 	"github.com/rasteiro11/MCABankCustomer/src/customer/repository"
 	"github.com/rasteiro11/PogCore/pkg/logger"
@@ -13,27 +20,90 @@ import (
 
 var tracer = otel.Tracer("customer-service")
 
-type customerService struct {
-	repo          repository.CustomerRepository
-	paymentClient pbPaymentClient.BalanceServiceClient
+const (
+	// purgeBatchSize bounds how many rows a single purge transaction removes.
+	purgeBatchSize = 500
+
+	// sagaBatchSize steps are claimed at a time, for sagaLease, which must
+	// cover resuming all of them against a slow payment service.
+	sagaBatchSize          = 20
+	sagaLease              = 5 * time.Minute
+	defaultSagaGracePeriod = 5 * time.Minute
+)
+
+type (
+	ServiceOpt      func(*customerService)
+	customerService struct {
+		repo              repository.CustomerRepository
+		sagaRepo          repository.SagaRepository
+		paymentClient     pbPaymentClient.BalanceServiceClient
+		authClient        pbUserClient.AuthServiceClient
+		documentValidator validator.DocumentValidator
+		purgeRetention    time.Duration
+		sagaGracePeriod   time.Duration
+		now               func() time.Time
+	}
+)
+
+// WithPurgeRetention sets how long soft-deleted customers stay restorable.
+// Purge never removes customers deleted more recently than this.
+func WithPurgeRetention(d time.Duration) ServiceOpt {
+	return func(s *customerService) {
+		s.purgeRetention = d
+	}
+}
+
+// WithSagaGracePeriod sets how old a pending saga step must be before
+// ResumePendingSagas picks it up. Younger steps may still belong to a
+// request in flight.
+func WithSagaGracePeriod(d time.Duration) ServiceOpt {
+	return func(s *customerService) {
+		if d > 0 {
+			s.sagaGracePeriod = d
+		}
+	}
 }
 
-func NewCustomerService(repo repository.CustomerRepository, paymentClient pbPaymentClient.BalanceServiceClient) CustomerService {
-	return &customerService{repo: repo, paymentClient: paymentClient}
+func NewCustomerService(
+	repo repository.CustomerRepository,
+	sagaRepo repository.SagaRepository,
+	paymentClient pbPaymentClient.BalanceServiceClient,
+	authClient pbUserClient.AuthServiceClient,
+	opts ...ServiceOpt,
+) CustomerService {
+	s := &customerService{
+		repo:              repo,
+		sagaRepo:          sagaRepo,
+		paymentClient:     paymentClient,
+		authClient:        authClient,
+		documentValidator: validator.NewDocumentValidator(),
+		purgeRetention:    domain.DefaultPurgeRetention,
+		sagaGracePeriod:   defaultSagaGracePeriod,
+		now:               time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
-func (s *customerService) GetAll(ctx context.Context) ([]domain.Customer, error) {
+func (s *customerService) GetAll(ctx context.Context, q domain.CustomerQuery) (*domain.CustomerPage, error) {
 	ctx, span := tracer.Start(ctx, "GetAll")
 	defer span.End()
 
-	customers, err := s.repo.FindAll(ctx)
+	page, err := s.repo.FindAll(ctx, q)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
 
-	span.SetAttributes(attribute.Int("customer.count", len(customers)))
-	return customers, nil
+	span.SetAttributes(
+		attribute.Int("customer.count", len(page.Items)),
+		attribute.Int64("customer.total", page.Total),
+	)
+	return page, nil
 }
 
 func (s *customerService) GetByID(ctx context.Context, id uint) (*domain.Customer, error) {
@@ -49,28 +119,137 @@ func (s *customerService) GetByID(ctx context.Context, id uint) (*domain.Custome
 	return m, nil
 }
 
+// GetByDocument looks a live customer up by its normalized CPF or CNPJ.
+func (s *customerService) GetByDocument(ctx context.Context, document string) (*domain.Customer, error) {
+	ctx, span := tracer.Start(ctx, "GetByDocument")
+	defer span.End()
+
+	m, err := s.repo.FindByDocument(ctx, document)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return m, nil
+}
+
+// GetByUser returns the customer of an auth user. A customer registered
+// before it was linked is found through the auth user's document and linked
+// on the way, provided its email matches too.
+func (s *customerService) GetByUser(ctx context.Context, userID uint64) (*domain.Customer, error) {
+	ctx, span := tracer.Start(ctx, "GetByUser")
+	defer span.End()
+	span.SetAttributes(attribute.Int64("customer.user_id", int64(userID)))
+
+	m, err := s.repo.FindByUserID(ctx, userID)
+	if err == nil {
+		return m, nil
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	document, err := s.documentValidator.Normalize(user.GetDocument())
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+
+	m, err = s.repo.FindByDocument(ctx, document)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if m.UserID != 0 || !strings.EqualFold(m.Email, user.GetEmail()) {
+		return nil, domain.ErrNotFound
+	}
+
+	if m, err = s.repo.LinkUser(ctx, m.ID, userID); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return m, nil
+}
+
 func (s *customerService) Create(ctx context.Context, c *domain.Customer) (*domain.Customer, error) {
 	ctx, span := tracer.Start(ctx, "CreateCustomer")
 	defer span.End()
 
-	m, err := s.repo.CreateWithCallback(ctx, c, func(c *domain.Customer) error {
-		_, subSpan := tracer.Start(ctx, "CreateBalance")
-		defer subSpan.End()
+	var (
+		m    *domain.Customer
+		step *domain.SagaStep
+	)
+
+	if c.UserID != 0 {
+		if err := s.verifyUser(ctx, c); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
 
-		logger.Of(ctx).Infof("Creating balance for customer ID %d", c.ID)
-		if _, err := s.paymentClient.CreateBalance(ctx, &pbPaymentClient.CreateBalanceRequest{
-			CustomerId: uint32(c.ID),
-		}); err != nil {
-			subSpan.RecordError(err)
+	// every customer starts under review until an operator activates it
+	c.Status = domain.StatusPending
+
+	if err := s.repo.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		if m, err = s.repo.Create(ctx, c); err != nil {
 			return err
 		}
-		logger.Of(ctx).Infof("Balance created successfully for customer ID %d", c.ID)
-		return nil
-	})
-	if err != nil {
+
+		step, err = s.sagaRepo.Begin(ctx, &domain.SagaStep{
+			CustomerID: m.ID,
+			Action:     domain.SagaActionCreate,
+			Step:       domain.SagaStepCreateBalance,
+		})
+		return err
+	}); err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
+
+	if err := s.createBalance(ctx, m.ID); err != nil {
+		span.RecordError(err)
+		return s.settleCreate(ctx, m, step, err)
+	}
+
+	s.complete(ctx, step)
+	customersCreated.Add(ctx, 1)
+	return m, nil
+}
+
+// settleCreate decides what a failed CreateBalance means for a committed
+// customer. Only a definite rejection undoes the create. Any other failure
+// may hide a balance that was created after all, so the payment service is
+// asked: an empty balance completes the saga, a funded one predates the
+// customer and is a rejection too, and no answer leaves the step pending
+// for ResumePendingSagas while the customer is returned as created.
+func (s *customerService) settleCreate(ctx context.Context, m *domain.Customer, step *domain.SagaStep, cause error) (*domain.Customer, error) {
+	undo := func(ctx context.Context) error {
+		return s.repo.HardDelete(ctx, m.ID)
+	}
+
+	if rejected(cause) {
+		s.compensate(ctx, step, cause, undo)
+		return nil, cause
+	}
+
+	b, err := s.findBalance(ctx, m.ID)
+	switch {
+	case err != nil || b == nil:
+		logger.Of(ctx).Warnf("[customerService.Create] balance of customer %d is unconfirmed, step %d stays pending: %+v\n", m.ID, step.ID, cause)
+	case b.GetBalance() != 0 || b.GetBlockedBalance() != 0:
+		s.compensate(ctx, step, cause, undo)
+		return nil, cause
+	default:
+		s.complete(ctx, step)
+	}
+
+	customersCreated.Add(ctx, 1)
 	return m, nil
 }
 
@@ -79,6 +258,15 @@ func (s *customerService) Update(ctx context.Context, c *domain.Customer) (*doma
 	defer span.End()
 	span.SetAttributes(attribute.Int("customer.id", int(c.ID)))
 
+	var document *string
+	if c.Document != "" {
+		document = &c.Document
+	}
+	if err := s.verifyLinked(ctx, c.ID, &c.Email, document); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
 	m, err := s.repo.Update(ctx, c)
 	if err != nil {
 		span.RecordError(err)
@@ -87,14 +275,436 @@ func (s *customerService) Update(ctx context.Context, c *domain.Customer) (*doma
 	return m, nil
 }
 
-func (s *customerService) Delete(ctx context.Context, id uint) error {
+func (s *customerService) Patch(ctx context.Context, id uint, patch domain.CustomerPatch) (*domain.Customer, error) {
+	ctx, span := tracer.Start(ctx, "PatchCustomer")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("customer.id", int(id)),
+		attribute.Bool("customer.patch.nome", patch.Nome != nil),
+		attribute.Bool("customer.patch.email", patch.Email != nil),
+	)
+
+	if err := s.verifyLinked(ctx, id, patch.Email, patch.Document); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	m, err := s.repo.Patch(ctx, id, patch)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return m, nil
+}
+
+// Delete soft-deletes a customer whose payment balance holds no funds. The
+// balance is checked before anything changes, so a customer is never
+// deleted, and then brought back, because the check failed. Funds that
+// arrive between the check and the delete show up in the reconciler report
+// as a deleted customer's orphaned balance.
+func (s *customerService) Delete(ctx context.Context, id uint, expectedVersion uint) error {
 	ctx, span := tracer.Start(ctx, "DeleteCustomer")
 	defer span.End()
 	span.SetAttributes(attribute.Int("customer.id", int(id)))
 
-	if err := s.repo.Delete(ctx, id); err != nil {
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
 		span.RecordError(err)
 		return err
 	}
+
+	if err := s.reconcileBalance(ctx, id); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id, expectedVersion); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	customersDeleted.Add(ctx, 1)
 	return nil
 }
+
+func (s *customerService) Restore(ctx context.Context, id uint) (*domain.Customer, error) {
+	ctx, span := tracer.Start(ctx, "RestoreCustomer")
+	defer span.End()
+	span.SetAttributes(attribute.Int("customer.id", int(id)))
+
+	m, err := s.repo.Restore(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return m, nil
+}
+
+// Purge permanently removes customers soft-deleted more than olderThan ago.
+// olderThan is raised to the retention period when shorter.
+func (s *customerService) Purge(ctx context.Context, olderThan time.Duration) (*domain.PurgeResult, error) {
+	ctx, span := tracer.Start(ctx, "PurgeCustomers")
+	defer span.End()
+
+	if olderThan < s.purgeRetention {
+		olderThan = s.purgeRetention
+	}
+
+	result := &domain.PurgeResult{DeletedBefore: time.Now().Add(-olderThan)}
+
+	for {
+		n, err := s.repo.Purge(ctx, result.DeletedBefore, purgeBatchSize)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		result.Purged += n
+		if n < purgeBatchSize {
+			break
+		}
+	}
+
+	span.SetAttributes(attribute.Int64("customer.purged", result.Purged))
+	logger.Of(ctx).Infof("Purged %d customers deleted before %s", result.Purged, result.DeletedBefore.Format(time.RFC3339))
+	return result, nil
+}
+
+// ChangeStatus moves a customer through the status state machine on behalf
+// of changedBy. Closing additionally requires an empty balance.
+func (s *customerService) ChangeStatus(
+	ctx context.Context,
+	id uint,
+	action domain.StatusAction,
+	reason string,
+	changedBy uint64,
+) (*domain.Customer, error) {
+	ctx, span := tracer.Start(ctx, "ChangeCustomerStatus")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("customer.id", int(id)),
+		attribute.String("customer.status.action", string(action)),
+	)
+
+	customer, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	change, err := domain.NewStatusChange(customer, action, reason, changedBy)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if change.To == domain.StatusClosed {
+		if err := s.reconcileBalance(ctx, id); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+
+	m, err := s.repo.ChangeStatus(ctx, change)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.String("customer.status.from", string(change.From)),
+		attribute.String("customer.status.to", string(change.To)),
+	)
+
+	if change.To == domain.StatusBlocked {
+		customersBlocked.Add(ctx, 1)
+	}
+	return m, nil
+}
+
+func (s *customerService) StatusHistory(ctx context.Context, id uint) ([]domain.StatusChange, error) {
+	ctx, span := tracer.Start(ctx, "StatusHistory")
+	defer span.End()
+	span.SetAttributes(attribute.Int("customer.id", int(id)))
+
+	changes, err := s.repo.StatusHistory(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetBalances fetches the balances of the given customers in a single
+// payment service call. Customers without a balance are absent from the map.
+func (s *customerService) GetBalances(ctx context.Context, ids []uint) (map[uint]domain.Balance, error) {
+	ctx, span := tracer.Start(ctx, "GetBalances")
+	defer span.End()
+	span.SetAttributes(attribute.Int("customer.count", len(ids)))
+
+	balances := make(map[uint]domain.Balance, len(ids))
+	if len(ids) == 0 {
+		return balances, nil
+	}
+
+	customerIDs := make([]uint32, 0, len(ids))
+	for _, id := range ids {
+		customerIDs = append(customerIDs, uint32(id))
+	}
+
+	res, err := s.paymentClient.GetBalances(ctx, &pbPaymentClient.GetBalancesRequest{
+		CustomerIds: customerIDs,
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, paymentError(err)
+	}
+
+	for _, b := range res.GetBalances() {
+		balances[uint(b.GetCustomerId())] = domain.Balance{
+			CustomerID:     uint(b.GetCustomerId()),
+			Balance:        b.GetBalance(),
+			BlockedBalance: b.GetBlockedBalance(),
+		}
+	}
+
+	return balances, nil
+}
+
+// ResyncBalance creates the payment balance of a live customer when the
+// payment service has none, and reports whether it had to. Customers that
+// already have a balance are left alone, so it is safe to repeat.
+func (s *customerService) ResyncBalance(ctx context.Context, id uint) (bool, error) {
+	ctx, span := tracer.Start(ctx, "ResyncBalance")
+	defer span.End()
+	span.SetAttributes(attribute.Int("customer.id", int(id)))
+
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		span.RecordError(err)
+		return false, err
+	}
+
+	created, err := s.ensureBalance(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return false, err
+	}
+
+	span.SetAttributes(attribute.Bool("customer.balance.created", created))
+	return created, nil
+}
+
+// ResumePendingSagas finishes remote steps left pending by a crash between
+// the local commit and the payment call, or by a payment call with an
+// unknown outcome. Only steps older than the grace period are taken, each
+// claimed for a lease so concurrent runs never resume the same one. A step
+// that fails again stays pending for a later run, unless the payment
+// service definitely rejected it: that step is marked failed for manual
+// follow-up and the customer is kept.
+func (s *customerService) ResumePendingSagas(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "ResumePendingSagas")
+	defer span.End()
+
+	for {
+		steps, err := s.sagaRepo.ClaimPending(ctx, s.now().Add(-s.sagaGracePeriod), sagaBatchSize, sagaLease)
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+		if len(steps) == 0 {
+			return nil
+		}
+
+		for i := range steps {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			s.resume(ctx, &steps[i])
+		}
+	}
+}
+
+func (s *customerService) resume(ctx context.Context, step *domain.SagaStep) {
+	if step.Step != domain.SagaStepCreateBalance {
+		s.fail(ctx, step, fmt.Errorf("unknown saga step %q", step.Step))
+		return
+	}
+
+	_, err := s.ensureBalance(ctx, step.CustomerID)
+	switch {
+	case err == nil:
+		s.complete(ctx, step)
+	case rejected(err):
+		s.fail(ctx, step, err)
+	default:
+		logger.Of(ctx).Errorf("[customerService.ResumePendingSagas] step %d (%s) returned error: %+v\n", step.ID, step.Step, err)
+	}
+}
+
+// verifyUser checks that the email and document of a customer being linked
+// to an auth user match what the auth service holds for that user. A
+// customer without a document is checked on the email alone.
+func (s *customerService) verifyUser(ctx context.Context, c *domain.Customer) error {
+	user, err := s.getUser(ctx, c.UserID)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(user.GetEmail(), c.Email) {
+		return domain.ErrAuthUserMismatch
+	}
+
+	if c.Document == "" {
+		return nil
+	}
+
+	document, err := s.documentValidator.Normalize(user.GetDocument())
+	if err != nil || document != c.Document {
+		return domain.ErrAuthUserMismatch
+	}
+
+	return nil
+}
+
+// verifyLinked runs verifyUser again when a write changes the email or
+// document of a customer linked to an auth user, so an owner cannot move
+// their record away from the identity it was linked with. Nil leaves the
+// field as stored.
+func (s *customerService) verifyLinked(ctx context.Context, id uint, email, document *string) error {
+	if email == nil && document == nil {
+		return nil
+	}
+
+	stored, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if stored.UserID == 0 {
+		return nil
+	}
+
+	changed := *stored
+	if email != nil {
+		changed.Email = *email
+	}
+	if document != nil {
+		changed.Document = *document
+	}
+	if strings.EqualFold(changed.Email, stored.Email) && changed.Document == stored.Document {
+		return nil
+	}
+
+	return s.verifyUser(ctx, &changed)
+}
+
+func (s *customerService) getUser(ctx context.Context, userID uint64) (*pbUserClient.GetUserResponse, error) {
+	ctx, span := tracer.Start(ctx, "GetUser")
+	defer span.End()
+
+	// auth user ids are int32 on the wire
+	if userID > math.MaxInt32 {
+		return nil, domain.ErrAuthUserNotFound
+	}
+
+	user, err := s.authClient.GetUser(ctx, &pbUserClient.GetUserRequest{Id: int32(userID)})
+	if err != nil {
+		span.RecordError(err)
+		return nil, authError(err)
+	}
+	return user, nil
+}
+
+func (s *customerService) createBalance(ctx context.Context, customerID uint) error {
+	ctx, span := tracer.Start(ctx, "CreateBalance")
+	defer span.End()
+
+	logger.Of(ctx).Infof("Creating balance for customer ID %d", customerID)
+	if _, err := s.paymentClient.CreateBalance(ctx, &pbPaymentClient.CreateBalanceRequest{
+		CustomerId: uint32(customerID),
+	}); err != nil {
+		span.RecordError(err)
+		return paymentError(err)
+	}
+	logger.Of(ctx).Infof("Balance created successfully for customer ID %d", customerID)
+	return nil
+}
+
+// ensureBalance creates the balance only if the payment service does not
+// already have one, so retrying a create step is safe. It reports whether
+// the balance was created.
+func (s *customerService) ensureBalance(ctx context.Context, customerID uint) (bool, error) {
+	b, err := s.findBalance(ctx, customerID)
+	if err != nil || b != nil {
+		return false, err
+	}
+
+	if err := s.createBalance(ctx, customerID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// reconcileBalance checks with the payment service that the balance of a
+// deleted or closing customer holds no funds.
+func (s *customerService) reconcileBalance(ctx context.Context, customerID uint) error {
+	ctx, span := tracer.Start(ctx, "ReconcileBalance")
+	defer span.End()
+
+	b, err := s.findBalance(ctx, customerID)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if b != nil && (b.GetBalance() != 0 || b.GetBlockedBalance() != 0) {
+		return domain.ErrBalanceNotEmpty
+	}
+	return nil
+}
+
+// findBalance returns the balance of one customer, nil when the payment
+// service has none.
+func (s *customerService) findBalance(ctx context.Context, customerID uint) (*pbPaymentClient.Balance, error) {
+	res, err := s.paymentClient.GetBalances(ctx, &pbPaymentClient.GetBalancesRequest{
+		CustomerIds: []uint32{uint32(customerID)},
+	})
+	if err != nil {
+		return nil, paymentError(err)
+	}
+
+	for _, b := range res.GetBalances() {
+		if b.GetCustomerId() == uint32(customerID) {
+			return b, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *customerService) complete(ctx context.Context, step *domain.SagaStep) {
+	if err := s.sagaRepo.SetStatus(ctx, step.ID, domain.SagaStatusCompleted, nil); err != nil {
+		logger.Of(ctx).Errorf("[customerService.complete] sagaRepo.SetStatus() returned error: %+v\n", err)
+	}
+}
+
+// fail gives up on a step that can never succeed, keeping its cause for
+// whoever follows it up.
+func (s *customerService) fail(ctx context.Context, step *domain.SagaStep, cause error) {
+	logger.Of(ctx).Errorf("[customerService.fail] step %d (%s) failed: %+v\n", step.ID, step.Step, cause)
+	if err := s.sagaRepo.SetStatus(ctx, step.ID, domain.SagaStatusFailed, cause); err != nil {
+		logger.Of(ctx).Errorf("[customerService.fail] sagaRepo.SetStatus() returned error: %+v\n", err)
+	}
+}
+
+// compensate undoes the local side of a saga after its remote step failed.
+// A failed compensation is recorded on the step for manual follow-up.
+func (s *customerService) compensate(ctx context.Context, step *domain.SagaStep, cause error, undo func(ctx context.Context) error) {
+	status := domain.SagaStatusCompensated
+
+	if err := s.repo.Transaction(ctx, undo); err != nil {
+		logger.Of(ctx).Errorf("[customerService.compensate] compensation for step %d returned error: %+v\n", step.ID, err)
+		status, cause = domain.SagaStatusFailed, err
+	}
+
+	if err := s.sagaRepo.SetStatus(ctx, step.ID, status, cause); err != nil {
+		logger.Of(ctx).Errorf("[customerService.compensate] sagaRepo.SetStatus() returned error: %+v\n", err)
+	}
+}
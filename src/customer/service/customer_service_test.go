@@ -0,0 +1,490 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	pbPaymentClient "github.com/rasteiro11/MCABankCustomer/gen/proto/go/payment"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/repository"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakePayment is a BalanceServiceClient whose calls fail as scripted. A
+// CreateBalance that fails with landed set still stores the balance, like
+// a call that timed out after the payment service handled it.
+type fakePayment struct {
+	mu         sync.Mutex
+	balances   map[uint32]*pbPaymentClient.Balance
+	getErrs    []error
+	createErrs []error
+	landed     bool
+	creates    int
+}
+
+var _ pbPaymentClient.BalanceServiceClient = (*fakePayment)(nil)
+
+func newFakePayment() *fakePayment {
+	return &fakePayment{balances: make(map[uint32]*pbPaymentClient.Balance)}
+}
+
+func (f *fakePayment) setBalance(customerID uint, balance float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balances[uint32(customerID)] = &pbPaymentClient.Balance{CustomerId: uint32(customerID), Balance: balance}
+}
+
+func (f *fakePayment) hasBalance(customerID uint) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.balances[uint32(customerID)]
+	return ok
+}
+
+func (f *fakePayment) GetBalances(_ context.Context, in *pbPaymentClient.GetBalancesRequest, _ ...grpc.CallOption) (*pbPaymentClient.GetBalancesResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := pop(&f.getErrs); err != nil {
+		return nil, err
+	}
+
+	res := &pbPaymentClient.GetBalancesResponse{}
+	for _, id := range in.GetCustomerIds() {
+		if b, ok := f.balances[id]; ok {
+			res.Balances = append(res.Balances, b)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakePayment) CreateBalance(_ context.Context, in *pbPaymentClient.CreateBalanceRequest, _ ...grpc.CallOption) (*pbPaymentClient.CreateBalanceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.creates++
+
+	if err := pop(&f.createErrs); err != nil {
+		if f.landed {
+			f.balances[in.GetCustomerId()] = &pbPaymentClient.Balance{CustomerId: in.GetCustomerId()}
+		}
+		return nil, err
+	}
+
+	if _, ok := f.balances[in.GetCustomerId()]; ok {
+		return nil, status.Error(codes.AlreadyExists, "balance already exists")
+	}
+
+	b := &pbPaymentClient.Balance{CustomerId: in.GetCustomerId()}
+	f.balances[in.GetCustomerId()] = b
+	return &pbPaymentClient.CreateBalanceResponse{Balance: b}, nil
+}
+
+func pop(errs *[]error) error {
+	if len(*errs) == 0 {
+		return nil
+	}
+	err := (*errs)[0]
+	*errs = (*errs)[1:]
+	return err
+}
+
+// recordingSagas remembers the last status set on every step.
+type recordingSagas struct {
+	repository.SagaRepository
+
+	mu       sync.Mutex
+	statuses map[uint]domain.SagaStatus
+}
+
+func (r *recordingSagas) Begin(ctx context.Context, step *domain.SagaStep) (*domain.SagaStep, error) {
+	begun, err := r.SagaRepository.Begin(ctx, step)
+	if err == nil {
+		r.mu.Lock()
+		r.statuses[begun.ID] = domain.SagaStatusPending
+		r.mu.Unlock()
+	}
+	return begun, err
+}
+
+func (r *recordingSagas) SetStatus(ctx context.Context, id uint, status domain.SagaStatus, cause error) error {
+	r.mu.Lock()
+	r.statuses[id] = status
+	r.mu.Unlock()
+	return r.SagaRepository.SetStatus(ctx, id, status, cause)
+}
+
+func (r *recordingSagas) only(t *testing.T) domain.SagaStatus {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.statuses) != 1 {
+		t.Fatalf("got %d saga steps, want 1", len(r.statuses))
+	}
+	for _, status := range r.statuses {
+		return status
+	}
+	return ""
+}
+
+type fixture struct {
+	svc      *customerService
+	repo     repository.CustomerRepository
+	sagas    *recordingSagas
+	payments *fakePayment
+}
+
+func newFixture() *fixture {
+	db := repository.NewMemoryDB()
+	f := &fixture{
+		repo:     repository.NewMemoryCustomerRepository(db),
+		sagas:    &recordingSagas{SagaRepository: repository.NewMemorySagaRepository(db), statuses: map[uint]domain.SagaStatus{}},
+		payments: newFakePayment(),
+	}
+	f.svc = NewCustomerService(f.repo, f.sagas, f.payments, nil).(*customerService)
+	return f
+}
+
+func newCustomer() *domain.Customer {
+	return &domain.Customer{Nome: "Ana", Email: "ana@example.com", Document: "52998224725"}
+}
+
+func TestCreateSettlesFailedBalance(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "payment down")
+
+	tests := []struct {
+		name       string
+		createErrs []error
+		getErrs    []error
+		landed     bool
+		funded     bool
+		wantErr    bool
+		wantStatus domain.SagaStatus
+	}{
+		{name: "created", wantStatus: domain.SagaStatusCompleted},
+		{name: "invalid argument is compensated", createErrs: []error{status.Error(codes.InvalidArgument, "bad id")}, wantErr: true, wantStatus: domain.SagaStatusCompensated},
+		{name: "deadline exceeded after the balance landed completes", createErrs: []error{status.Error(codes.DeadlineExceeded, "slow")}, landed: true, wantStatus: domain.SagaStatusCompleted},
+		{name: "deadline exceeded without a balance stays pending", createErrs: []error{status.Error(codes.DeadlineExceeded, "slow")}, wantStatus: domain.SagaStatusPending},
+		{name: "unknown error stays pending", createErrs: []error{errors.New("connection reset")}, wantStatus: domain.SagaStatusPending},
+		{name: "unconfirmable outage stays pending", createErrs: []error{unavailable}, getErrs: []error{unavailable}, wantStatus: domain.SagaStatusPending},
+		{name: "already exists with an empty balance completes", createErrs: []error{status.Error(codes.AlreadyExists, "exists")}, landed: true, wantStatus: domain.SagaStatusCompleted},
+		{name: "already exists with a funded balance is compensated", createErrs: []error{status.Error(codes.AlreadyExists, "exists")}, funded: true, wantErr: true, wantStatus: domain.SagaStatusCompensated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			f.payments.createErrs = tt.createErrs
+			f.payments.getErrs = tt.getErrs
+			f.payments.landed = tt.landed
+			if tt.funded {
+				// the first customer gets ID 1
+				f.payments.setBalance(1, 50)
+			}
+
+			created, err := f.svc.Create(context.Background(), newCustomer())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Create() returned error %v, want error %v", err, tt.wantErr)
+			}
+			if got := f.sagas.only(t); got != tt.wantStatus {
+				t.Errorf("saga step is %s, want %s", got, tt.wantStatus)
+			}
+
+			_, findErr := f.repo.FindByID(context.Background(), 1)
+			if tt.wantErr {
+				if !errors.Is(findErr, domain.ErrNotFound) {
+					t.Errorf("compensated customer is still there: %v", findErr)
+				}
+				return
+			}
+			if created == nil || findErr != nil {
+				t.Errorf("customer was not kept: %v", findErr)
+			}
+		})
+	}
+}
+
+func TestDeleteChecksBalanceFirst(t *testing.T) {
+	tests := []struct {
+		name       string
+		hasBalance bool
+		balance    float64
+		getErrs    []error
+		id         uint
+		wantErr    *domain.Error
+	}{
+		{name: "no balance", id: 1},
+		{name: "empty balance", hasBalance: true, id: 1},
+		{name: "funded balance", hasBalance: true, balance: 10, id: 1, wantErr: domain.ErrBalanceNotEmpty},
+		{name: "payment unavailable", getErrs: []error{status.Error(codes.Unavailable, "down")}, id: 1, wantErr: domain.ErrDependencyUnavailable},
+		{name: "unknown customer", id: 2, wantErr: domain.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+			c, err := f.repo.Create(context.Background(), newCustomer())
+			if err != nil {
+				t.Fatalf("repo.Create() returned error: %v", err)
+			}
+			if tt.hasBalance {
+				f.payments.setBalance(c.ID, tt.balance)
+			}
+			f.payments.getErrs = tt.getErrs
+
+			err = f.svc.Delete(context.Background(), tt.id, 0)
+			_, findErr := f.repo.FindByID(context.Background(), c.ID)
+
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Delete() returned error: %v", err)
+				}
+				if !errors.Is(findErr, domain.ErrNotFound) {
+					t.Errorf("customer is still live: %v", findErr)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Delete() returned %v, want %s", err, tt.wantErr.Code)
+			}
+			if findErr != nil {
+				t.Errorf("a refused delete removed the customer: %v", findErr)
+			}
+			if len(f.sagas.statuses) != 0 {
+				t.Errorf("a refused delete left saga steps: %v", f.sagas.statuses)
+			}
+		})
+	}
+}
+
+func TestResumePendingSagas(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "payment down")
+
+	tests := []struct {
+		name        string
+		young       bool
+		landed      bool
+		getErrs     []error
+		createErrs  []error
+		wantStatus  domain.SagaStatus
+		wantCreates int
+	}{
+		{name: "steps inside the grace period are left alone", young: true, wantStatus: domain.SagaStatusPending},
+		{name: "missing balance is created", wantStatus: domain.SagaStatusCompleted, wantCreates: 1},
+		{name: "balance that landed completes the step", landed: true, wantStatus: domain.SagaStatusCompleted},
+		{name: "payment still down stays pending", getErrs: []error{unavailable}, wantStatus: domain.SagaStatusPending},
+		{name: "ambiguous create stays pending", createErrs: []error{status.Error(codes.DeadlineExceeded, "slow")}, wantStatus: domain.SagaStatusPending, wantCreates: 1},
+		{name: "rejected create fails the step", createErrs: []error{status.Error(codes.InvalidArgument, "bad id")}, wantStatus: domain.SagaStatusFailed, wantCreates: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture()
+
+			// leave the create step pending, as an outage during Create does
+			f.payments.createErrs = []error{unavailable}
+			f.payments.getErrs = []error{unavailable}
+			c, err := f.svc.Create(context.Background(), newCustomer())
+			if err != nil {
+				t.Fatalf("Create() returned error: %v", err)
+			}
+			if tt.landed {
+				f.payments.setBalance(c.ID, 0)
+			}
+			f.payments.creates = 0
+			f.payments.getErrs = tt.getErrs
+			f.payments.createErrs = tt.createErrs
+
+			if !tt.young {
+				f.svc.now = func() time.Time { return time.Now().Add(time.Hour) }
+			}
+
+			if err := f.svc.ResumePendingSagas(context.Background()); err != nil {
+				t.Fatalf("ResumePendingSagas() returned error: %v", err)
+			}
+			if got := f.sagas.only(t); got != tt.wantStatus {
+				t.Errorf("saga step is %s, want %s", got, tt.wantStatus)
+			}
+			if f.payments.creates != tt.wantCreates {
+				t.Errorf("CreateBalance called %d times, want %d", f.payments.creates, tt.wantCreates)
+			}
+			if tt.wantStatus == domain.SagaStatusCompleted && !f.payments.hasBalance(c.ID) {
+				t.Error("completed step left no balance")
+			}
+			if _, err := f.repo.FindByID(context.Background(), c.ID); err != nil {
+				t.Errorf("resuming removed the customer: %v", err)
+			}
+		})
+	}
+}
+
+func TestResumeClaimsStepsForALease(t *testing.T) {
+	f := newFixture()
+
+	unavailable := status.Error(codes.Unavailable, "payment down")
+	f.payments.createErrs = []error{unavailable}
+	f.payments.getErrs = []error{unavailable, unavailable}
+	if _, err := f.svc.Create(context.Background(), newCustomer()); err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	f.svc.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+	// the first run fails and keeps the claim; the second finds nothing
+	for run := 0; run < 2; run++ {
+		if err := f.svc.ResumePendingSagas(context.Background()); err != nil {
+			t.Fatalf("ResumePendingSagas() returned error: %v", err)
+		}
+	}
+
+	// a second resume would have found the payment service back and
+	// created the balance
+	if f.payments.creates != 1 || f.payments.hasBalance(1) {
+		t.Errorf("claimed step was resumed again before its lease expired")
+	}
+	if got := f.sagas.only(t); got != domain.SagaStatusPending {
+		t.Errorf("saga step is %s, want pending", got)
+	}
+}
+
+func TestChangeStatus(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "payment down")
+
+	tests := []struct {
+		name    string
+		from    domain.Status
+		action  domain.StatusAction
+		reason  string
+		balance float64
+		getErrs []error
+		want    domain.Status
+		wantErr error
+	}{
+		{name: "activate a pending customer", from: domain.StatusPending, action: domain.StatusActionActivate, want: domain.StatusActive},
+		{name: "block with a reason", from: domain.StatusActive, action: domain.StatusActionBlock, reason: "fraud", want: domain.StatusBlocked},
+		{name: "block without a reason", from: domain.StatusActive, action: domain.StatusActionBlock, reason: "  ", wantErr: domain.ErrReasonRequired},
+		{name: "unblock a blocked customer", from: domain.StatusBlocked, action: domain.StatusActionUnblock, want: domain.StatusActive},
+		{name: "unblock an active customer", from: domain.StatusActive, action: domain.StatusActionUnblock, wantErr: domain.ErrIllegalTransition},
+		{name: "closed is terminal", from: domain.StatusClosed, action: domain.StatusActionActivate, wantErr: domain.ErrIllegalTransition},
+		{name: "close with an empty balance", from: domain.StatusActive, action: domain.StatusActionClose, want: domain.StatusClosed},
+		{name: "close with funds", from: domain.StatusActive, action: domain.StatusActionClose, balance: 10, wantErr: domain.ErrBalanceNotEmpty},
+		{name: "close while payment is down", from: domain.StatusActive, action: domain.StatusActionClose, getErrs: []error{unavailable}, wantErr: domain.ErrDependencyUnavailable},
+		{name: "block does not ask payment", from: domain.StatusActive, action: domain.StatusActionBlock, reason: "fraud", getErrs: []error{unavailable}, want: domain.StatusBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newFixture()
+
+			c := newCustomer()
+			c.Status = tt.from
+			stored, err := f.repo.Create(ctx, c)
+			if err != nil {
+				t.Fatalf("Create() returned error: %v", err)
+			}
+			f.payments.setBalance(stored.ID, tt.balance)
+			f.payments.getErrs = tt.getErrs
+
+			changed, err := f.svc.ChangeStatus(ctx, stored.ID, tt.action, tt.reason, 7)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ChangeStatus() returned %v, want %v", err, tt.wantErr)
+				}
+				if kept, _ := f.repo.FindByID(ctx, stored.ID); kept.Status != tt.from {
+					t.Errorf("status moved to %s on a refused change", kept.Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChangeStatus() returned error: %v", err)
+			}
+			if changed.Status != tt.want {
+				t.Errorf("status is %s, want %s", changed.Status, tt.want)
+			}
+
+			history, err := f.svc.StatusHistory(ctx, stored.ID)
+			if err != nil || len(history) != 1 {
+				t.Fatalf("StatusHistory() returned %d changes, error %v", len(history), err)
+			}
+			if h := history[0]; h.From != tt.from || h.To != tt.want || h.ChangedBy != 7 {
+				t.Errorf("recorded change %+v", h)
+			}
+		})
+	}
+}
+
+func TestResyncBalance(t *testing.T) {
+	tests := []struct {
+		name        string
+		hasBalance  bool
+		createErrs  []error
+		wantCreated bool
+		wantErr     error
+	}{
+		{name: "creates a missing balance", wantCreated: true},
+		{name: "leaves an existing balance", hasBalance: true},
+		{name: "reports a payment outage", createErrs: []error{status.Error(codes.Unavailable, "down")}, wantErr: domain.ErrDependencyUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newFixture()
+
+			stored, err := f.repo.Create(ctx, newCustomer())
+			if err != nil {
+				t.Fatalf("Create() returned error: %v", err)
+			}
+			if tt.hasBalance {
+				f.payments.setBalance(stored.ID, 0)
+			}
+			f.payments.createErrs = tt.createErrs
+
+			created, err := f.svc.ResyncBalance(ctx, stored.ID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ResyncBalance() returned %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || created != tt.wantCreated {
+				t.Fatalf("ResyncBalance() returned %v, error %v, want %v", created, err, tt.wantCreated)
+			}
+			if !f.payments.hasBalance(stored.ID) {
+				t.Error("customer has no balance after a resync")
+			}
+		})
+	}
+
+	if _, err := newFixture().svc.ResyncBalance(context.Background(), 42); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("ResyncBalance() of a missing customer returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetBalances(t *testing.T) {
+	f := newFixture()
+	f.payments.setBalance(1, 10)
+	f.payments.setBalance(3, 0)
+
+	balances, err := f.svc.GetBalances(context.Background(), []uint{1, 2, 3})
+	if err != nil {
+		t.Fatalf("GetBalances() returned error: %v", err)
+	}
+	if len(balances) != 2 || balances[1].Balance != 10 {
+		t.Errorf("GetBalances() = %+v, want customers 1 and 3", balances)
+	}
+	if _, ok := balances[2]; ok {
+		t.Error("a customer without a balance is in the map")
+	}
+
+	f.payments.getErrs = []error{status.Error(codes.Unavailable, "down")}
+	if _, err := f.svc.GetBalances(context.Background(), []uint{1}); !errors.Is(err, domain.ErrDependencyUnavailable) {
+		t.Errorf("GetBalances() returned %v, want ErrDependencyUnavailable", err)
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// paymentError maps a failed payment service call, keeping the gRPC status
+// as the cause. A definite refusal, or a balance that already exists, is
+// ErrPaymentRejected: retrying will not help. Anything else is an outage
+// or an unknown outcome and reported as ErrDependencyUnavailable. Errors
+// the payment client already reports as an outage, like an open circuit,
+// are kept as they are.
+func paymentError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrDependencyUnavailable):
+		return err
+	case rejected(err), status.Code(err) == codes.AlreadyExists:
+		return domain.Wrap(domain.ErrPaymentRejected, err)
+	}
+	return domain.Wrap(domain.ErrDependencyUnavailable, err)
+}
+
+// rejected reports whether a failed payment service call is a definite
+// refusal, known to have had no effect. Timeouts, outages and unknown
+// failures may hide a request that went through before the error.
+func rejected(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange, codes.NotFound,
+		codes.PermissionDenied, codes.Unauthenticated, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+// authError maps a failed AuthService.GetUser call. Only NotFound says
+// anything about the user; every other failure is an outage.
+func authError(err error) error {
+	if status.Code(err) == codes.NotFound {
+		return domain.Wrap(domain.ErrAuthUserNotFound, err)
+	}
+	return domain.Wrap(domain.ErrDependencyUnavailable, err)
+}
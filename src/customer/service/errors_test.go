@@ -0,0 +1,42 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/payment"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPaymentError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "unavailable", err: status.Error(codes.Unavailable, "down"), want: domain.ErrDependencyUnavailable},
+		{name: "deadline exceeded", err: status.Error(codes.DeadlineExceeded, "slow"), want: domain.ErrDependencyUnavailable},
+		{name: "internal", err: status.Error(codes.Internal, "boom"), want: domain.ErrDependencyUnavailable},
+		{name: "not a status", err: errors.New("connection reset"), want: domain.ErrDependencyUnavailable},
+		{name: "open circuit", err: payment.ErrCircuitOpen, want: domain.ErrDependencyUnavailable},
+		{name: "invalid argument", err: status.Error(codes.InvalidArgument, "bad id"), want: domain.ErrPaymentRejected},
+		{name: "failed precondition", err: status.Error(codes.FailedPrecondition, "frozen"), want: domain.ErrPaymentRejected},
+		{name: "not found", err: status.Error(codes.NotFound, "no balance"), want: domain.ErrPaymentRejected},
+		{name: "permission denied", err: status.Error(codes.PermissionDenied, "denied"), want: domain.ErrPaymentRejected},
+		{name: "already exists", err: status.Error(codes.AlreadyExists, "balance exists"), want: domain.ErrPaymentRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := paymentError(tt.err)
+			if !errors.Is(got, tt.want) {
+				t.Fatalf("paymentError() = %v, want %v", got, tt.want)
+			}
+			if status.Code(got) != status.Code(tt.err) {
+				t.Errorf("paymentError() lost the status %v of the cause", status.Code(tt.err))
+			}
+		})
+	}
+}
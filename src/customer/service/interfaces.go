@@ -2,14 +2,25 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
 )
 
 type CustomerService interface {
-	GetAll(ctx context.Context) ([]domain.Customer, error)
+	GetAll(ctx context.Context, q domain.CustomerQuery) (*domain.CustomerPage, error)
 	GetByID(ctx context.Context, id uint) (*domain.Customer, error)
+	GetByDocument(ctx context.Context, document string) (*domain.Customer, error)
+	GetByUser(ctx context.Context, userID uint64) (*domain.Customer, error)
 	Create(ctx context.Context, c *domain.Customer) (*domain.Customer, error)
 	Update(ctx context.Context, c *domain.Customer) (*domain.Customer, error)
-	Delete(ctx context.Context, id uint) error
+	Patch(ctx context.Context, id uint, patch domain.CustomerPatch) (*domain.Customer, error)
+	Delete(ctx context.Context, id uint, expectedVersion uint) error
+	Restore(ctx context.Context, id uint) (*domain.Customer, error)
+	Purge(ctx context.Context, olderThan time.Duration) (*domain.PurgeResult, error)
+	ChangeStatus(ctx context.Context, id uint, action domain.StatusAction, reason string, changedBy uint64) (*domain.Customer, error)
+	StatusHistory(ctx context.Context, id uint) ([]domain.StatusChange, error)
+	GetBalances(ctx context.Context, ids []uint) (map[uint]domain.Balance, error)
+	ResyncBalance(ctx context.Context, id uint) (bool, error)
+	ResumePendingSagas(ctx context.Context) error
 }
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Business counters. They go through the global meter provider, which the
+// process bridges to the Prometheus /metrics endpoint.
+var (
+	meter = otel.Meter("customer-service")
+
+	customersCreated, _ = meter.Int64Counter("customers.created",
+		metric.WithDescription("Customers created, counted once their balance exists"))
+	customersDeleted, _ = meter.Int64Counter("customers.deleted",
+		metric.WithDescription("Customers soft-deleted"))
+	customersBlocked, _ = meter.Int64Counter("customers.blocked",
+		metric.WithDescription("Customers moved to the blocked status"))
+)
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth"
+	"github.com/rasteiro11/MCABankCustomer/pkg/rbac"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+)
+
+// Actions checked by the policy service, as named in the policy file.
+const (
+	ActionList          = "customer.list"
+	ActionRead          = "customer.read"
+	ActionCreate        = "customer.create"
+	ActionUpdate        = "customer.update"
+	ActionPatch         = "customer.patch"
+	ActionDelete        = "customer.delete"
+	ActionRestore       = "customer.restore"
+	ActionPurge         = "customer.purge"
+	ActionChangeStatus  = "customer.change_status"
+	ActionResyncBalance = "customer.resync_balance"
+)
+
+// policyService authorizes every call against an RBAC policy before
+// handing it to the wrapped service. The caller is the session stored on
+// ctx; a customer is owned by the auth user it is linked to.
+type policyService struct {
+	next   CustomerService
+	policy *rbac.Policy
+}
+
+var _ CustomerService = (*policyService)(nil)
+
+func NewPolicyService(next CustomerService, policy *rbac.Policy) CustomerService {
+	return &policyService{next: next, policy: policy}
+}
+
+func (s *policyService) GetAll(ctx context.Context, q domain.CustomerQuery) (*domain.CustomerPage, error) {
+	if err := s.authorize(ctx, ActionList, nil); err != nil {
+		return nil, err
+	}
+	return s.next.GetAll(ctx, q)
+}
+
+func (s *policyService) GetByID(ctx context.Context, id uint) (*domain.Customer, error) {
+	return s.read(ctx, func() (*domain.Customer, error) {
+		return s.next.GetByID(ctx, id)
+	})
+}
+
+func (s *policyService) GetByDocument(ctx context.Context, document string) (*domain.Customer, error) {
+	return s.read(ctx, func() (*domain.Customer, error) {
+		return s.next.GetByDocument(ctx, document)
+	})
+}
+
+func (s *policyService) GetByUser(ctx context.Context, userID uint64) (*domain.Customer, error) {
+	if err := s.authorize(ctx, ActionRead, record(&domain.Customer{UserID: userID})); err != nil {
+		return nil, err
+	}
+	return s.next.GetByUser(ctx, userID)
+}
+
+func (s *policyService) Create(ctx context.Context, c *domain.Customer) (*domain.Customer, error) {
+	if err := s.authorize(ctx, ActionCreate, record(c)); err != nil {
+		return nil, err
+	}
+	return s.next.Create(ctx, c)
+}
+
+func (s *policyService) Update(ctx context.Context, c *domain.Customer) (*domain.Customer, error) {
+	if _, err := s.authorizeStored(ctx, ActionUpdate, s.byID(ctx, c.ID)); err != nil {
+		return nil, err
+	}
+	return s.next.Update(ctx, c)
+}
+
+func (s *policyService) Patch(ctx context.Context, id uint, patch domain.CustomerPatch) (*domain.Customer, error) {
+	if _, err := s.authorizeStored(ctx, ActionPatch, s.byID(ctx, id)); err != nil {
+		return nil, err
+	}
+	return s.next.Patch(ctx, id, patch)
+}
+
+func (s *policyService) Delete(ctx context.Context, id uint, expectedVersion uint) error {
+	if _, err := s.authorizeStored(ctx, ActionDelete, s.byID(ctx, id)); err != nil {
+		return err
+	}
+	return s.next.Delete(ctx, id, expectedVersion)
+}
+
+// Restore cannot be granted on own records: a deleted customer is not
+// visible to GetByID, so there is no owner to check.
+func (s *policyService) Restore(ctx context.Context, id uint) (*domain.Customer, error) {
+	if err := s.authorize(ctx, ActionRestore, nil); err != nil {
+		return nil, err
+	}
+	return s.next.Restore(ctx, id)
+}
+
+func (s *policyService) Purge(ctx context.Context, olderThan time.Duration) (*domain.PurgeResult, error) {
+	if err := s.authorize(ctx, ActionPurge, nil); err != nil {
+		return nil, err
+	}
+	return s.next.Purge(ctx, olderThan)
+}
+
+func (s *policyService) ChangeStatus(
+	ctx context.Context,
+	id uint,
+	action domain.StatusAction,
+	reason string,
+	changedBy uint64,
+) (*domain.Customer, error) {
+	if _, err := s.authorizeStored(ctx, ActionChangeStatus, s.byID(ctx, id)); err != nil {
+		return nil, err
+	}
+	return s.next.ChangeStatus(ctx, id, action, reason, changedBy)
+}
+
+func (s *policyService) StatusHistory(ctx context.Context, id uint) ([]domain.StatusChange, error) {
+	if _, err := s.authorizeStored(ctx, ActionRead, s.byID(ctx, id)); err != nil {
+		return nil, err
+	}
+	return s.next.StatusHistory(ctx, id)
+}
+
+// GetBalances requires read access to every customer asked for; a balance
+// reveals as much as the customer record does.
+func (s *policyService) GetBalances(ctx context.Context, ids []uint) (map[uint]domain.Balance, error) {
+	for _, id := range ids {
+		if _, err := s.authorizeStored(ctx, ActionRead, s.byID(ctx, id)); err != nil {
+			return nil, err
+		}
+	}
+	return s.next.GetBalances(ctx, ids)
+}
+
+func (s *policyService) ResyncBalance(ctx context.Context, id uint) (bool, error) {
+	if _, err := s.authorizeStored(ctx, ActionResyncBalance, s.byID(ctx, id)); err != nil {
+		return false, err
+	}
+	return s.next.ResyncBalance(ctx, id)
+}
+
+func (s *policyService) ResumePendingSagas(ctx context.Context) error {
+	return s.next.ResumePendingSagas(ctx)
+}
+
+// read authorizes by role first, so callers allowed on every customer
+// only pay for one lookup, and callers limited to their own records load
+// the customer once for the ownership check.
+func (s *policyService) read(ctx context.Context, load func() (*domain.Customer, error)) (*domain.Customer, error) {
+	c, err := s.authorizeStored(ctx, ActionRead, load)
+	if err != nil || c != nil {
+		return c, err
+	}
+	return load()
+}
+
+// authorizeStored checks action on a stored customer and returns it when
+// the ownership check had to load it. Callers only granted their own
+// records are denied with the reason on other customers.
+func (s *policyService) authorizeStored(ctx context.Context, action string, load func() (*domain.Customer, error)) (*domain.Customer, error) {
+	var loaded *domain.Customer
+	err := s.authorize(ctx, action, func() (*domain.Customer, error) {
+		c, err := load()
+		loaded = c
+		return c, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// authorize checks action for the session user. load returns the customer
+// whose owner decides own-only grants; nil means the action has no owner.
+func (s *policyService) authorize(ctx context.Context, action string, load func() (*domain.Customer, error)) error {
+	session, err := auth.SessionFromContext(ctx)
+	if err != nil {
+		return domain.Forbidden(rbac.ErrNoAuthenticated.Error())
+	}
+
+	decision, err := s.policy.Authorize(session.UserID, action, func() (bool, error) {
+		if load == nil {
+			return false, nil
+		}
+
+		c, err := load()
+		if err != nil {
+			return false, err
+		}
+		return c.UserID != 0 && c.UserID == session.UserID, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !decision.Allowed {
+		return domain.Forbidden(decision.Reason)
+	}
+	return nil
+}
+
+func (s *policyService) byID(ctx context.Context, id uint) func() (*domain.Customer, error) {
+	return func() (*domain.Customer, error) {
+		return s.next.GetByID(ctx, id)
+	}
+}
+
+func record(c *domain.Customer) func() (*domain.Customer, error) {
+	return func() (*domain.Customer, error) {
+		return c, nil
+	}
+}
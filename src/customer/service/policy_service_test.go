@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rasteiro11/MCABankCustomer/pkg/auth"
+	"github.com/rasteiro11/MCABankCustomer/pkg/rbac"
+	"github.com/rasteiro11/MCABankCustomer/src/customer/domain"
+)
+
+// testPolicy mirrors config/policy.yaml with user 1 as admin and user 2 as
+// operator; everyone else is a customer.
+const testPolicy = `
+default_role: customer
+users:
+  admin: [1]
+  operator: [2]
+roles:
+  admin:
+    allow: ["*"]
+  operator:
+    allow: [customer.list, customer.read, customer.create, customer.update, customer.patch, customer.change_status]
+  customer:
+    own: [customer.read, customer.create, customer.patch]
+`
+
+const (
+	adminUser    = 1
+	operatorUser = 2
+	ownerUser    = 10
+	strangerUser = 11
+)
+
+// newPolicyFixture stores one customer linked to ownerUser and returns
+// the policy service in front of it.
+func newPolicyFixture(t *testing.T) (CustomerService, *domain.Customer) {
+	t.Helper()
+
+	policy, err := rbac.Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	f := newFixture()
+	c := newCustomer()
+	c.UserID = ownerUser
+	c.Status = domain.StatusActive
+	stored, err := f.repo.Create(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	f.payments.setBalance(stored.ID, 0)
+
+	return NewPolicyService(f.svc, policy), stored
+}
+
+func as(userID uint64) context.Context {
+	return auth.ContextWithSession(context.Background(), &auth.Session{UserID: userID})
+}
+
+func TestPolicyService(t *testing.T) {
+	type call func(s CustomerService, ctx context.Context, c *domain.Customer) error
+
+	var (
+		getByID = func(s CustomerService, ctx context.Context, c *domain.Customer) error {
+			_, err := s.GetByID(ctx, c.ID)
+			return err
+		}
+		getMissing = func(s CustomerService, ctx context.Context, c *domain.Customer) error {
+			_, err := s.GetByID(ctx, c.ID+100)
+			return err
+		}
+		getByDocument = func(s CustomerService, ctx context.Context, c *domain.Customer) error {
+			_, err := s.GetByDocument(ctx, c.Document)
+			return err
+		}
+		getBalances = func(s CustomerService, ctx context.Context, c *domain.Customer) error {
+			_, err := s.GetBalances(ctx, []uint{c.ID})
+			return err
+		}
+		patch = func(s CustomerService, ctx context.Context, c *domain.Customer) error {
+			nome := "Ana Maria"
+			_, err := s.Patch(ctx, c.ID, domain.CustomerPatch{Nome: &nome})
+			return err
+		}
+		remove = func(s CustomerService, ctx context.Context, c *domain.Customer) error {
+			return s.Delete(ctx, c.ID, c.Version)
+		}
+		list = func(s CustomerService, ctx context.Context, _ *domain.Customer) error {
+			_, err := s.GetAll(ctx, domain.CustomerQuery{})
+			return err
+		}
+	)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		call call
+		want error
+	}{
+		{"admin reads any customer", as(adminUser), getByID, nil},
+		{"operator reads any customer", as(operatorUser), getByID, nil},
+		{"owner reads their customer", as(ownerUser), getByID, nil},
+		{"stranger is denied another customer", as(strangerUser), getByID, domain.ErrForbidden},
+		{"stranger gets not found for a missing customer", as(strangerUser), getMissing, domain.ErrNotFound},
+		{"owner finds their customer by document", as(ownerUser), getByDocument, nil},
+		{"stranger cannot probe documents", as(strangerUser), getByDocument, domain.ErrForbidden},
+		{"operator reads balances", as(operatorUser), getBalances, nil},
+		{"owner reads their balance", as(ownerUser), getBalances, nil},
+		{"stranger cannot read balances", as(strangerUser), getBalances, domain.ErrForbidden},
+		{"owner patches their customer", as(ownerUser), patch, nil},
+		{"stranger cannot patch", as(strangerUser), patch, domain.ErrForbidden},
+		{"admin deletes", as(adminUser), remove, nil},
+		{"operator is not granted delete", as(operatorUser), remove, domain.ErrForbidden},
+		{"owner is not granted delete", as(ownerUser), remove, domain.ErrForbidden},
+		{"customers cannot list", as(ownerUser), list, domain.ErrForbidden},
+		{"operator lists", as(operatorUser), list, nil},
+		{"no session", context.Background(), getByID, domain.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := newPolicyFixture(t)
+
+			err := tt.call(s, tt.ctx, c)
+			if tt.want == nil && err != nil {
+				t.Fatalf("returned error: %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("returned %v, want %v", err, tt.want)
+			}
+		})
+	}
+}